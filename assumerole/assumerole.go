@@ -0,0 +1,194 @@
+// Package assumerole lets handlers act with a different role, possibly in
+// another account, by assuming it with the caller credentials that
+// CloudFormation passes to every invocation.
+package assumerole
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/webdestroya/cfnresource"
+	"github.com/webdestroya/cfnresource/cfncontext"
+	"github.com/webdestroya/cfnresource/cfnerr"
+	"github.com/webdestroya/cfnresource/cfnutils"
+)
+
+// Options describes the role to assume
+type Options struct {
+	// RoleARN is the role to assume. No role is assumed when it is empty.
+	RoleARN string
+
+	// ExternalID is passed to STS when the role's trust policy requires one
+	ExternalID string
+
+	// SessionName identifies the session in CloudTrail. By default it is made
+	// from the stack name and logical ID of the request.
+	SessionName string
+
+	// Duration of the role session. Zero uses the STS default of one hour.
+	Duration time.Duration
+}
+
+// sessionNameOptions follow the limits of the STS RoleSessionName parameter
+var sessionNameOptions = cfnutils.NameOptions{
+	MaxLength:    64,
+	Charset:      cfnutils.CharsetAlphanumeric + "+=,.@_",
+	Separator:    "-",
+	SuffixLength: 8,
+}
+
+// Config returns a copy of base whose credentials come from assuming the role
+// with the credentials of base. The role is assumed immediately, so that
+// problems are reported here with an InvalidCredentials or AccessDenied
+// error, and the credentials are cached for as long as they are valid.
+func Config(ctx context.Context, base aws.Config, opts Options) (aws.Config, error) {
+	if opts.RoleARN == "" {
+		return base, nil
+	}
+
+	sessionName := opts.SessionName
+	if sessionName == "" {
+		sessionName = defaultSessionName(ctx)
+	}
+
+	provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(base), opts.RoleARN, func(o *stscreds.AssumeRoleOptions) {
+		o.RoleSessionName = sessionName
+		if opts.ExternalID != "" {
+			o.ExternalID = aws.String(opts.ExternalID)
+		}
+		if opts.Duration > 0 {
+			o.Duration = opts.Duration
+		}
+	})
+
+	cfg := base.Copy()
+	cfg.Credentials = aws.NewCredentialsCache(provider)
+
+	if _, err := cfg.Credentials.Retrieve(ctx); err != nil {
+		return aws.Config{}, assumeRoleError(opts.RoleARN, err)
+	}
+
+	return cfg, nil
+}
+
+// assumeRoleError maps a failure to assume a role to a handler error code.
+// Anything that is not clearly transient is reported as InvalidCredentials.
+func assumeRoleError(roleARN string, err error) error {
+	ce := cfnerr.FromAWSError(err)
+
+	code := cfnerr.InvalidCredentials
+	switch ce.Code() {
+	case cfnerr.AccessDenied, cfnerr.Throttling, cfnerr.NetworkFailure, cfnerr.ServiceInternalError:
+		code = ce.Code()
+	}
+
+	return cfnerr.New(code, fmt.Sprintf("unable to assume role %s: %s", roleARN, ce.Message()), err)
+}
+
+// defaultSessionName names the session after the resource being handled
+func defaultSessionName(ctx context.Context) string {
+	info, err := cfncontext.RequestInfo(ctx)
+	if err != nil {
+		return "cfnresource"
+	}
+	return cfnutils.GenerateName(nameSource(info), &sessionNameOptions)
+}
+
+type nameSource cfncontext.RequestMetadata
+
+func (n nameSource) NameInput() cfnutils.NameInput {
+	return cfnutils.NameInput{
+		StackName:          n.StackName,
+		StackID:            n.StackID,
+		LogicalResourceID:  n.LogicalResourceID,
+		ClientRequestToken: n.ClientRequestToken,
+	}
+}
+
+// RoleFunc returns the role to assume for a request
+type RoleFunc[Model any, Ctx any] func(req *cfnresource.Request[Model, Ctx]) (Options, error)
+
+// Middleware assumes the role returned by roleFn before running the handler,
+// and replaces the caller aws.Config in the context with one using the role's
+// credentials, so that cfncontext.GetAwsConfig and cfncontext.Client act as
+// the role for the rest of the invocation:
+//
+//	func (h *Handler) Middleware() []cfnresource.Middleware[Model, CallbackCtx] {
+//		return []cfnresource.Middleware[Model, CallbackCtx]{
+//			assumerole.Middleware(func(req *cfnresource.Request[Model, CallbackCtx]) (assumerole.Options, error) {
+//				return assumerole.Options{RoleARN: req.ResourceProperties.RoleArn}, nil
+//			}),
+//		}
+//	}
+func Middleware[Model any, Ctx any](roleFn RoleFunc[Model, Ctx]) cfnresource.Middleware[Model, Ctx] {
+	return func(next cfnresource.HandlerFunc[Model, Ctx]) cfnresource.HandlerFunc[Model, Ctx] {
+		return func(ctx context.Context, req *cfnresource.Request[Model, Ctx]) (*cfnresource.ProgressEvent[Model, Ctx], error) {
+			opts, err := roleFn(req)
+			if err != nil {
+				return nil, err
+			}
+			if opts.RoleARN == "" {
+				return next(ctx, req)
+			}
+
+			base, err := cfncontext.GetAwsConfig(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			cfg, err := Config(ctx, base, opts)
+			if err != nil {
+				return nil, err
+			}
+
+			// clients built with the caller credentials must not be reused
+			ctx = cfncontext.SetAwsConfig(ctx, cfg)
+			ctx = cfncontext.SetClientCache(ctx, cfncontext.NewClientCache())
+
+			return next(ctx, req)
+		}
+	}
+}
+
+// FromTypeConfiguration returns a RoleFunc that reads the role ARN, and
+// optionally an external ID, from the named properties of the type
+// configuration. Pass an empty externalIDProperty if there is none.
+func FromTypeConfiguration[Model any, Ctx any](roleARNProperty string, externalIDProperty string) RoleFunc[Model, Ctx] {
+	return func(req *cfnresource.Request[Model, Ctx]) (Options, error) {
+		if len(req.TypeConfiguration) == 0 {
+			return Options{}, nil
+		}
+
+		var typeConfig map[string]json.RawMessage
+		if err := json.Unmarshal(req.TypeConfiguration, &typeConfig); err != nil {
+			return Options{}, cfnerr.New(cfnerr.InvalidTypeConfiguration, "unable to read type configuration", err)
+		}
+
+		var opts Options
+		if err := stringProperty(typeConfig, roleARNProperty, &opts.RoleARN); err != nil {
+			return Options{}, err
+		}
+		if externalIDProperty != "" {
+			if err := stringProperty(typeConfig, externalIDProperty, &opts.ExternalID); err != nil {
+				return Options{}, err
+			}
+		}
+		return opts, nil
+	}
+}
+
+func stringProperty(props map[string]json.RawMessage, name string, out *string) error {
+	raw, ok := props[name]
+	if !ok || string(raw) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return cfnerr.New(cfnerr.InvalidTypeConfiguration, fmt.Sprintf("type configuration property %s must be a string", name), err)
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+package assumerole
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/stretchr/testify/require"
+	"github.com/webdestroya/cfnresource"
+	"github.com/webdestroya/cfnresource/cfncontext"
+	"github.com/webdestroya/cfnresource/cfnerr"
+)
+
+const assumeRoleResponse = `<AssumeRoleResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+<AssumeRoleResult>
+<Credentials>
+<AccessKeyId>ASSUMEDKEY</AccessKeyId>
+<SecretAccessKey>assumedsecret</SecretAccessKey>
+<SessionToken>assumedtoken</SessionToken>
+<Expiration>2030-01-01T00:00:00Z</Expiration>
+</Credentials>
+<AssumedRoleUser>
+<Arn>arn:aws:sts::123456789012:assumed-role/Target/session</Arn>
+<AssumedRoleId>AROA:session</AssumedRoleId>
+</AssumedRoleUser>
+</AssumeRoleResult>
+<ResponseMetadata><RequestId>req-1</RequestId></ResponseMetadata>
+</AssumeRoleResponse>`
+
+const errorResponse = `<ErrorResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+<Error><Type>Sender</Type><Code>%CODE%</Code><Message>not allowed</Message></Error>
+<RequestId>req-1</RequestId>
+</ErrorResponse>`
+
+// fakeSTS answers AssumeRole calls and records the parameters it was sent
+type fakeSTS struct {
+	mu        sync.Mutex
+	calls     []url.Values
+	errorCode string
+	status    int
+}
+
+func (f *fakeSTS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+
+	f.mu.Lock()
+	f.calls = append(f.calls, r.PostForm)
+	f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/xml")
+	if f.errorCode != "" {
+		w.WriteHeader(f.status)
+		_, _ = w.Write([]byte(strings.ReplaceAll(errorResponse, "%CODE%", f.errorCode)))
+		return
+	}
+	_, _ = w.Write([]byte(assumeRoleResponse))
+}
+
+func newBase(t *testing.T, fake *fakeSTS) aws.Config {
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	return aws.Config{
+		Region:           "us-east-1",
+		Credentials:      credentials.NewStaticCredentialsProvider("CALLERKEY", "callersecret", "callertoken"),
+		BaseEndpoint:     aws.String(srv.URL),
+		RetryMaxAttempts: 1,
+	}
+}
+
+func TestConfig(t *testing.T) {
+	fake := &fakeSTS{}
+	base := newBase(t, fake)
+
+	ctx := cfncontext.SetRequestInfo(context.Background(), cfncontext.RequestMetadata{
+		StackName:          "MyStack",
+		StackID:            "arn:aws:cloudformation:us-east-1:123456789012:stack/MyStack/abc",
+		LogicalResourceID:  "MyResource",
+		ClientRequestToken: "token-1",
+	})
+
+	cfg, err := Config(ctx, base, Options{
+		RoleARN:    "arn:aws:iam::210987654321:role/Target",
+		ExternalID: "external",
+	})
+	require.NoError(t, err)
+
+	creds, err := cfg.Credentials.Retrieve(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "ASSUMEDKEY", creds.AccessKeyID)
+	require.Equal(t, "assumedtoken", creds.SessionToken)
+
+	// the credentials are cached, so STS is only called once
+	require.Len(t, fake.calls, 1)
+	call := fake.calls[0]
+	require.Equal(t, "AssumeRole", call.Get("Action"))
+	require.Equal(t, "arn:aws:iam::210987654321:role/Target", call.Get("RoleArn"))
+	require.Equal(t, "external", call.Get("ExternalId"))
+	require.Regexp(t, `^MyStack-MyResource-[A-Z0-9]{8}$`, call.Get("RoleSessionName"))
+
+	// the base config is left alone
+	baseCreds, err := base.Credentials.Retrieve(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "CALLERKEY", baseCreds.AccessKeyID)
+}
+
+func TestConfigNoRole(t *testing.T) {
+	fake := &fakeSTS{}
+	base := newBase(t, fake)
+
+	cfg, err := Config(context.Background(), base, Options{})
+	require.NoError(t, err)
+	require.Equal(t, base.Credentials, cfg.Credentials)
+	require.Empty(t, fake.calls)
+}
+
+func TestConfigSessionName(t *testing.T) {
+	fake := &fakeSTS{}
+	base := newBase(t, fake)
+
+	_, err := Config(context.Background(), base, Options{RoleARN: "arn:aws:iam::210987654321:role/Target"})
+	require.NoError(t, err)
+	require.Equal(t, "cfnresource", fake.calls[0].Get("RoleSessionName"))
+
+	_, err = Config(context.Background(), base, Options{RoleARN: "arn:aws:iam::210987654321:role/Target", SessionName: "custom"})
+	require.NoError(t, err)
+	require.Equal(t, "custom", fake.calls[1].Get("RoleSessionName"))
+}
+
+func TestConfigErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		code   string
+		status int
+		exp    cfnerr.Error
+	}{
+		{"access denied", "AccessDenied", 403, cfnerr.NewMessage(cfnerr.AccessDenied, "")},
+		{"expired", "ExpiredToken", 400, cfnerr.NewMessage(cfnerr.InvalidCredentials, "")},
+		{"invalid token", "InvalidClientTokenId", 403, cfnerr.NewMessage(cfnerr.InvalidCredentials, "")},
+		{"malformed", "ValidationError", 400, cfnerr.NewMessage(cfnerr.InvalidCredentials, "")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := newBase(t, &fakeSTS{errorCode: tt.code, status: tt.status})
+
+			_, err := Config(context.Background(), base, Options{RoleARN: "arn:aws:iam::210987654321:role/Target"})
+			require.Error(t, err)
+
+			ce, ok := cfnerr.As(err)
+			require.True(t, ok)
+			require.Equal(t, tt.exp.Code(), ce.Code())
+			require.Contains(t, ce.Message(), "unable to assume role arn:aws:iam::210987654321:role/Target")
+		})
+	}
+}
+
+type model struct {
+	RoleArn string `json:",omitempty"`
+}
+
+type callbackCtx struct{}
+
+type requestType = *cfnresource.Request[model, callbackCtx]
+type progEventType = *cfnresource.ProgressEvent[model, callbackCtx]
+
+func TestMiddleware(t *testing.T) {
+	fake := &fakeSTS{}
+	base := newBase(t, fake)
+
+	mw := Middleware(func(req requestType) (Options, error) {
+		return Options{RoleARN: req.ResourceProperties.RoleArn}, nil
+	})
+
+	var seen aws.Credentials
+	next := func(ctx context.Context, req requestType) (progEventType, error) {
+		cfg, err := cfncontext.GetAwsConfig(ctx)
+		require.NoError(t, err)
+		seen, err = cfg.Credentials.Retrieve(ctx)
+		require.NoError(t, err)
+		return req.SuccessResponse(req.ResourceProperties), nil
+	}
+
+	cache := cfncontext.NewClientCache()
+	ctx := cfncontext.SetAwsConfig(context.Background(), base)
+	ctx = cfncontext.SetClientCache(ctx, cache)
+
+	t.Run("no role", func(t *testing.T) {
+		_, err := mw(next)(ctx, &cfnresource.Request[model, callbackCtx]{ResourceProperties: &model{}})
+		require.NoError(t, err)
+		require.Equal(t, "CALLERKEY", seen.AccessKeyID)
+		require.Empty(t, fake.calls)
+	})
+
+	t.Run("role", func(t *testing.T) {
+		req := &cfnresource.Request[model, callbackCtx]{ResourceProperties: &model{RoleArn: "arn:aws:iam::210987654321:role/Target"}}
+		_, err := mw(next)(ctx, req)
+		require.NoError(t, err)
+		require.Equal(t, "ASSUMEDKEY", seen.AccessKeyID)
+		require.Len(t, fake.calls, 1)
+	})
+
+	t.Run("missing config", func(t *testing.T) {
+		req := &cfnresource.Request[model, callbackCtx]{ResourceProperties: &model{RoleArn: "arn:aws:iam::210987654321:role/Target"}}
+		_, err := mw(next)(context.Background(), req)
+		require.ErrorIs(t, err, cfncontext.ErrContextValueMissingError)
+	})
+}
+
+func TestFromTypeConfiguration(t *testing.T) {
+	roleFn := FromTypeConfiguration[model, callbackCtx]("RoleArn", "ExternalId")
+
+	tests := []struct {
+		name    string
+		config  string
+		exp     Options
+		errCode string
+	}{
+		{"empty", ``, Options{}, ""},
+		{"missing", `{"Other":"x"}`, Options{}, ""},
+		{"null", `{"RoleArn":null}`, Options{}, ""},
+		{"role", `{"RoleArn":"arn:role"}`, Options{RoleARN: "arn:role"}, ""},
+		{"external", `{"RoleArn":"arn:role","ExternalId":"ext"}`, Options{RoleARN: "arn:role", ExternalID: "ext"}, ""},
+		{"wrong type", `{"RoleArn":5}`, Options{}, string(cfnerr.InvalidTypeConfiguration)},
+		{"malformed", `[`, Options{}, string(cfnerr.InvalidTypeConfiguration)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &cfnresource.Request[model, callbackCtx]{}
+			if tt.config != "" {
+				req.TypeConfiguration = []byte(tt.config)
+			}
+
+			opts, err := roleFn(req)
+			if tt.errCode != "" {
+				ce, ok := cfnerr.As(err)
+				require.True(t, ok)
+				require.Equal(t, tt.errCode, string(ce.Code()))
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.exp, opts)
+		})
+	}
+}
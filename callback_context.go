@@ -0,0 +1,159 @@
+package cfnresource
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// callbackEnvelopeKey marks a callback context that was wrapped by the runtime
+// so it can carry its own state alongside the handler's callback context.
+const callbackEnvelopeKey = "__cfnresource"
+
+// callbackEnvelope is the wire format of a wrapped callback context:
+//
+//	{"__cfnresource": {"context": {...}, "extensions": {...}}}
+type callbackEnvelope struct {
+	// Context is the handler's stringified callback context
+	Context json.RawMessage `json:"context,omitempty"`
+
+	// Extensions hold state that is managed by the runtime or helpers
+	Extensions map[string]json.RawMessage `json:"extensions,omitempty"`
+}
+
+// unwrapCallbackContext splits a callback context into the handler's
+// context and any extension state stored by the runtime.
+func unwrapCallbackContext(data json.RawMessage) (json.RawMessage, map[string]json.RawMessage, error) {
+	if len(data) == 0 || !bytes.Contains(data, []byte(callbackEnvelopeKey)) {
+		return data, nil, nil
+	}
+
+	var wrapper map[string]json.RawMessage
+	if err := json.Unmarshal(data, &wrapper); err != nil {
+		return nil, nil, err
+	}
+
+	raw, ok := wrapper[callbackEnvelopeKey]
+	if !ok || len(wrapper) != 1 {
+		return data, nil, nil
+	}
+
+	var env callbackEnvelope
+	if err := json.Unmarshal(raw, &env); err != nil {
+		return nil, nil, fmt.Errorf("invalid callback context envelope: %w", err)
+	}
+
+	if bytes.Equal(env.Context, []byte("null")) {
+		env.Context = nil
+	}
+
+	return env.Context, env.Extensions, nil
+}
+
+// wrapCallbackContext returns the callback context that is sent to CloudFormation.
+// If there are no extensions, the handler's context is returned as-is.
+func wrapCallbackContext(cbCtx any, extensions map[string]any) (any, error) {
+	if len(extensions) == 0 {
+		return cbCtx, nil
+	}
+
+	env := callbackEnvelope{
+		Extensions: make(map[string]json.RawMessage, len(extensions)),
+	}
+
+	if cbCtx != nil {
+		data, err := json.Marshal(cbCtx)
+		if err != nil {
+			return nil, err
+		}
+		env.Context = data
+	}
+
+	for k, v := range extensions {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("unable to marshal callback extension %q: %w", k, err)
+		}
+		env.Extensions[k] = data
+	}
+
+	return map[string]any{callbackEnvelopeKey: env}, nil
+}
+
+// Extension decodes state that was stored in the callback context under key
+// by a previous invocation's ProgressEvent.WithExtension. It reports whether
+// the key was present.
+//
+// Extensions are used by the runtime and helpers to keep their own state
+// without requiring fields on the handler's callback context type.
+func (r *Request[Model, Ctx]) Extension(key string, v any) (bool, error) {
+	raw, ok := r.extensions[key]
+	if !ok {
+		return false, nil
+	}
+
+	if err := json.Unmarshal(raw, v); err != nil {
+		return true, fmt.Errorf("unable to unmarshal callback extension %q: %w", key, err)
+	}
+
+	return true, nil
+}
+
+// WithExtension stores v under key in the callback context so that it is
+// available from Request.Extension on the next invocation. The value is
+// encoded with encoding/json.
+func (pe *ProgressEvent[Model, CallbackCtx]) WithExtension(key string, v any) *ProgressEvent[Model, CallbackCtx] {
+	if pe.extensions == nil {
+		pe.extensions = make(map[string]any)
+	}
+	pe.extensions[key] = v
+	return pe
+}
+
+// Extensions returns the extension state set on the event, encoded as JSON.
+// Passing each value back to WithExtension restores it, which allows a
+// progress event to be stored and replayed later.
+func (pe *ProgressEvent[Model, CallbackCtx]) Extensions() (map[string]json.RawMessage, error) {
+	out := make(map[string]json.RawMessage, len(pe.extensions))
+	for k, v := range pe.extensions {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("unable to marshal callback extension %q: %w", k, err)
+		}
+		out[k] = data
+	}
+	return out, nil
+}
+
+// withRequestExtensions carries every extension from the request forward
+// into the progress event, without replacing ones that are already set.
+func (pe *ProgressEvent[Model, CallbackCtx]) withRequestExtensions(req *Request[Model, CallbackCtx]) *ProgressEvent[Model, CallbackCtx] {
+	for k, v := range req.extensions {
+		if _, ok := pe.extensions[k]; !ok {
+			pe = pe.WithExtension(k, v)
+		}
+	}
+	return pe
+}
+
+// CallbackRequest returns the request that CloudFormation would send when
+// re-invoking the handler after it returned pe. The model and callback context
+// from pe replace those of the current request.
+//
+// This is intended for driving handlers through their callbacks in tests.
+func (r *Request[Model, Ctx]) CallbackRequest(pe *ProgressEvent[Model, Ctx]) (*Request[Model, Ctx], error) {
+	next := *r
+	next.ResourceProperties = pe.ResourceModel
+	next.CallbackContext = pe.CallbackContext
+	next.extensions = make(map[string]json.RawMessage, len(pe.extensions))
+
+	for k, v := range pe.extensions {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("unable to marshal callback extension %q: %w", k, err)
+		}
+		next.extensions[k] = data
+	}
+
+	return &next, nil
+}
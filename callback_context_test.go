@@ -0,0 +1,81 @@
+package cfnresource
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallbackContextEnvelope(t *testing.T) {
+	step := 7
+	req := &Request[model, callbackCtx]{}
+	pe := req.InProgressResponse(nil, &callbackCtx{Step: &step}).WithExtension("thing", map[string]int{"count": 2})
+
+	resp, err := newResponse(pe, "token")
+	require.NoError(t, err)
+
+	data, err := json.Marshal(resp.CallbackContext)
+	require.NoError(t, err)
+	require.Contains(t, string(data), callbackEnvelopeKey)
+
+	ev := newTestEvent(updateAction, `{}`)
+	ev.CallbackContext = data
+
+	req, err = newRequest[model, callbackCtx](ev)
+	require.NoError(t, err)
+	require.NotNil(t, req.CallbackContext)
+	require.Equal(t, 7, *req.CallbackContext.Step)
+
+	var thing map[string]int
+	ok, err := req.Extension("thing", &thing)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, 2, thing["count"])
+
+	ok, err = req.Extension("missing", &thing)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	t.Run("no handler context", func(t *testing.T) {
+		pe := req.InProgressResponse(nil, nil).WithExtension("thing", 1)
+		resp, err := newResponse(pe, "token")
+		require.NoError(t, err)
+
+		ev.CallbackContext, err = json.Marshal(resp.CallbackContext)
+		require.NoError(t, err)
+
+		req, err := newRequest[model, callbackCtx](ev)
+		require.NoError(t, err)
+		require.Nil(t, req.CallbackContext)
+	})
+
+	t.Run("without extensions", func(t *testing.T) {
+		pe := req.InProgressResponse(nil, &callbackCtx{Step: &step})
+		resp, err := newResponse(pe, "token")
+		require.NoError(t, err)
+
+		data, err := json.Marshal(resp.CallbackContext)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"Step": "7"}`, string(data))
+	})
+}
+
+func TestCallbackRequest(t *testing.T) {
+	step := 3
+	req := &Request[model, callbackCtx]{Action: updateAction, ResourceProperties: &model{Name: "before"}}
+	pe := req.InProgressResponse(&model{Name: "after"}, &callbackCtx{Step: &step}).WithExtension("thing", 5)
+
+	next, err := req.CallbackRequest(pe)
+	require.NoError(t, err)
+	require.Equal(t, updateAction, next.Action)
+	require.Equal(t, "after", next.ResourceProperties.Name)
+	require.Equal(t, 3, *next.CallbackContext.Step)
+	require.Equal(t, "before", req.ResourceProperties.Name)
+
+	var thing int
+	ok, err := next.Extension("thing", &thing)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, 5, thing)
+}
@@ -0,0 +1,61 @@
+package cfncontext
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+const clientCacheKey = ctxKey(`clients`)
+
+// ClientCache holds SDK clients built from one aws.Config. The runtime keeps
+// one per set of caller credentials, so clients are reused by warm invocations.
+type ClientCache struct {
+	mu      sync.Mutex
+	clients map[reflect.Type]any
+}
+
+func NewClientCache() *ClientCache {
+	return &ClientCache{clients: make(map[reflect.Type]any)}
+}
+
+func SetClientCache(ctx context.Context, cache *ClientCache) context.Context {
+	return context.WithValue(ctx, clientCacheKey, cache)
+}
+
+// Client returns a client of type T built by newFn from the caller aws.Config
+// in the context. The client is built once and reused for as long as the
+// caller credentials stay the same:
+//
+//	client, err := cfncontext.Client(ctx, func(cfg aws.Config) *s3.Client {
+//		return s3.NewFromConfig(cfg)
+//	})
+//
+// Clients are cached by type, so a handler that needs two differently
+// configured clients of the same type should build the second one itself.
+func Client[T any](ctx context.Context, newFn func(aws.Config) T) (T, error) {
+	cfg, err := GetAwsConfig(ctx)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	cache, ok := ctx.Value(clientCacheKey).(*ClientCache)
+	if !ok || cache == nil {
+		return newFn(cfg), nil
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	key := reflect.TypeFor[T]()
+	if client, ok := cache.clients[key].(T); ok {
+		return client, nil
+	}
+
+	client := newFn(cfg)
+	cache.clients[key] = client
+	return client, nil
+}
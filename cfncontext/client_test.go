@@ -0,0 +1,44 @@
+package cfncontext_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/require"
+	"github.com/webdestroya/cfnresource/cfncontext"
+)
+
+type fakeClient struct {
+	region string
+}
+
+func TestClient(t *testing.T) {
+	builds := 0
+	newFn := func(cfg aws.Config) *fakeClient {
+		builds++
+		return &fakeClient{region: cfg.Region}
+	}
+
+	_, err := cfncontext.Client(context.Background(), newFn)
+	require.ErrorIs(t, err, cfncontext.ErrContextValueMissingError)
+
+	ctx := cfncontext.SetAwsConfig(context.Background(), aws.Config{Region: "us-east-1"})
+
+	// without a cache, a client is built every time
+	_, err = cfncontext.Client(ctx, newFn)
+	require.NoError(t, err)
+	_, err = cfncontext.Client(ctx, newFn)
+	require.NoError(t, err)
+	require.Equal(t, 2, builds)
+
+	ctx = cfncontext.SetClientCache(ctx, cfncontext.NewClientCache())
+
+	a, err := cfncontext.Client(ctx, newFn)
+	require.NoError(t, err)
+	b, err := cfncontext.Client(ctx, newFn)
+	require.NoError(t, err)
+	require.Same(t, a, b)
+	require.Equal(t, "us-east-1", a.region)
+	require.Equal(t, 3, builds)
+}
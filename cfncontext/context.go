@@ -3,26 +3,105 @@ package cfncontext
 import (
 	"context"
 	"errors"
+	"fmt"
+	"reflect"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 )
 
 type ctxKey string
 
+// ErrContextValueMissingError matches every MissingValueError with errors.Is
 var ErrContextValueMissingError = errors.New("Config missing")
 
+// MissingValueError is returned when a value was not stored in the context
+type MissingValueError struct {
+	// Name describes the missing value
+	Name string
+}
+
+func (e *MissingValueError) Error() string {
+	return fmt.Sprintf("%s missing from context", e.Name)
+}
+
+func (e *MissingValueError) Is(target error) bool {
+	return target == ErrContextValueMissingError
+}
+
 const (
-	awsCfgKey = ctxKey(`awscfg`)
+	awsCfgKey         = ctxKey(`awscfg`)
+	providerAwsCfgKey = ctxKey(`providerawscfg`)
+	requestInfoKey    = ctxKey(`requestinfo`)
 )
 
 func SetAwsConfig(ctx context.Context, cfg aws.Config) context.Context {
 	return context.WithValue(ctx, awsCfgKey, cfg)
 }
 
+// GetAwsConfig returns the config built from the caller credentials, which
+// act on behalf of the user in their account
 func GetAwsConfig(ctx context.Context) (aws.Config, error) {
 	val, ok := ctx.Value(awsCfgKey).(aws.Config)
 	if !ok {
-		return aws.Config{}, ErrContextValueMissingError
+		return aws.Config{}, &MissingValueError{Name: "caller aws config"}
+	}
+	return val, nil
+}
+
+func SetProviderAwsConfig(ctx context.Context, cfg aws.Config) context.Context {
+	return context.WithValue(ctx, providerAwsCfgKey, cfg)
+}
+
+// GetProviderAwsConfig returns the config built from the provider
+// credentials, which assume the execution role of the resource type
+func GetProviderAwsConfig(ctx context.Context) (aws.Config, error) {
+	val, ok := ctx.Value(providerAwsCfgKey).(aws.Config)
+	if !ok {
+		return aws.Config{}, &MissingValueError{Name: "provider aws config"}
+	}
+	return val, nil
+}
+
+// RequestMetadata describes the handler invocation that a context belongs to
+type RequestMetadata struct {
+	Action              string
+	AWSAccountID        string
+	Region              string
+	StackID             string
+	StackName           string
+	LogicalResourceID   string
+	ResourceType        string
+	ResourceTypeVersion string
+	ClientRequestToken  string
+}
+
+func SetRequestInfo(ctx context.Context, info RequestMetadata) context.Context {
+	return context.WithValue(ctx, requestInfoKey, info)
+}
+
+// RequestInfo returns the metadata of the current handler invocation
+func RequestInfo(ctx context.Context) (RequestMetadata, error) {
+	val, ok := ctx.Value(requestInfoKey).(RequestMetadata)
+	if !ok {
+		return RequestMetadata{}, &MissingValueError{Name: "request info"}
+	}
+	return val, nil
+}
+
+// typeKey stores one value of each type with With
+type typeKey[T any] struct{}
+
+// With stores v in the context, keyed by its type, so it can be retrieved
+// with Get. Use a distinct type for each value to avoid collisions.
+func With[T any](ctx context.Context, v T) context.Context {
+	return context.WithValue(ctx, typeKey[T]{}, v)
+}
+
+// Get returns the value of type T stored by With
+func Get[T any](ctx context.Context) (T, error) {
+	val, ok := ctx.Value(typeKey[T]{}).(T)
+	if !ok {
+		return val, &MissingValueError{Name: reflect.TypeFor[T]().String()}
 	}
 	return val, nil
 }
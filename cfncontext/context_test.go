@@ -0,0 +1,55 @@
+package cfncontext_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/require"
+	"github.com/webdestroya/cfnresource/cfncontext"
+)
+
+type tenantID string
+
+func TestContextValues(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := cfncontext.GetProviderAwsConfig(ctx)
+	require.ErrorIs(t, err, cfncontext.ErrContextValueMissingError)
+	require.EqualError(t, err, "provider aws config missing from context")
+
+	_, err = cfncontext.RequestInfo(ctx)
+	var missing *cfncontext.MissingValueError
+	require.True(t, errors.As(err, &missing))
+	require.Equal(t, "request info", missing.Name)
+
+	_, err = cfncontext.Get[tenantID](ctx)
+	require.EqualError(t, err, "cfncontext_test.tenantID missing from context")
+
+	ctx = cfncontext.SetAwsConfig(ctx, aws.Config{Region: "caller"})
+	ctx = cfncontext.SetProviderAwsConfig(ctx, aws.Config{Region: "provider"})
+	ctx = cfncontext.SetRequestInfo(ctx, cfncontext.RequestMetadata{Action: "CREATE", LogicalResourceID: "MyThing"})
+	ctx = cfncontext.With(ctx, tenantID("acme"))
+	ctx = cfncontext.With(ctx, "a plain string")
+
+	cfg, err := cfncontext.GetAwsConfig(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "caller", cfg.Region)
+
+	cfg, err = cfncontext.GetProviderAwsConfig(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "provider", cfg.Region)
+
+	info, err := cfncontext.RequestInfo(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "MyThing", info.LogicalResourceID)
+
+	tenant, err := cfncontext.Get[tenantID](ctx)
+	require.NoError(t, err)
+	require.Equal(t, tenantID("acme"), tenant)
+
+	s, err := cfncontext.Get[string](ctx)
+	require.NoError(t, err)
+	require.Equal(t, "a plain string", s)
+}
@@ -0,0 +1,266 @@
+package cfnerr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	cfnTypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/smithy-go"
+)
+
+var (
+	awsOverridesMu sync.RWMutex
+
+	// awsOverrides maps a service ID to API error codes and their classification.
+	// The empty service ID applies to all services.
+	awsOverrides = map[string]map[string]cfnTypes.HandlerErrorCode{}
+)
+
+// awsErrorCodes is the default classification of well known AWS API error codes
+var awsErrorCodes = map[string]cfnTypes.HandlerErrorCode{
+	"NotFound":                  NotFound,
+	"NotFoundException":         NotFound,
+	"ResourceNotFound":          NotFound,
+	"ResourceNotFoundException": NotFound,
+	"NoSuchEntity":              NotFound,
+	"NoSuchEntityException":     NotFound,
+	"NoSuchBucket":              NotFound,
+	"NoSuchKey":                 NotFound,
+
+	"AlreadyExists":                  AlreadyExists,
+	"AlreadyExistsException":         AlreadyExists,
+	"ResourceAlreadyExistsException": AlreadyExists,
+	"EntityAlreadyExists":            AlreadyExists,
+	"EntityAlreadyExistsException":   AlreadyExists,
+	"BucketAlreadyExists":            AlreadyExists,
+	"BucketAlreadyOwnedByYou":        AlreadyExists,
+
+	"AccessDenied":          AccessDenied,
+	"AccessDeniedException": AccessDenied,
+	"UnauthorizedOperation": AccessDenied,
+	"AuthorizationError":    AccessDenied,
+	"Forbidden":             AccessDenied,
+	"ForbiddenException":    AccessDenied,
+
+	"UnrecognizedClientException": InvalidCredentials,
+	"InvalidClientTokenId":        InvalidCredentials,
+	"ExpiredToken":                InvalidCredentials,
+	"ExpiredTokenException":       InvalidCredentials,
+	"InvalidAccessKeyId":          InvalidCredentials,
+	"SignatureDoesNotMatch":       InvalidCredentials,
+	"InvalidSignatureException":   InvalidCredentials,
+	"AuthFailure":                 InvalidCredentials,
+	"MissingAuthenticationToken":  InvalidCredentials,
+
+	"LimitExceeded":                 ServiceLimitExceeded,
+	"LimitExceededException":        ServiceLimitExceeded,
+	"ServiceQuotaExceededException": ServiceLimitExceeded,
+	"QuotaExceededException":        ServiceLimitExceeded,
+	"TooManyBuckets":                ServiceLimitExceeded,
+
+	"ConflictException":               ResourceConflict,
+	"ResourceConflictException":       ResourceConflict,
+	"ResourceInUseException":          ResourceConflict,
+	"ResourceInUse":                   ResourceConflict,
+	"OperationAbortedException":       ResourceConflict,
+	"OperationAborted":                ResourceConflict,
+	"ConcurrentModificationException": ResourceConflict,
+	"ConcurrentModification":          ResourceConflict,
+	"IncorrectState":                  ResourceConflict,
+	"InvalidStateException":           ResourceConflict,
+	"OperationInProgressException":    ResourceConflict,
+	"ResourceInUseByAnotherOperation": ResourceConflict,
+	"PreconditionFailed":              ResourceConflict,
+	"PreconditionFailedException":     ResourceConflict,
+	"TransactionConflictException":    ResourceConflict,
+
+	"InvalidParameterCombination":      InvalidRequest,
+	"InvalidParameterException":        InvalidRequest,
+	"InvalidParameterValue":            InvalidRequest,
+	"InvalidParameterValueException":   InvalidRequest,
+	"InvalidInput":                     InvalidRequest,
+	"InvalidInputException":            InvalidRequest,
+	"InvalidRequest":                   InvalidRequest,
+	"InvalidRequestException":          InvalidRequest,
+	"MalformedPolicyDocument":          InvalidRequest,
+	"MalformedPolicyDocumentException": InvalidRequest,
+	"MissingParameter":                 InvalidRequest,
+	"ValidationError":                  InvalidRequest,
+	"ValidationException":              InvalidRequest,
+
+	"InternalError":               ServiceInternalError,
+	"InternalFailure":             ServiceInternalError,
+	"InternalServerError":         ServiceInternalError,
+	"InternalServerException":     ServiceInternalError,
+	"InternalServiceError":        ServiceInternalError,
+	"InternalServiceException":    ServiceInternalError,
+	"ServiceUnavailable":          ServiceInternalError,
+	"ServiceUnavailableException": ServiceInternalError,
+}
+
+// awsErrorSuffixes classifies error codes that follow a naming convention,
+// such as EC2's "InvalidVpcID.NotFound" or RDS's "DBInstanceNotFoundFault"
+var awsErrorSuffixes = []struct {
+	suffix string
+	code   cfnTypes.HandlerErrorCode
+}{
+	{"NotFound", NotFound},
+	{"NotFoundException", NotFound},
+	{"NotFoundFault", NotFound},
+	{".Duplicate", AlreadyExists},
+	{"AlreadyExists", AlreadyExists},
+	{"AlreadyExistsException", AlreadyExists},
+	{"AlreadyExistsFault", AlreadyExists},
+	{"QuotaExceeded", ServiceLimitExceeded},
+	{"QuotaExceededFault", ServiceLimitExceeded},
+	{"LimitExceeded", ServiceLimitExceeded},
+	{"LimitExceededFault", ServiceLimitExceeded},
+	{"InUse", ResourceConflict},
+	{"InvalidState", ResourceConflict},
+	{"InvalidStateFault", ResourceConflict},
+}
+
+// RegisterAWSErrorCode overrides the classification of an AWS API error code.
+// If serviceID is blank, the override applies to errors from every service.
+// The serviceID is the value used by the SDK, such as "S3" or "EC2".
+func RegisterAWSErrorCode(serviceID string, apiErrorCode string, code cfnTypes.HandlerErrorCode) {
+	awsOverridesMu.Lock()
+	defer awsOverridesMu.Unlock()
+
+	if _, ok := awsOverrides[serviceID]; !ok {
+		awsOverrides[serviceID] = make(map[string]cfnTypes.HandlerErrorCode)
+	}
+	awsOverrides[serviceID][apiErrorCode] = code
+}
+
+// FromAWSError classifies an error returned by the AWS SDK into an Error
+// with the most appropriate handler error code.
+//
+// Errors that are already an Error are returned unchanged. Errors that cannot
+// be classified are reported as InternalFailure.
+func FromAWSError(err error) Error {
+	if err == nil {
+		return nil
+	}
+
+	if ce, ok := As(err); ok {
+		return ce
+	}
+
+	message := err.Error()
+
+	// the SDK reports a cancelled or expired context as a retryable
+	// connection error, but retrying will not help once the invocation's
+	// deadline has passed
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return New(InternalFailure, message, err)
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		if apiErr.ErrorMessage() != "" {
+			message = apiErr.ErrorCode() + ": " + apiErr.ErrorMessage()
+		}
+
+		var serviceID string
+		var opErr *smithy.OperationError
+		if errors.As(err, &opErr) {
+			serviceID = opErr.ServiceID
+		}
+
+		if code, ok := classifyAPIErrorCode(serviceID, apiErr.ErrorCode()); ok {
+			return New(code, message, err)
+		}
+	}
+
+	if retry.IsErrorThrottles(retry.DefaultThrottles).IsErrorThrottle(err) == aws.TrueTernary {
+		return New(Throttling, message, err)
+	}
+
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) {
+		if code, ok := classifyHTTPStatus(respErr.HTTPStatusCode()); ok {
+			return New(code, message, err)
+		}
+	}
+
+	if (retry.RetryableConnectionError{}).IsErrorRetryable(err) == aws.TrueTernary {
+		return New(NetworkFailure, message, err)
+	}
+
+	if apiErr != nil {
+		if apiErr.ErrorFault() == smithy.FaultServer {
+			return New(ServiceInternalError, message, err)
+		}
+		return New(GeneralServiceException, message, err)
+	}
+
+	if retry.IsErrorRetryables(retry.DefaultRetryables).IsErrorRetryable(err) == aws.TrueTernary {
+		return New(GeneralServiceException, message, err)
+	}
+
+	return New(InternalFailure, message, err)
+}
+
+func classifyAPIErrorCode(serviceID string, apiCode string) (cfnTypes.HandlerErrorCode, bool) {
+	awsOverridesMu.RLock()
+	if code, ok := awsOverrides[serviceID][apiCode]; ok {
+		awsOverridesMu.RUnlock()
+		return code, true
+	}
+	if code, ok := awsOverrides[""][apiCode]; ok {
+		awsOverridesMu.RUnlock()
+		return code, true
+	}
+	awsOverridesMu.RUnlock()
+
+	if code, ok := awsErrorCodes[apiCode]; ok {
+		return code, true
+	}
+
+	if retry.IsErrorThrottles(retry.DefaultThrottles).IsErrorThrottle(throttleCode(apiCode)) == aws.TrueTernary {
+		return Throttling, true
+	}
+
+	for _, s := range awsErrorSuffixes {
+		if strings.HasSuffix(apiCode, s.suffix) {
+			return s.code, true
+		}
+	}
+
+	return "", false
+}
+
+func classifyHTTPStatus(status int) (cfnTypes.HandlerErrorCode, bool) {
+	switch {
+	case status == http.StatusNotFound:
+		return NotFound, true
+	case status == http.StatusConflict || status == http.StatusPreconditionFailed:
+		return ResourceConflict, true
+	case status == http.StatusTooManyRequests:
+		return Throttling, true
+	case status == http.StatusUnauthorized:
+		return InvalidCredentials, true
+	case status == http.StatusForbidden:
+		return AccessDenied, true
+	case status == http.StatusBadRequest:
+		return InvalidRequest, true
+	case status >= 500:
+		return ServiceInternalError, true
+	case status >= 400:
+		return GeneralServiceException, true
+	}
+	return "", false
+}
+
+// throttleCode lets a bare error code be checked against the SDK's throttle list
+type throttleCode string
+
+func (c throttleCode) Error() string     { return string(c) }
+func (c throttleCode) ErrorCode() string { return string(c) }
@@ -0,0 +1,102 @@
+package cfnerr_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	cfnTypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/stretchr/testify/require"
+	"github.com/webdestroya/cfnresource/cfnerr"
+)
+
+func apiError(service string, code string) error {
+	return &smithy.OperationError{
+		ServiceID:     service,
+		OperationName: "DoThing",
+		Err: &smithy.GenericAPIError{
+			Code:    code,
+			Message: "something happened",
+		},
+	}
+}
+
+func httpError(status int) error {
+	return &smithy.OperationError{
+		ServiceID:     "Fake",
+		OperationName: "DoThing",
+		Err: &awshttp.ResponseError{
+			ResponseError: &smithyhttp.ResponseError{
+				Response: &smithyhttp.Response{Response: &http.Response{StatusCode: status}},
+				Err:      errors.New("http failure"),
+			},
+		},
+	}
+}
+
+func TestFromAWSError(t *testing.T) {
+	require.Nil(t, cfnerr.FromAWSError(nil))
+
+	tests := []struct {
+		name string
+		err  error
+		code cfnTypes.HandlerErrorCode
+	}{
+		{"plain", errors.New("plain"), cfnerr.InternalFailure},
+		{"cfnerr", cfnerr.NewMessage(cfnerr.NotUpdatable, "nope"), cfnerr.NotUpdatable},
+		{"not found", apiError("DynamoDB", "ResourceNotFoundException"), cfnerr.NotFound},
+		{"ec2 not found", apiError("EC2", "InvalidVpcID.NotFound"), cfnerr.NotFound},
+		{"rds not found", apiError("RDS", "DBInstanceNotFoundFault"), cfnerr.NotFound},
+		{"already exists", apiError("IAM", "EntityAlreadyExists"), cfnerr.AlreadyExists},
+		{"throttled", apiError("S3", "SlowDown"), cfnerr.Throttling},
+		{"throttled sdk list", apiError("EC2", "RequestLimitExceeded"), cfnerr.Throttling},
+		{"access denied", apiError("S3", "AccessDenied"), cfnerr.AccessDenied},
+		{"bad creds", apiError("STS", "ExpiredToken"), cfnerr.InvalidCredentials},
+		{"limit", apiError("Lambda", "ServiceQuotaExceededException"), cfnerr.ServiceLimitExceeded},
+		{"conflict", apiError("ECS", "ConflictException"), cfnerr.ResourceConflict},
+		{"validation", apiError("SSM", "ValidationException"), cfnerr.InvalidRequest},
+		{"unknown api", apiError("SSM", "SomethingOdd"), cfnerr.GeneralServiceException},
+		{"http 404", httpError(http.StatusNotFound), cfnerr.NotFound},
+		{"http 429", httpError(http.StatusTooManyRequests), cfnerr.Throttling},
+		{"http 409", httpError(http.StatusConflict), cfnerr.ResourceConflict},
+		{"http 400", httpError(http.StatusBadRequest), cfnerr.InvalidRequest},
+		{"http 418", httpError(http.StatusTeapot), cfnerr.GeneralServiceException},
+		{"http 503", httpError(http.StatusServiceUnavailable), cfnerr.ServiceInternalError},
+		{"network", &smithyhttp.RequestSendError{Err: errors.New("dial tcp: connection refused")}, cfnerr.NetworkFailure},
+		{"deadline", &smithyhttp.RequestSendError{Err: context.DeadlineExceeded}, cfnerr.InternalFailure},
+		{"canceled", fmt.Errorf("describe: %w", context.Canceled), cfnerr.InternalFailure},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cerr := cfnerr.FromAWSError(tt.err)
+			require.NotNil(t, cerr)
+			require.Equal(t, tt.code, cerr.Code())
+			require.ErrorIs(t, cerr, tt.err)
+		})
+	}
+
+	t.Run("message", func(t *testing.T) {
+		cerr := cfnerr.FromAWSError(apiError("S3", "NoSuchBucket"))
+		require.Equal(t, "NoSuchBucket: something happened", cerr.Message())
+	})
+
+	t.Run("overrides", func(t *testing.T) {
+		cfnerr.RestoreAWSErrorCodes(t)
+
+		cfnerr.RegisterAWSErrorCode("Route 53", "NoSuchHostedZone", cfnerr.NotFound)
+		cfnerr.RegisterAWSErrorCode("", "SomethingWeird", cfnerr.ResourceConflict)
+		cfnerr.RegisterAWSErrorCode("ECS", "ConflictException", cfnerr.AlreadyExists)
+
+		require.Equal(t, cfnerr.NotFound, cfnerr.FromAWSError(apiError("Route 53", "NoSuchHostedZone")).Code())
+		require.Equal(t, cfnerr.GeneralServiceException, cfnerr.FromAWSError(apiError("S3", "NoSuchHostedZone")).Code())
+		require.Equal(t, cfnerr.ResourceConflict, cfnerr.FromAWSError(apiError("S3", "SomethingWeird")).Code())
+		require.Equal(t, cfnerr.AlreadyExists, cfnerr.FromAWSError(apiError("ECS", "ConflictException")).Code())
+		require.Equal(t, cfnerr.ResourceConflict, cfnerr.FromAWSError(apiError("EKS", "ConflictException")).Code())
+	})
+}
@@ -2,6 +2,7 @@ package cfnerr
 
 import (
 	"errors"
+	"time"
 
 	cfnTypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
 )
@@ -33,6 +34,12 @@ type cfnErr struct {
 	// Optional original error this error is based off of. Allows building
 	// chained errors.
 	err error
+
+	// Whether the error is transient and the operation should be retried
+	retryable bool
+
+	// How long to wait before retrying
+	retryAfter time.Duration
 }
 
 var _ error = (*cfnErr)(nil)
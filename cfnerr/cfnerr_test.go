@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"github.com/webdestroya/cfnresource/cfnerr"
@@ -57,3 +58,27 @@ func TestErrorCreation(t *testing.T) {
 
 	})
 }
+
+func TestRetryable(t *testing.T) {
+	innerErr := errors.New("inner error fake")
+
+	_, ok := cfnerr.RetryAfter(innerErr)
+	require.False(t, ok)
+
+	_, ok = cfnerr.RetryAfter(cfnerr.Wrap(cfnerr.Throttling, innerErr))
+	require.False(t, ok)
+
+	require.Nil(t, cfnerr.Retryable(nil, time.Second))
+
+	err := cfnerr.Retryable(cfnerr.Wrap(cfnerr.NotFound, innerErr), 10*time.Second)
+	require.Equal(t, cfnerr.NotFound, err.Code())
+	require.ErrorIs(t, err, innerErr)
+
+	after, ok := cfnerr.RetryAfter(fmt.Errorf("wrapped: %w", err))
+	require.True(t, ok)
+	require.Equal(t, 10*time.Second, after)
+
+	err = cfnerr.Retryable(innerErr, 0)
+	require.Equal(t, cfnerr.InternalFailure, err.Code())
+	require.Equal(t, "inner error fake", err.Message())
+}
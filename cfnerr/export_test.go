@@ -0,0 +1,25 @@
+package cfnerr
+
+import (
+	"maps"
+	"testing"
+
+	cfnTypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+)
+
+// RestoreAWSErrorCodes puts back the codes registered with
+// RegisterAWSErrorCode when the test finishes
+func RestoreAWSErrorCodes(t *testing.T) {
+	awsOverridesMu.RLock()
+	saved := make(map[string]map[string]cfnTypes.HandlerErrorCode, len(awsOverrides))
+	for serviceID, codes := range awsOverrides {
+		saved[serviceID] = maps.Clone(codes)
+	}
+	awsOverridesMu.RUnlock()
+
+	t.Cleanup(func() {
+		awsOverridesMu.Lock()
+		awsOverrides = saved
+		awsOverridesMu.Unlock()
+	})
+}
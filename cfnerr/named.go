@@ -19,4 +19,11 @@ const (
 	ServiceInternalError    = cfnTypes.HandlerErrorCodeServiceInternalError
 	ServiceLimitExceeded    = cfnTypes.HandlerErrorCodeServiceLimitExceeded
 	Throttling              = cfnTypes.HandlerErrorCodeThrottling
+
+	// Hook specific error codes
+	HandlerInternalFailure   = cfnTypes.HandlerErrorCodeHandlerInternalFailure
+	InvalidTypeConfiguration = cfnTypes.HandlerErrorCodeInvalidTypeConfiguration
+	NonCompliant             = cfnTypes.HandlerErrorCodeNonCompliant
+	Unknown                  = cfnTypes.HandlerErrorCodeUnknown
+	UnsupportedTarget        = cfnTypes.HandlerErrorCodeUnsupportedTarget
 )
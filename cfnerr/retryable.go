@@ -0,0 +1,37 @@
+package cfnerr
+
+import (
+	"errors"
+	"time"
+)
+
+// Retryable marks an error as transient. Instead of failing the operation, the
+// runtime will return an IN_PROGRESS event so CloudFormation invokes the
+// handler again after the given delay. A zero delay uses the retry policy's backoff.
+//
+// Errors that are not already an Error are classified using FromAWSError.
+func Retryable(err error, after time.Duration) Error {
+	if err == nil {
+		return nil
+	}
+
+	ce := FromAWSError(err)
+
+	return &cfnErr{
+		code:       ce.Code(),
+		message:    ce.Message(),
+		err:        err,
+		retryable:  true,
+		retryAfter: after,
+	}
+}
+
+// RetryAfter reports whether the error was marked with Retryable, and the
+// delay that was requested.
+func RetryAfter(err error) (time.Duration, bool) {
+	var ce *cfnErr
+	if errors.As(err, &ce) && ce.retryable {
+		return ce.retryAfter, true
+	}
+	return 0, false
+}
@@ -0,0 +1,235 @@
+// Package cfntest runs resource handlers in-process, through the same runtime
+// that handles Lambda invocations, so that they can be tested without
+// deploying them or using the CloudFormation CLI.
+package cfntest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	cfnTypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/webdestroya/cfnresource"
+	"github.com/webdestroya/cfnresource/encoding"
+	"github.com/webdestroya/cfnresource/internal/invocation"
+)
+
+// Actions that a Request can run
+const (
+	Create = "CREATE"
+	Read   = "READ"
+	Update = "UPDATE"
+	Delete = "DELETE"
+	List   = "LIST"
+)
+
+// DefaultMaxInvocations is used when Driver.MaxInvocations is zero
+var DefaultMaxInvocations = 100
+
+// Request is a single operation to run against a handler
+type Request[Model any] struct {
+	Action string
+
+	// Model is the desired resource model
+	Model *Model
+
+	// PreviousModel is the current resource model for an UPDATE
+	PreviousModel *Model
+
+	// NextToken requests the next page of a LIST
+	NextToken string
+
+	// ClientRequestToken identifies the operation. A new one is made if it is empty.
+	ClientRequestToken string
+
+	// TypeConfiguration is encoded to JSON and sent as the type configuration
+	TypeConfiguration any
+
+	ResourceTags         map[string]string
+	PreviousResourceTags map[string]string
+}
+
+// Result is the outcome of an operation, once the handler has stopped
+// returning IN_PROGRESS
+type Result[Model any] struct {
+	Status    cfnTypes.OperationStatus
+	ErrorCode cfnTypes.HandlerErrorCode
+	Message   string
+
+	Model     *Model
+	Models    []*Model
+	NextToken string
+
+	// Invocations is how many times the handler was invoked, including callbacks
+	Invocations int
+}
+
+// Driver invokes a handler the way CloudFormation does, re-invoking it with
+// the callback context and model it returns until it finishes. Callback
+// delays are skipped unless Delay is set.
+type Driver[Model any, Ctx any] struct {
+	Handler cfnresource.Handler[Model, Ctx]
+
+	Region            string
+	AccountID         string
+	StackName         string
+	LogicalResourceID string
+	ResourceType      string
+
+	// StackID is made from the region, account and stack name if it is empty
+	StackID string
+
+	// MaxInvocations limits the invocations of one operation, so that a
+	// handler that never finishes fails the test instead of hanging it
+	MaxInvocations int
+
+	// Delay, when set, is called with each callback delay before the handler
+	// is invoked again
+	Delay func(time.Duration)
+}
+
+// New returns a Driver for the handler with placeholder stack details
+func New[Model any, Ctx any](handler cfnresource.Handler[Model, Ctx]) *Driver[Model, Ctx] {
+	return &Driver[Model, Ctx]{
+		Handler:           handler,
+		Region:            "us-east-1",
+		AccountID:         "123456789012",
+		StackName:         "cfntest",
+		LogicalResourceID: "Resource",
+	}
+}
+
+func (d *Driver[Model, Ctx]) Create(ctx context.Context, model *Model) (*Result[Model], error) {
+	return d.Run(ctx, Request[Model]{Action: Create, Model: model})
+}
+
+func (d *Driver[Model, Ctx]) Read(ctx context.Context, model *Model) (*Result[Model], error) {
+	return d.Run(ctx, Request[Model]{Action: Read, Model: model})
+}
+
+func (d *Driver[Model, Ctx]) Update(ctx context.Context, previous *Model, desired *Model) (*Result[Model], error) {
+	return d.Run(ctx, Request[Model]{Action: Update, Model: desired, PreviousModel: previous})
+}
+
+func (d *Driver[Model, Ctx]) Delete(ctx context.Context, model *Model) (*Result[Model], error) {
+	return d.Run(ctx, Request[Model]{Action: Delete, Model: model})
+}
+
+func (d *Driver[Model, Ctx]) List(ctx context.Context, model *Model, nextToken string) (*Result[Model], error) {
+	return d.Run(ctx, Request[Model]{Action: List, Model: model, NextToken: nextToken})
+}
+
+// Run invokes the handler until the operation finishes. An error is only
+// returned if the handler could not be invoked, or did not finish within
+// MaxInvocations; a failed operation is reported in the Result.
+func (d *Driver[Model, Ctx]) Run(ctx context.Context, req Request[Model]) (*Result[Model], error) {
+	payload, err := d.event(req)
+	if err != nil {
+		return nil, err
+	}
+
+	maxInvocations := d.MaxInvocations
+	if maxInvocations <= 0 {
+		maxInvocations = DefaultMaxInvocations
+	}
+
+	invoke := func(ctx context.Context, payload []byte) ([]byte, error) {
+		return cfnresource.Invoke(ctx, d.Handler, payload)
+	}
+
+	resp, invocations, err := invocation.Run(ctx, invoke, payload, maxInvocations, func(_ int, _ []byte, resp *invocation.Response) (bool, error) {
+		if resp.Status == cfnTypes.OperationStatusInProgress && d.Delay != nil && resp.CallbackDelaySeconds > 0 {
+			d.Delay(time.Duration(resp.CallbackDelaySeconds) * time.Second)
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeResult[Model](resp, invocations)
+}
+
+// event builds the invocation payload for a request
+func (d *Driver[Model, Ctx]) event(req Request[Model]) (map[string]any, error) {
+	requestData := map[string]any{
+		"callerCredentials":    placeholderCredentials,
+		"providerCredentials":  placeholderCredentials,
+		"desiredResourceTags":  req.ResourceTags,
+		"previousResourceTags": req.PreviousResourceTags,
+	}
+
+	for key, model := range map[string]*Model{"resourceProperties": req.Model, "previousResourceProperties": req.PreviousModel} {
+		if model == nil {
+			continue
+		}
+		// CloudFormation sends every value as a string
+		data, err := encoding.Marshal(model)
+		if err != nil {
+			return nil, err
+		}
+		requestData[key] = json.RawMessage(data)
+	}
+
+	if req.TypeConfiguration != nil {
+		requestData["typeConfiguration"] = req.TypeConfiguration
+	}
+
+	stack := invocation.Stack{
+		Region:            d.Region,
+		AccountID:         d.AccountID,
+		StackName:         d.StackName,
+		StackID:           d.StackID,
+		LogicalResourceID: d.LogicalResourceID,
+		ResourceType:      d.ResourceType,
+	}
+	return stack.Event(req.Action, req.ClientRequestToken, req.NextToken, requestData), nil
+}
+
+// decodeResult converts the final response of an operation into a Result
+func decodeResult[Model any](resp *invocation.Response, invocations int) (*Result[Model], error) {
+	result := &Result[Model]{
+		Status:      resp.Status,
+		ErrorCode:   cfnTypes.HandlerErrorCode(resp.ErrorCode),
+		Message:     resp.Message,
+		NextToken:   resp.NextToken,
+		Invocations: invocations,
+	}
+
+	var err error
+	if result.Model, err = decodeModel[Model](resp.ResourceModel); err != nil {
+		return nil, err
+	}
+
+	for _, raw := range resp.ResourceModels {
+		model, err := decodeModel[Model](raw)
+		if err != nil {
+			return nil, err
+		}
+		result.Models = append(result.Models, model)
+	}
+
+	return result, nil
+}
+
+// decodeModel decodes a model that was stringified by the runtime
+func decodeModel[Model any](raw json.RawMessage) (*Model, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+
+	model := new(Model)
+	if err := encoding.Unmarshal(raw, model); err != nil {
+		return nil, fmt.Errorf("invalid model in response: %w", err)
+	}
+	return model, nil
+}
+
+// placeholderCredentials are sent in place of real credentials, which
+// handlers under test are not expected to use
+var placeholderCredentials = map[string]string{
+	"accessKeyId":     "cfntest",
+	"secretAccessKey": "cfntest",
+	"sessionToken":    "cfntest",
+}
@@ -0,0 +1,133 @@
+package cfntest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cfnTypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/stretchr/testify/require"
+	"github.com/webdestroya/cfnresource"
+	"github.com/webdestroya/cfnresource/cfnerr"
+)
+
+type model struct {
+	Name  *string `json:",omitempty"`
+	Size  *int    `json:",omitempty"`
+	Arn   *string `json:",omitempty"`
+	Token *string `json:",omitempty"`
+}
+
+type callbackCtx struct {
+	Step int `json:",omitempty"`
+}
+
+type requestType = *cfnresource.Request[model, callbackCtx]
+type progEventType = *cfnresource.ProgressEvent[model, callbackCtx]
+
+// stepHandler creates resources in three invocations, and never finishes updates
+type stepHandler struct{}
+
+func (stepHandler) Create(ctx context.Context, req requestType) (progEventType, error) {
+	m := req.ResourceProperties
+
+	step := 0
+	if req.CallbackContext != nil {
+		step = req.CallbackContext.Step
+	}
+
+	switch step {
+	case 0:
+		m.Token = &req.ClientRequestToken
+		return req.InProgressResponse(m, &callbackCtx{Step: 1}), nil
+	case 1:
+		if m.Token == nil || *m.Token != req.ClientRequestToken {
+			return nil, cfnerr.NewMessage(cfnerr.InternalFailure, "model was not sent back")
+		}
+		arn := req.ARN("example", *m.Name)
+		m.Arn = &arn
+		return req.InProgressResponse(m, &callbackCtx{Step: 2}), nil
+	}
+
+	m.Token = nil
+	return req.SuccessResponse(m), nil
+}
+
+func (stepHandler) Update(ctx context.Context, req requestType) (progEventType, error) {
+	return req.InProgressResponse(req.ResourceProperties, &callbackCtx{Step: 1}), nil
+}
+
+func (stepHandler) Delete(ctx context.Context, req requestType) (progEventType, error) {
+	return req.SuccessResponse(nil), nil
+}
+
+func (stepHandler) Read(ctx context.Context, req requestType) (progEventType, error) {
+	return nil, cfnerr.NewMessage(cfnerr.NotFound, "not found")
+}
+
+func (stepHandler) List(ctx context.Context, req requestType) (progEventType, error) {
+	if req.NextToken == "" {
+		pe := req.SuccessResponse(nil).WithModels(&model{Name: ptr("a")})
+		pe.NextToken = "page2"
+		return pe, nil
+	}
+	return req.SuccessResponse(nil).WithModels(&model{Name: ptr("b")}), nil
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestDriver(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("callbacks", func(t *testing.T) {
+		var delays []time.Duration
+		d := New[model, callbackCtx](stepHandler{})
+		d.Delay = func(delay time.Duration) {
+			delays = append(delays, delay)
+		}
+
+		result, err := d.Create(ctx, &model{Name: ptr("thing"), Size: ptr(3)})
+		require.NoError(t, err)
+		require.Equal(t, cfnTypes.OperationStatusSuccess, result.Status)
+		require.Equal(t, 3, result.Invocations)
+		require.Equal(t, "arn:aws:example:us-east-1:123456789012:thing", *result.Model.Arn)
+		require.Equal(t, 3, *result.Model.Size)
+		require.Nil(t, result.Model.Token)
+		require.Len(t, delays, 2)
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		result, err := New[model, callbackCtx](stepHandler{}).Read(ctx, &model{Name: ptr("thing")})
+		require.NoError(t, err)
+		require.Equal(t, cfnTypes.OperationStatusFailed, result.Status)
+		require.Equal(t, cfnTypes.HandlerErrorCodeNotFound, result.ErrorCode)
+		require.Equal(t, "not found", result.Message)
+		require.Nil(t, result.Model)
+	})
+
+	t.Run("list", func(t *testing.T) {
+		d := New[model, callbackCtx](stepHandler{})
+
+		result, err := d.List(ctx, &model{}, "")
+		require.NoError(t, err)
+		require.Len(t, result.Models, 1)
+		require.Equal(t, "a", *result.Models[0].Name)
+		require.Equal(t, "page2", result.NextToken)
+
+		result, err = d.List(ctx, &model{}, result.NextToken)
+		require.NoError(t, err)
+		require.Len(t, result.Models, 1)
+		require.Equal(t, "b", *result.Models[0].Name)
+		require.Empty(t, result.NextToken)
+	})
+
+	t.Run("max invocations", func(t *testing.T) {
+		d := New[model, callbackCtx](stepHandler{})
+		d.MaxInvocations = 5
+
+		_, err := d.Update(ctx, &model{Name: ptr("thing")}, &model{Name: ptr("thing")})
+		require.EqualError(t, err, "UPDATE did not finish after 5 invocations")
+	})
+}
@@ -0,0 +1,125 @@
+package cfntest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/webdestroya/cfnresource"
+	"github.com/webdestroya/cfnresource/internal/invocation"
+	"github.com/webdestroya/cfnresource/record"
+)
+
+// Replay invokes the handler with each event in a recording made by
+// record.Recorder, as a subtest of t, and fails the subtest if the response
+// differs from the recorded one. The handler should use stubbed clients, so
+// that the recorded calls get the same results.
+//
+// Fields of the response that are expected to change, such as generated
+// identifiers, are ignored by listing their dotted path, such as
+// "resourceModel.Arn" or "message". Array indexes are left out of the path, so
+// "resourceModels.Arn" ignores the Arn of every listed model.
+func Replay[Model any, Ctx any](t *testing.T, handler cfnresource.Handler[Model, Ctx], path string, ignoreFields ...string) {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("cfntest: unable to open recording: %v", err)
+	}
+	defer f.Close()
+
+	entries, err := record.Read(f)
+	if err != nil {
+		t.Fatalf("cfntest: unable to read recording %s: %v", path, err)
+	}
+
+	for i, entry := range entries {
+		t.Run(entryName(i, entry), func(t *testing.T) {
+			diff, err := replayEntry(context.Background(), handler, entry, ignoreFields)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff != "" {
+				t.Errorf("response differs from the recording (-recorded +replayed):\n%s", diff)
+			}
+		})
+	}
+}
+
+// replayEntry invokes the handler with a recorded event and returns the
+// difference between the responses
+func replayEntry[Model any, Ctx any](ctx context.Context, handler cfnresource.Handler[Model, Ctx], entry record.Entry, ignoreFields []string) (string, error) {
+	var event map[string]any
+	if err := json.Unmarshal(entry.Event, &event); err != nil {
+		return "", fmt.Errorf("invalid recorded event: %w", err)
+	}
+	restoreSecrets(event)
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return "", err
+	}
+
+	out, err := cfnresource.Invoke(ctx, handler, payload)
+	if err != nil {
+		return "", err
+	}
+
+	var recorded, replayed any
+	if err := json.Unmarshal(entry.Response, &recorded); err != nil {
+		return "", fmt.Errorf("invalid recorded response: %w", err)
+	}
+	if err := json.Unmarshal(out, &replayed); err != nil {
+		return "", err
+	}
+
+	ignored := map[string]bool{"bearerToken": true}
+	for _, field := range ignoreFields {
+		ignored[field] = true
+	}
+
+	return cmp.Diff(recorded, replayed, cmp.FilterPath(func(p cmp.Path) bool {
+		return ignored[fieldPath(p)]
+	}, cmp.Ignore())), nil
+}
+
+// restoreSecrets puts placeholders in place of the redacted values the
+// runtime requires
+func restoreSecrets(event map[string]any) {
+	if event["bearerToken"] == record.Redacted {
+		event["bearerToken"] = invocation.RandomToken()
+	}
+
+	data, ok := event["requestData"].(map[string]any)
+	if !ok {
+		return
+	}
+	for key, v := range data {
+		if strings.HasSuffix(key, "Credentials") && v == record.Redacted {
+			data[key] = placeholderCredentials
+		}
+	}
+}
+
+// fieldPath returns the dotted path of the map keys in p
+func fieldPath(p cmp.Path) string {
+	var keys []string
+	for _, step := range p {
+		if mi, ok := step.(cmp.MapIndex); ok {
+			keys = append(keys, fmt.Sprint(mi.Key().Interface()))
+		}
+	}
+	return strings.Join(keys, ".")
+}
+
+func entryName(i int, entry record.Entry) string {
+	var event struct {
+		Action string `json:"action"`
+	}
+	_ = json.Unmarshal(entry.Event, &event)
+	return fmt.Sprintf("%d_%s", i+1, event.Action)
+}
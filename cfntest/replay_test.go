@@ -0,0 +1,63 @@
+package cfntest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/webdestroya/cfnresource/record"
+)
+
+// countingHandler gives each resource it creates a new ARN
+type countingHandler struct {
+	stepHandler
+	*record.Recorder[model, callbackCtx]
+	count int
+}
+
+func (h *countingHandler) Create(ctx context.Context, req requestType) (progEventType, error) {
+	h.count++
+	arn := fmt.Sprintf("arn:thing/%d", h.count)
+	req.ResourceProperties.Arn = &arn
+	return req.SuccessResponse(req.ResourceProperties), nil
+}
+
+func TestReplay(t *testing.T) {
+	ctx := context.Background()
+	recording := &bytes.Buffer{}
+
+	d := New[model, callbackCtx](&countingHandler{Recorder: record.New[model, callbackCtx](recording, nil)})
+	_, err := d.Create(ctx, &model{Name: ptr("thing")})
+	require.NoError(t, err)
+	_, err = d.Read(ctx, &model{Name: ptr("thing")})
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "recording.jsonl")
+	require.NoError(t, os.WriteFile(path, recording.Bytes(), 0o600))
+
+	// the first replayed create gets the same ARN as the recorded one
+	Replay[model, callbackCtx](t, &countingHandler{}, path)
+
+	entries, err := record.Read(bytes.NewReader(recording.Bytes()))
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	replayed := &countingHandler{count: 5}
+
+	diff, err := replayEntry[model, callbackCtx](ctx, replayed, entries[0], nil)
+	require.NoError(t, err)
+	require.Contains(t, diff, "arn:thing/1")
+	require.Contains(t, diff, "arn:thing/6")
+
+	diff, err = replayEntry[model, callbackCtx](ctx, replayed, entries[0], []string{"resourceModel.Arn"})
+	require.NoError(t, err)
+	require.Empty(t, diff)
+
+	diff, err = replayEntry[model, callbackCtx](ctx, replayed, entries[1], nil)
+	require.NoError(t, err)
+	require.Empty(t, diff)
+}
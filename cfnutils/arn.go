@@ -0,0 +1,283 @@
+package cfnutils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+)
+
+// ErrInvalidARN is wrapped by every ARN parsing error
+var ErrInvalidARN = errors.New("invalid ARN")
+
+const cloudformationService = "cloudformation"
+
+// StackARN is a parsed stack ARN, such as
+// arn:aws:cloudformation:us-east-1:123456789012:stack/MyStack/e722ae60-fe62-11e8-9a0e-0ae8cc519968
+type StackARN struct {
+	Partition string
+	Region    string
+	AccountID string
+	Name      string
+	ID        string
+}
+
+func (a StackARN) String() string {
+	return buildCFNARN(a.Partition, a.Region, a.AccountID, "stack/"+a.Name+"/"+a.ID)
+}
+
+// ParseStackARN parses a stack ARN
+func ParseStackARN(value string) (StackARN, error) {
+	v, parts, err := parseCFNARN(value, "stack", 3)
+	if err != nil {
+		return StackARN{}, err
+	}
+	return StackARN{Partition: v.Partition, Region: v.Region, AccountID: v.AccountID, Name: parts[1], ID: parts[2]}, nil
+}
+
+// ChangeSetARN is a parsed change set ARN, such as
+// arn:aws:cloudformation:us-east-1:123456789012:changeSet/MyChanges/1a2b3c4d-5e6f-7a8b-9c0d-1e2f3a4b5c6d
+type ChangeSetARN struct {
+	Partition string
+	Region    string
+	AccountID string
+	Name      string
+	ID        string
+}
+
+func (a ChangeSetARN) String() string {
+	return buildCFNARN(a.Partition, a.Region, a.AccountID, "changeSet/"+a.Name+"/"+a.ID)
+}
+
+// ParseChangeSetARN parses a change set ARN
+func ParseChangeSetARN(value string) (ChangeSetARN, error) {
+	v, parts, err := parseCFNARN(value, "changeSet", 3)
+	if err != nil {
+		return ChangeSetARN{}, err
+	}
+	return ChangeSetARN{Partition: v.Partition, Region: v.Region, AccountID: v.AccountID, Name: parts[1], ID: parts[2]}, nil
+}
+
+// TypeARN is a parsed extension type ARN, such as
+// arn:aws:cloudformation:us-east-1:123456789012:type/resource/Org-Svc-Res/00000001
+//
+// Version is empty when the ARN refers to the type rather than one version of
+// it, and AccountID is empty for public AWS types.
+type TypeARN struct {
+	Partition string
+	Region    string
+	AccountID string
+
+	// Category is resource, hook or module
+	Category string
+
+	// TypeName is in its usual form, such as Org::Svc::Res
+	TypeName string
+
+	Version string
+}
+
+func (a TypeARN) String() string {
+	resource := "type/" + a.Category + "/" + strings.ReplaceAll(a.TypeName, "::", "-")
+	if a.Version != "" {
+		resource += "/" + a.Version
+	}
+	return buildCFNARN(a.Partition, a.Region, a.AccountID, resource)
+}
+
+// ParseTypeARN parses an extension type ARN, with or without a version
+func ParseTypeARN(value string) (TypeARN, error) {
+	v, parts, err := parseCFNARN(value, "type", 0)
+	if err != nil {
+		return TypeARN{}, err
+	}
+	if len(parts) != 3 && len(parts) != 4 {
+		return TypeARN{}, fmt.Errorf("%w: %q is not a type ARN", ErrInvalidARN, value)
+	}
+
+	a := TypeARN{
+		Partition: v.Partition,
+		Region:    v.Region,
+		AccountID: v.AccountID,
+		Category:  parts[1],
+		TypeName:  strings.ReplaceAll(parts[2], "-", "::"),
+	}
+	if len(parts) == 4 {
+		a.Version = parts[3]
+	}
+	return a, nil
+}
+
+// StackSetARN is a parsed stack set ARN, such as
+// arn:aws:cloudformation:us-east-1:123456789012:stackset/MyStackSet:1a2b3c4d-5e6f-7a8b-9c0d-1e2f3a4b5c6d
+type StackSetARN struct {
+	Partition string
+	Region    string
+	AccountID string
+	Name      string
+	ID        string
+}
+
+func (a StackSetARN) String() string {
+	return buildCFNARN(a.Partition, a.Region, a.AccountID, "stackset/"+a.Name+":"+a.ID)
+}
+
+// StackSetID returns the stack set ID, in the form name:uuid
+func (a StackSetARN) StackSetID() string {
+	return a.Name + ":" + a.ID
+}
+
+// ParseStackSetARN parses a stack set ARN
+func ParseStackSetARN(value string) (StackSetARN, error) {
+	v, parts, err := parseCFNARN(value, "stackset", 2)
+	if err != nil {
+		return StackSetARN{}, err
+	}
+
+	name, id, err := ParseStackSetID(parts[1])
+	if err != nil {
+		return StackSetARN{}, fmt.Errorf("%w: %q: %w", ErrInvalidARN, value, err)
+	}
+	return StackSetARN{Partition: v.Partition, Region: v.Region, AccountID: v.AccountID, Name: name, ID: id}, nil
+}
+
+// StackInstanceARN is a parsed stack instance ARN, which identifies the stack
+// that a stack set deployed to one account and region, such as
+// arn:aws:cloudformation:us-east-1:123456789012:stackset/MyStackSet:1a2b3c4d-5e6f-7a8b-9c0d-1e2f3a4b5c6d/stackinstance/210987654321/eu-west-1
+type StackInstanceARN struct {
+	// StackSet is the stack set that the instance belongs to
+	StackSet StackSetARN
+
+	// AccountID and Region are where the stack instance is deployed
+	AccountID string
+	Region    string
+}
+
+func (a StackInstanceARN) String() string {
+	return a.StackSet.String() + "/stackinstance/" + a.AccountID + "/" + a.Region
+}
+
+// ParseStackInstanceARN parses a stack instance ARN
+func ParseStackInstanceARN(value string) (StackInstanceARN, error) {
+	v, parts, err := parseCFNARN(value, "stackset", 5)
+	if err != nil {
+		return StackInstanceARN{}, err
+	}
+	if parts[2] != "stackinstance" {
+		return StackInstanceARN{}, fmt.Errorf("%w: %q is not a stack instance ARN", ErrInvalidARN, value)
+	}
+
+	name, id, err := ParseStackSetID(parts[1])
+	if err != nil {
+		return StackInstanceARN{}, fmt.Errorf("%w: %q: %w", ErrInvalidARN, value, err)
+	}
+	return StackInstanceARN{
+		StackSet:  StackSetARN{Partition: v.Partition, Region: v.Region, AccountID: v.AccountID, Name: name, ID: id},
+		AccountID: parts[3],
+		Region:    parts[4],
+	}, nil
+}
+
+// ParseStackSetID splits a stack set ID, such as MyStackSet:1a2b3c4d-5e6f-7a8b-9c0d-1e2f3a4b5c6d,
+// into the stack set name and its UUID
+func ParseStackSetID(value string) (name string, id string, err error) {
+	name, id, ok := strings.Cut(value, ":")
+	if !ok || name == "" || id == "" {
+		return "", "", fmt.Errorf("invalid stack set ID %q", value)
+	}
+	return name, id, nil
+}
+
+// parseCFNARN parses a CloudFormation ARN whose resource starts with kind,
+// and returns the resource split on '/'. If count is not zero, the resource
+// must have exactly that many parts.
+func parseCFNARN(value string, kind string, count int) (arn.ARN, []string, error) {
+	v, err := arn.Parse(value)
+	if err != nil {
+		return arn.ARN{}, nil, fmt.Errorf("%w: %q: %w", ErrInvalidARN, value, err)
+	}
+	if v.Service != cloudformationService {
+		return arn.ARN{}, nil, fmt.Errorf("%w: %q is not a CloudFormation ARN", ErrInvalidARN, value)
+	}
+
+	parts := strings.Split(v.Resource, "/")
+	if parts[0] != kind || (count > 0 && len(parts) != count) || containsEmpty(parts) {
+		return arn.ARN{}, nil, fmt.Errorf("%w: %q is not a %s ARN", ErrInvalidARN, value, kind)
+	}
+
+	return v, parts, nil
+}
+
+func containsEmpty(parts []string) bool {
+	for _, p := range parts {
+		if p == "" {
+			return true
+		}
+	}
+	return false
+}
+
+func buildCFNARN(partition string, region string, accountID string, resource string) string {
+	return arn.ARN{
+		Partition: partition,
+		Service:   cloudformationService,
+		Region:    region,
+		AccountID: accountID,
+		Resource:  resource,
+	}.String()
+}
+
+// partitionPrefixes maps region prefixes to their partition. The first match wins.
+var partitionPrefixes = []struct {
+	prefix    string
+	partition string
+}{
+	{"cn-", "aws-cn"},
+	{"us-gov-", "aws-us-gov"},
+	{"us-isob-", "aws-iso-b"},
+	{"us-iso-", "aws-iso"},
+	{"eu-isoe-", "aws-iso-e"},
+	{"us-isof-", "aws-iso-f"},
+}
+
+// PartitionForRegion returns the partition that a region belongs to, which
+// is "aws" for all commercial regions
+func PartitionForRegion(region string) string {
+	for _, p := range partitionPrefixes {
+		if strings.HasPrefix(region, p.prefix) {
+			return p.partition
+		}
+	}
+	return "aws"
+}
+
+// BuildARN returns an ARN for a resource in the given region, using the
+// region's partition.
+//
+//	cfnutils.BuildARN("cn-north-1", "123456789012", "sqs", "my-queue")
+//	// arn:aws-cn:sqs:cn-north-1:123456789012:my-queue
+func BuildARN(region string, accountID string, service string, resource string) string {
+	return arn.ARN{
+		Partition: PartitionForRegion(region),
+		Service:   service,
+		Region:    region,
+		AccountID: accountID,
+		Resource:  resource,
+	}.String()
+}
+
+// BuildGlobalARN returns an ARN without a region, for global services such as
+// IAM, using the partition of the given region. Pass an empty account for
+// services that omit it, such as S3.
+//
+//	cfnutils.BuildGlobalARN("us-gov-west-1", "123456789012", "iam", "role/MyRole")
+//	// arn:aws-us-gov:iam::123456789012:role/MyRole
+func BuildGlobalARN(region string, accountID string, service string, resource string) string {
+	return arn.ARN{
+		Partition: PartitionForRegion(region),
+		Service:   service,
+		AccountID: accountID,
+		Resource:  resource,
+	}.String()
+}
@@ -0,0 +1,130 @@
+package cfnutils_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/webdestroya/cfnresource/cfnutils"
+)
+
+func TestParseARNs(t *testing.T) {
+	t.Run("stack", func(t *testing.T) {
+		value := "arn:aws-cn:cloudformation:cn-north-1:123456789012:stack/MyStack/e722ae60-fe62-11e8-9a0e-0ae8cc519968"
+		a, err := cfnutils.ParseStackARN(value)
+		require.NoError(t, err)
+		require.Equal(t, cfnutils.StackARN{
+			Partition: "aws-cn",
+			Region:    "cn-north-1",
+			AccountID: "123456789012",
+			Name:      "MyStack",
+			ID:        "e722ae60-fe62-11e8-9a0e-0ae8cc519968",
+		}, a)
+		require.Equal(t, value, a.String())
+		require.Equal(t, "MyStack", cfnutils.GetStackNameFromArn(value))
+	})
+
+	t.Run("change set", func(t *testing.T) {
+		value := "arn:aws:cloudformation:us-east-1:123456789012:changeSet/MyChanges/1a2b3c4d"
+		a, err := cfnutils.ParseChangeSetARN(value)
+		require.NoError(t, err)
+		require.Equal(t, "MyChanges", a.Name)
+		require.Equal(t, "1a2b3c4d", a.ID)
+		require.Equal(t, value, a.String())
+	})
+
+	t.Run("type", func(t *testing.T) {
+		value := "arn:aws:cloudformation:us-east-1:123456789012:type/resource/Org-Svc-Res/00000001"
+		a, err := cfnutils.ParseTypeARN(value)
+		require.NoError(t, err)
+		require.Equal(t, "resource", a.Category)
+		require.Equal(t, "Org::Svc::Res", a.TypeName)
+		require.Equal(t, "00000001", a.Version)
+		require.Equal(t, value, a.String())
+
+		a, err = cfnutils.ParseTypeARN("arn:aws:cloudformation:us-east-1::type/hook/AWS-Example-Hook")
+		require.NoError(t, err)
+		require.Empty(t, a.AccountID)
+		require.Empty(t, a.Version)
+		require.Equal(t, "AWS::Example::Hook", a.TypeName)
+	})
+
+	t.Run("stack set", func(t *testing.T) {
+		value := "arn:aws:cloudformation:us-east-1:123456789012:stackset/MySet:1a2b3c4d"
+		a, err := cfnutils.ParseStackSetARN(value)
+		require.NoError(t, err)
+		require.Equal(t, "MySet", a.Name)
+		require.Equal(t, "1a2b3c4d", a.ID)
+		require.Equal(t, "MySet:1a2b3c4d", a.StackSetID())
+		require.Equal(t, value, a.String())
+
+		_, _, err = cfnutils.ParseStackSetID("MySet")
+		require.Error(t, err)
+	})
+
+	t.Run("stack instance", func(t *testing.T) {
+		value := "arn:aws:cloudformation:us-east-1:123456789012:stackset/MySet:1a2b3c4d/stackinstance/210987654321/eu-west-1"
+		a, err := cfnutils.ParseStackInstanceARN(value)
+		require.NoError(t, err)
+		require.Equal(t, cfnutils.StackInstanceARN{
+			StackSet: cfnutils.StackSetARN{
+				Partition: "aws",
+				Region:    "us-east-1",
+				AccountID: "123456789012",
+				Name:      "MySet",
+				ID:        "1a2b3c4d",
+			},
+			AccountID: "210987654321",
+			Region:    "eu-west-1",
+		}, a)
+		require.Equal(t, value, a.String())
+
+		for _, value := range []string{
+			"arn:aws:cloudformation:us-east-1:123456789012:stackset/MySet:1a2b3c4d",
+			"arn:aws:cloudformation:us-east-1:123456789012:stackset/MySet:1a2b3c4d/stackinstance/210987654321",
+			"arn:aws:cloudformation:us-east-1:123456789012:stackset/MySet:1a2b3c4d/other/210987654321/eu-west-1",
+			"arn:aws:cloudformation:us-east-1:123456789012:stackset/MySet/stackinstance/210987654321/eu-west-1",
+		} {
+			_, err := cfnutils.ParseStackInstanceARN(value)
+			require.ErrorIs(t, err, cfnutils.ErrInvalidARN, value)
+		}
+
+		// a stack set ARN does not match an instance
+		_, err = cfnutils.ParseStackSetARN(value)
+		require.ErrorIs(t, err, cfnutils.ErrInvalidARN)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, value := range []string{
+			"",
+			"not-an-arn",
+			"arn:aws:s3:::bucket/key",
+			"arn:aws:cloudformation:us-east-1:123456789012:stack/MyStack",
+			"arn:aws:cloudformation:us-east-1:123456789012:stack//id",
+			"arn:aws:cloudformation:us-east-1:123456789012:changeSet/a/b",
+		} {
+			_, err := cfnutils.ParseStackARN(value)
+			require.ErrorIs(t, err, cfnutils.ErrInvalidARN, value)
+		}
+
+		_, err := cfnutils.ParseTypeARN("arn:aws:cloudformation:us-east-1:123456789012:type/resource")
+		require.ErrorIs(t, err, cfnutils.ErrInvalidARN)
+	})
+}
+
+func TestBuildARN(t *testing.T) {
+	tests := map[string]string{
+		"us-east-1":      "aws",
+		"cn-northwest-1": "aws-cn",
+		"us-gov-west-1":  "aws-us-gov",
+		"us-iso-east-1":  "aws-iso",
+		"us-isob-east-1": "aws-iso-b",
+		"":               "aws",
+	}
+	for region, partition := range tests {
+		require.Equal(t, partition, cfnutils.PartitionForRegion(region), region)
+	}
+
+	require.Equal(t, "arn:aws-cn:sqs:cn-north-1:123456789012:my-queue", cfnutils.BuildARN("cn-north-1", "123456789012", "sqs", "my-queue"))
+	require.Equal(t, "arn:aws-us-gov:iam::123456789012:role/MyRole", cfnutils.BuildGlobalARN("us-gov-west-1", "123456789012", "iam", "role/MyRole"))
+	require.Equal(t, "arn:aws:s3:::my-bucket", cfnutils.BuildGlobalARN("eu-west-1", "", "s3", "my-bucket"))
+}
@@ -0,0 +1,249 @@
+package cfnutils
+
+import (
+	"crypto/sha256"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// NameCase controls the casing of a generated name
+type NameCase int
+
+const (
+	CasePreserve NameCase = iota
+	CaseLower
+	CaseUpper
+)
+
+// CharsetAlphanumeric is the default set of characters allowed in generated names
+const CharsetAlphanumeric = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// NameOptions controls the names made by GenerateName
+type NameOptions struct {
+	// MaxLength is the longest name the service allows, in bytes. Names are
+	// only shortened between characters, so a name with multibyte characters
+	// may end up shorter. Zero means no limit.
+	MaxLength int
+
+	// Charset lists the characters the service allows, not counting the
+	// separator. Other characters are removed from the stack name and logical ID.
+	Charset string
+
+	// Case is applied before the characters are filtered
+	Case NameCase
+
+	// Separator is placed between the parts of the name
+	Separator string
+
+	// SuffixLength is the length of the random looking suffix. Zero uses the default.
+	SuffixLength int
+}
+
+// DefaultNameOptions are used when GenerateName is called with nil options
+var DefaultNameOptions = NameOptions{
+	MaxLength:    64,
+	Charset:      CharsetAlphanumeric,
+	Separator:    "-",
+	SuffixLength: 12,
+}
+
+// NameInput are the parts of a request that a generated name is made from
+type NameInput struct {
+	StackName          string
+	StackID            string
+	LogicalResourceID  string
+	ClientRequestToken string
+}
+
+// NameSource is implemented by cfnresource.Request
+type NameSource interface {
+	NameInput() NameInput
+}
+
+// GenerateName makes a physical resource name in the CloudFormation style of
+// {stack}-{logicalId}-{SUFFIX}. The suffix is derived from the client request
+// token, stack ID and logical ID, so every invocation of the same operation,
+// including retries and callbacks, produces the same name.
+//
+// When the name would be longer than MaxLength, the stack name and logical ID
+// are shortened, sharing the space that is left after the suffix. The suffix
+// is only shortened when MaxLength leaves no room for anything else.
+func GenerateName(src NameSource, opts *NameOptions) string {
+	if opts == nil {
+		opts = &DefaultNameOptions
+	}
+
+	in := src.NameInput()
+
+	charset := applyCase(opts.Charset, opts.Case)
+	if charset == "" {
+		charset = applyCase(CharsetAlphanumeric, opts.Case)
+	}
+
+	suffixLength := opts.SuffixLength
+	if suffixLength <= 0 {
+		suffixLength = DefaultNameOptions.SuffixLength
+	}
+
+	sep := opts.Separator
+	stack := cleanNamePart(in.StackName, charset, opts.Case, sep)
+	logical := cleanNamePart(in.LogicalResourceID, charset, opts.Case, sep)
+	suffix := nameSuffix(in, suffixAlphabet(charset), suffixLength)
+
+	if opts.MaxLength > 0 {
+		if len(suffix) >= opts.MaxLength {
+			return truncate(suffix, opts.MaxLength)
+		}
+		stack, logical = fitNameParts(stack, logical, opts.MaxLength-len(suffix), sep)
+	}
+
+	parts := make([]string, 0, 3)
+	for _, p := range []string{stack, logical, suffix} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, sep)
+}
+
+// fitNameParts shortens the stack and logical ID so that they, and the
+// separators needed to join them to the suffix, fit in available
+func fitNameParts(stack string, logical string, available int, sep string) (string, string) {
+	fits := func(s, l string) bool {
+		n := 0
+		for _, p := range []string{s, l} {
+			if p != "" {
+				n += len(p) + len(sep)
+			}
+		}
+		return n <= available
+	}
+
+	if fits(stack, logical) {
+		return stack, logical
+	}
+
+	switch {
+	case logical == "":
+		return trimNamePart(stack, available-len(sep), sep), ""
+	case stack == "":
+		return "", trimNamePart(logical, available-len(sep), sep)
+	}
+
+	// leave room for both separators, then split the rest evenly, giving any
+	// space the shorter part does not need to the longer one
+	room := available - 2*len(sep)
+	if room < 2 {
+		// only one part fits, so keep the stack name
+		return trimNamePart(stack, available-len(sep), sep), ""
+	}
+
+	half := room / 2
+	switch {
+	case len(stack) <= half:
+		logical = trimNamePart(logical, room-len(stack), sep)
+	case len(logical) <= half:
+		stack = trimNamePart(stack, room-len(logical), sep)
+	default:
+		stack = trimNamePart(stack, room-half, sep)
+		logical = trimNamePart(logical, half, sep)
+	}
+
+	return stack, logical
+}
+
+// trimNamePart truncates s to at most n bytes, without leaving a dangling separator
+func trimNamePart(s string, n int, sep string) string {
+	if n <= 0 {
+		return ""
+	}
+	s = truncate(s, n)
+	if sep != "" {
+		s = strings.TrimRight(s, sep)
+	}
+	return s
+}
+
+// truncate shortens s to at most n bytes without splitting a character
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// cleanNamePart applies the casing and removes disallowed characters
+func cleanNamePart(s string, charset string, c NameCase, sep string) string {
+	s = applyCase(s, c)
+
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(charset, r) || (sep != "" && strings.ContainsRune(sep, r)) {
+			b.WriteRune(r)
+		}
+	}
+
+	out := b.String()
+	if sep != "" {
+		out = strings.Trim(out, sep)
+	}
+	return out
+}
+
+func applyCase(s string, c NameCase) string {
+	switch c {
+	case CaseLower:
+		return strings.ToLower(s)
+	case CaseUpper:
+		return strings.ToUpper(s)
+	}
+	return s
+}
+
+// suffixAlphabet returns the letters and digits of the charset, preferring
+// upper case like CloudFormation does when the charset allows it
+func suffixAlphabet(charset string) string {
+	var upper, other strings.Builder
+	for _, r := range charset {
+		switch {
+		case r > unicode.MaxASCII:
+		case unicode.IsUpper(r) || unicode.IsDigit(r):
+			upper.WriteRune(r)
+			fallthrough
+		case unicode.IsLetter(r):
+			other.WriteRune(r)
+		}
+	}
+
+	if upper.Len() > 10 {
+		return upper.String()
+	}
+	if other.Len() > 0 {
+		return other.String()
+	}
+	return charset
+}
+
+// nameSuffix derives a suffix of length n from the request
+func nameSuffix(in NameInput, alphabet string, n int) string {
+	seed := []byte(strings.Join([]string{in.ClientRequestToken, in.StackID, in.LogicalResourceID}, "\x00"))
+	letters := []rune(alphabet)
+
+	var b strings.Builder
+	for count := 0; count < n; {
+		sum := sha256.Sum256(seed)
+		for _, c := range sum {
+			if count == n {
+				break
+			}
+			b.WriteRune(letters[int(c)%len(letters)])
+			count++
+		}
+		seed = sum[:]
+	}
+	return b.String()
+}
@@ -0,0 +1,121 @@
+package cfnutils_test
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+	"github.com/webdestroya/cfnresource/cfnutils"
+)
+
+type nameSource cfnutils.NameInput
+
+func (n nameSource) NameInput() cfnutils.NameInput {
+	return cfnutils.NameInput(n)
+}
+
+var testSource = nameSource{
+	StackName:          "my-stack",
+	StackID:            "arn:aws:cloudformation:us-east-1:123456789012:stack/my-stack/e722ae60-fe62-11e8-9a0e-0ae8cc519968",
+	LogicalResourceID:  "MyBucket",
+	ClientRequestToken: "4b90a7e4-b790-456b-a937-0cfdfa211dfe",
+}
+
+func TestGenerateName(t *testing.T) {
+	name := cfnutils.GenerateName(testSource, nil)
+	require.Regexp(t, `^my-stack-MyBucket-[A-Z0-9]{12}$`, name)
+
+	// the same request always produces the same name
+	require.Equal(t, name, cfnutils.GenerateName(testSource, nil))
+
+	// a different operation produces a different name
+	other := testSource
+	other.ClientRequestToken = "another"
+	require.NotEqual(t, name, cfnutils.GenerateName(other, nil))
+
+	tests := []struct {
+		name  string
+		src   nameSource
+		opts  cfnutils.NameOptions
+		match string
+	}{
+		{
+			name:  "lower case with dots",
+			src:   nameSource{StackName: "My_Stack", LogicalResourceID: "Queue"},
+			opts:  cfnutils.NameOptions{Charset: "abcdefghijklmnopqrstuvwxyz0123456789", Case: cfnutils.CaseLower, Separator: ".", SuffixLength: 6},
+			match: `^mystack\.queue\.[a-z0-9]{6}$`,
+		},
+		{
+			name:  "no separator",
+			src:   nameSource{StackName: "stack", LogicalResourceID: "Role"},
+			opts:  cfnutils.NameOptions{Charset: cfnutils.CharsetAlphanumeric},
+			match: `^stackRole[A-Z0-9]{12}$`,
+		},
+		{
+			name:  "long logical id is shortened",
+			src:   nameSource{StackName: "stack", LogicalResourceID: strings.Repeat("L", 100)},
+			opts:  cfnutils.NameOptions{MaxLength: 40, Separator: "-"},
+			match: `^stack-L{21}-[A-Z0-9]{12}$`,
+		},
+		{
+			name:  "both long share the space",
+			src:   nameSource{StackName: strings.Repeat("s", 100), LogicalResourceID: strings.Repeat("L", 100)},
+			opts:  cfnutils.NameOptions{MaxLength: 41, Separator: "-"},
+			match: `^s{14}-L{13}-[A-Z0-9]{12}$`,
+		},
+		{
+			name:  "no dangling separator",
+			src:   nameSource{StackName: "abcd-efgh-ijkl", LogicalResourceID: "Thing"},
+			opts:  cfnutils.NameOptions{MaxLength: 25, Separator: "-", SuffixLength: 8},
+			match: `^abcd-efgh-Thing-[A-Z0-9]{8}$`,
+		},
+		{
+			name:  "only the stack fits",
+			src:   nameSource{StackName: "stack", LogicalResourceID: "Thing"},
+			opts:  cfnutils.NameOptions{MaxLength: 15, Separator: "-", SuffixLength: 12},
+			match: `^st-[A-Z0-9]{12}$`,
+		},
+		{
+			name:  "one character each",
+			src:   nameSource{StackName: "stack", LogicalResourceID: "Thing"},
+			opts:  cfnutils.NameOptions{MaxLength: 16, Separator: "-", SuffixLength: 12},
+			match: `^s-T-[A-Z0-9]{12}$`,
+		},
+		{
+			name:  "only the suffix fits",
+			src:   nameSource{StackName: "stack", LogicalResourceID: "Thing"},
+			opts:  cfnutils.NameOptions{MaxLength: 10, Separator: "-", SuffixLength: 12},
+			match: `^[A-Z0-9]{10}$`,
+		},
+		{
+			name:  "missing stack name",
+			src:   nameSource{LogicalResourceID: strings.Repeat("L", 30)},
+			opts:  cfnutils.NameOptions{MaxLength: 20, Separator: "-", SuffixLength: 4},
+			match: `^L{15}-[A-Z0-9]{4}$`,
+		},
+		{
+			name:  "multibyte characters are not split",
+			src:   nameSource{StackName: strings.Repeat("é", 10)},
+			opts:  cfnutils.NameOptions{Charset: cfnutils.CharsetAlphanumeric + "é", MaxLength: 20, Separator: "-", SuffixLength: 4},
+			match: `^é{7}-[A-Z0-9]{4}$`,
+		},
+		{
+			name:  "multibyte suffix",
+			src:   nameSource{StackName: "stack"},
+			opts:  cfnutils.NameOptions{Charset: "αβγ", MaxLength: 7, SuffixLength: 5},
+			match: `^[αβγ]{3}$`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := cfnutils.GenerateName(tt.src, &tt.opts)
+			require.Regexp(t, tt.match, name)
+			require.True(t, utf8.ValidString(name))
+			if tt.opts.MaxLength > 0 {
+				require.LessOrEqual(t, len(name), tt.opts.MaxLength)
+			}
+		})
+	}
+}
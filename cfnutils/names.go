@@ -10,6 +10,9 @@ const (
 	stackArnPrefix = `stack/`
 )
 
+// GetStackNameFromArn returns the stack name from a stack ARN, or an empty
+// string if it is not one. Use ParseStackARN to find out why a value could not
+// be parsed.
 func GetStackNameFromArn(value string) string {
 	if !arn.IsARN(value) {
 		return ""
@@ -0,0 +1,105 @@
+// Package cli runs a handler from the command line, so that it can be invoked
+// locally with event files instead of being deployed to Lambda.
+//
+// The handler's main registers it, and hands over to Main when it is run with
+// arguments:
+//
+//	func main() {
+//		handler := resource.New()
+//		if len(os.Args) > 1 {
+//			cli.Register[resource.Model, resource.CallbackContext](handler)
+//			cli.Main()
+//		}
+//		cfnresource.Start[resource.Model, resource.CallbackContext](handler)
+//	}
+//
+// It can then be invoked with go run:
+//
+//	go run . invoke --action CREATE --properties props.json --loop
+//
+// The init command generates such a project for a new resource type, from
+// templates embedded in the binary.
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/webdestroya/cfnresource"
+)
+
+// invoker runs a registered handler with an invocation payload
+type invoker func(ctx context.Context, payload []byte) ([]byte, error)
+
+var registered invoker
+
+// Register makes handler the one that the invoke command runs
+func Register[Model any, Ctx any](handler cfnresource.Handler[Model, Ctx]) {
+	registered = func(ctx context.Context, payload []byte) ([]byte, error) {
+		// the event has no credentials, so the handler uses the local ones
+		return cfnresource.Invoke(ctx, handler, payload, func(o *cfnresource.RuntimeOptions) {
+			o.AllowDefaultCredentials = true
+		})
+	}
+}
+
+// command is a subcommand of the CLI
+type command struct {
+	summary string
+	run     func(ctx context.Context, args []string, stdout io.Writer, stderr io.Writer) int
+}
+
+var commands = map[string]command{
+	"invoke": {summary: "invoke the registered handler with a locally built event", run: runInvoke},
+	"event":  {summary: "print the event that invoke would send, without invoking", run: runEvent},
+	"init":   {summary: "generate a project for a new resource type", run: runInit},
+}
+
+// Exit codes returned by Run
+const (
+	ExitOK     = 0
+	ExitFailed = 1
+	ExitUsage  = 2
+)
+
+// Main runs the command named by the program's arguments and exits
+func Main() {
+	os.Exit(Run(context.Background(), os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// Run runs the command named by args[0] and returns the exit code. ExitFailed
+// is returned when the handler reports a FAILED operation.
+func Run(ctx context.Context, args []string, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		usage(stderr)
+		return ExitUsage
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n\n", args[0])
+		usage(stderr)
+		return ExitUsage
+	}
+	return cmd.run(ctx, args[1:], stdout, stderr)
+}
+
+func usage(w io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("Usage: <command> [flags]\n\nCommands:\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "  %-8s %s\n", name, commands[name].summary)
+	}
+	b.WriteString("\nRun <command> -h for the flags of a command.\n")
+	fmt.Fprint(w, b.String())
+}
@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/webdestroya/cfnresource"
+	"github.com/webdestroya/cfnresource/cfnerr"
+)
+
+type model struct {
+	Name *string `json:",omitempty"`
+	Arn  *string `json:",omitempty"`
+}
+
+type callbackCtx struct {
+	Step int `json:",omitempty"`
+}
+
+type requestType = *cfnresource.Request[model, callbackCtx]
+type progEventType = *cfnresource.ProgressEvent[model, callbackCtx]
+
+// twoStepHandler takes a callback to create a resource
+type twoStepHandler struct{}
+
+func (twoStepHandler) Create(ctx context.Context, req requestType) (progEventType, error) {
+	if req.CallbackContext == nil {
+		return req.InProgressResponse(req.ResourceProperties, &callbackCtx{Step: 1}), nil
+	}
+	arn := req.ARN("example", *req.ResourceProperties.Name)
+	req.ResourceProperties.Arn = &arn
+	return req.SuccessResponse(req.ResourceProperties), nil
+}
+
+func (twoStepHandler) Update(ctx context.Context, req requestType) (progEventType, error) {
+	return nil, cfnerr.NewMessage(cfnerr.NotUpdatable, "cannot update")
+}
+
+func (twoStepHandler) Delete(ctx context.Context, req requestType) (progEventType, error) {
+	return req.SuccessResponse(nil), nil
+}
+
+func (twoStepHandler) Read(ctx context.Context, req requestType) (progEventType, error) {
+	return req.SuccessResponse(req.ResourceProperties), nil
+}
+
+func (twoStepHandler) List(ctx context.Context, req requestType) (progEventType, error) {
+	return req.SuccessResponse(nil).WithModels(), nil
+}
+
+func writeFile(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func run(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := Run(context.Background(), args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+// responses splits the indented responses printed by invoke
+func responses(t *testing.T, out string) []map[string]any {
+	var list []map[string]any
+	dec := json.NewDecoder(bytes.NewBufferString(out))
+	for dec.More() {
+		var resp map[string]any
+		require.NoError(t, dec.Decode(&resp))
+		list = append(list, resp)
+	}
+	return list
+}
+
+func TestRun(t *testing.T) {
+	registered = nil
+	t.Cleanup(func() { registered = nil })
+
+	props := writeFile(t, "props.json", `{"Name": "thing"}`)
+
+	t.Run("usage", func(t *testing.T) {
+		code, _, stderr := run()
+		require.Equal(t, ExitUsage, code)
+		require.Contains(t, stderr, "invoke")
+
+		code, _, stderr = run("deploy")
+		require.Equal(t, ExitUsage, code)
+		require.Contains(t, stderr, `unknown command "deploy"`)
+	})
+
+	t.Run("no handler", func(t *testing.T) {
+		code, _, stderr := run("invoke", "--action", "CREATE")
+		require.Equal(t, ExitUsage, code)
+		require.Contains(t, stderr, "no handler is registered")
+	})
+
+	t.Run("event", func(t *testing.T) {
+		cbCtx := writeFile(t, "ctx.json", `{"Step": 1}`)
+
+		props := writeFile(t, "props.json", `{"Name": "thing", "Size": 10, "Enabled": true}`)
+
+		code, stdout, _ := run("event", "--action", "create", "--properties", props, "--callback-context", cbCtx, "--region", "eu-west-1")
+		require.Equal(t, ExitOK, code)
+
+		var event map[string]any
+		require.NoError(t, json.Unmarshal([]byte(stdout), &event))
+		require.Equal(t, "CREATE", event["action"])
+		require.Equal(t, "eu-west-1", event["region"])
+		require.Equal(t, map[string]any{"Step": 1.0}, event["callbackContext"])
+		require.Equal(t, map[string]any{"Name": "thing", "Size": "10", "Enabled": "true"}, event["requestData"].(map[string]any)["resourceProperties"])
+	})
+
+	t.Run("invalid flags", func(t *testing.T) {
+		code, _, stderr := run("event", "--properties", props)
+		require.Equal(t, ExitUsage, code)
+		require.Contains(t, stderr, "--action is required")
+
+		code, _, stderr = run("event", "--action", "DESTROY")
+		require.Equal(t, ExitUsage, code)
+		require.Contains(t, stderr, `unknown action "DESTROY"`)
+
+		code, _, stderr = run("event", "--action", "CREATE", "--properties", writeFile(t, "bad.json", `{`))
+		require.Equal(t, ExitUsage, code)
+		require.Contains(t, stderr, "does not contain valid JSON")
+	})
+
+	Register[model, callbackCtx](twoStepHandler{})
+
+	t.Run("invoke", func(t *testing.T) {
+		code, stdout, _ := run("invoke", "--action", "CREATE", "--properties", props)
+		require.Equal(t, ExitOK, code)
+
+		list := responses(t, stdout)
+		require.Len(t, list, 1)
+		require.Equal(t, "IN_PROGRESS", list[0]["status"])
+	})
+
+	t.Run("loop", func(t *testing.T) {
+		code, stdout, _ := run("invoke", "--action", "CREATE", "--properties", props, "--loop", "--region", "us-east-1")
+		require.Equal(t, ExitOK, code)
+
+		list := responses(t, stdout)
+		require.Len(t, list, 2)
+		require.Equal(t, "SUCCESS", list[1]["status"])
+		require.Equal(t, map[string]any{"Name": "thing", "Arn": "arn:aws:example:us-east-1:123456789012:thing"}, list[1]["resourceModel"])
+	})
+
+	t.Run("max invocations", func(t *testing.T) {
+		code, _, stderr := run("invoke", "--action", "CREATE", "--properties", props, "--loop", "--max-invocations", "1")
+		require.Equal(t, ExitFailed, code)
+		require.Contains(t, stderr, "CREATE did not finish after 1 invocations")
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		code, stdout, _ := run("invoke", "--action", "UPDATE", "--properties", props, "--previous", props)
+		require.Equal(t, ExitFailed, code)
+
+		list := responses(t, stdout)
+		require.Len(t, list, 1)
+		require.Equal(t, "NotUpdatable", list[0]["errorCode"])
+	})
+}
@@ -0,0 +1,208 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime/debug"
+	"strings"
+	"text/template"
+)
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+// scaffoldFiles maps each template to the file it generates
+var scaffoldFiles = []struct {
+	template string
+	path     string
+}{
+	{"go.mod.tmpl", "go.mod"},
+	{"main.go.tmpl", "main.go"},
+	{"model.go.tmpl", "resource/model.go"},
+	{"handler.go.tmpl", "resource/handler.go"},
+	{"handler_test.go.tmpl", "resource/handler_test.go"},
+	{"schema.json.tmpl", "resource/{{.SchemaFile}}"},
+	{"rpdk-config.tmpl", ".rpdk-config"},
+	{"Makefile.tmpl", "Makefile"},
+	{"gitignore.tmpl", ".gitignore"},
+}
+
+// scaffoldGoVersion is the go directive of generated modules, which must be
+// at least the version this module requires
+const scaffoldGoVersion = "1.23.1"
+
+const modulePath = "github.com/webdestroya/cfnresource"
+
+var typeNamePattern = regexp.MustCompile(`^[A-Za-z0-9]{2,64}::[A-Za-z0-9]{2,64}::[A-Za-z0-9]{2,64}$`)
+
+// scaffold is the data the templates are executed with
+type scaffold struct {
+	TypeName   string
+	Module     string
+	SchemaFile string
+	GoVersion  string
+
+	// Version is the version of this module to require, if it is known
+	Version string
+}
+
+func newScaffold(typeName string, module string) (*scaffold, error) {
+	if !typeNamePattern.MatchString(typeName) {
+		return nil, fmt.Errorf("invalid type name %q: it must look like Org::Service::Resource", typeName)
+	}
+
+	name := strings.ToLower(strings.ReplaceAll(typeName, "::", "-"))
+	if module == "" {
+		module = name
+	}
+
+	return &scaffold{
+		TypeName:   typeName,
+		Module:     module,
+		SchemaFile: name + ".json",
+		GoVersion:  scaffoldGoVersion,
+		Version:    moduleVersion(),
+	}, nil
+}
+
+// write generates the project in dir. Existing files are only replaced if
+// force is set.
+func (s *scaffold) write(dir string, force bool) ([]string, error) {
+	var paths []string
+	for _, file := range scaffoldFiles {
+		path, err := s.execute("path", file.path)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, string(path))
+	}
+
+	if !force {
+		for _, path := range paths {
+			if _, err := os.Stat(filepath.Join(dir, path)); err == nil {
+				return nil, fmt.Errorf("%s already exists, use --force to replace it", filepath.Join(dir, path))
+			} else if !errors.Is(err, fs.ErrNotExist) {
+				return nil, err
+			}
+		}
+	}
+
+	// every file is rendered before any is written
+	contents := make([][]byte, len(scaffoldFiles))
+	for i, file := range scaffoldFiles {
+		text, err := templateFS.ReadFile("templates/" + file.template)
+		if err != nil {
+			return nil, err
+		}
+		if contents[i], err = s.execute(file.template, string(text)); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, path := range paths {
+		target := filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(target, contents[i], 0o644); err != nil {
+			return nil, err
+		}
+	}
+
+	return paths, nil
+}
+
+func (s *scaffold) execute(name string, text string) ([]byte, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, s); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// moduleVersion returns the version of this module that the binary was built
+// with, or "" for a development build
+func moduleVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+
+	version := info.Main.Version
+	if info.Main.Path != modulePath {
+		version = ""
+		for _, dep := range info.Deps {
+			if dep.Path == modulePath {
+				version = dep.Version
+			}
+		}
+	}
+
+	// local builds are stamped with versions such as v0.0.0-...+dirty,
+	// which can't be required
+	if !strings.HasPrefix(version, "v") || strings.Contains(version, "+") {
+		return ""
+	}
+	return version
+}
+
+func runInit(ctx context.Context, args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("init", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	typeName := flags.String("type", "", "the resource type name, such as Org::Service::Resource")
+	module := flags.String("module", "", "the Go module path (default the type name, as org-service-resource)")
+	dir := flags.String("dir", "", "the directory to generate the project in (default org-service-resource)")
+	force := flags.Bool("force", false, "replace files that already exist")
+	if err := flags.Parse(args); err != nil {
+		return ExitUsage
+	}
+
+	if *typeName == "" {
+		fmt.Fprintln(stderr, "--type is required")
+		return ExitUsage
+	}
+
+	s, err := newScaffold(*typeName, *module)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitUsage
+	}
+
+	if *dir == "" {
+		*dir = strings.TrimSuffix(s.SchemaFile, ".json")
+	}
+
+	paths, err := s.write(*dir, *force)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitFailed
+	}
+
+	for _, path := range paths {
+		fmt.Fprintf(stdout, "created %s\n", filepath.Join(*dir, path))
+	}
+
+	fmt.Fprintf(stdout, "\nNext steps:\n  cd %s\n", *dir)
+	if s.Version == "" {
+		fmt.Fprintf(stdout, "  go get %s@latest\n", modulePath)
+	}
+	fmt.Fprintln(stdout, "  go mod tidy")
+	fmt.Fprintln(stdout, "  go run . invoke --action CREATE --properties props.json")
+
+	return ExitOK
+}
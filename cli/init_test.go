@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"encoding/json"
+	"go/format"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/webdestroya/cfnresource/schema"
+)
+
+func TestInit(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "project")
+
+	code, stdout, stderr := run("init", "--type", "Acme::Widget::Gadget", "--module", "example.com/gadget", "--dir", dir)
+	require.Equal(t, ExitOK, code, stderr)
+	require.Contains(t, stdout, "created "+filepath.Join(dir, "resource/acme-widget-gadget.json"))
+
+	read := func(path string) string {
+		data, err := os.ReadFile(filepath.Join(dir, path))
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	require.True(t, strings.HasPrefix(read("go.mod"), "module example.com/gadget\n"))
+	require.Contains(t, read("main.go"), `"example.com/gadget/resource"`)
+	require.Contains(t, read("resource/handler.go"), `//go:embed acme-widget-gadget.json`)
+
+	for _, path := range []string{"main.go", "resource/model.go", "resource/handler.go", "resource/handler_test.go"} {
+		src := read(path)
+		formatted, err := format.Source([]byte(src))
+		require.NoError(t, err, path)
+		require.Equal(t, string(formatted), src, "%s is not gofmt'd", path)
+	}
+
+	s, err := schema.Parse([]byte(read("resource/acme-widget-gadget.json")))
+	require.NoError(t, err)
+	require.Equal(t, "Acme::Widget::Gadget", s.TypeName)
+
+	var config map[string]any
+	require.NoError(t, json.Unmarshal([]byte(read(".rpdk-config")), &config))
+	require.Equal(t, "Acme::Widget::Gadget", config["typeName"])
+	require.Equal(t, "go", config["language"])
+
+	require.Contains(t, read("Makefile"), "\tGOOS=linux")
+
+	t.Run("existing files", func(t *testing.T) {
+		code, _, stderr := run("init", "--type", "Acme::Widget::Gadget", "--dir", dir)
+		require.Equal(t, ExitFailed, code)
+		require.Contains(t, stderr, "go.mod already exists")
+
+		code, _, stderr = run("init", "--type", "Acme::Widget::Gadget", "--dir", dir, "--force")
+		require.Equal(t, ExitOK, code, stderr)
+		require.True(t, strings.HasPrefix(read("go.mod"), "module acme-widget-gadget\n"))
+	})
+
+	t.Run("invalid type", func(t *testing.T) {
+		code, _, stderr := run("init")
+		require.Equal(t, ExitUsage, code)
+		require.Contains(t, stderr, "--type is required")
+
+		code, _, stderr = run("init", "--type", "Acme::Gadget")
+		require.Equal(t, ExitUsage, code)
+		require.Contains(t, stderr, `invalid type name "Acme::Gadget"`)
+	})
+}
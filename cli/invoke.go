@@ -0,0 +1,235 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	cfnTypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/webdestroya/cfnresource/encoding"
+	"github.com/webdestroya/cfnresource/internal/invocation"
+)
+
+var actions = []string{"CREATE", "READ", "UPDATE", "DELETE", "LIST"}
+
+// eventFlags are the flags used to build an event
+type eventFlags struct {
+	action            string
+	properties        string
+	previous          string
+	callbackContext   string
+	typeConfiguration string
+	nextToken         string
+
+	region             string
+	accountID          string
+	stackName          string
+	logicalResourceID  string
+	resourceType       string
+	clientRequestToken string
+}
+
+func (f *eventFlags) register(fs *flag.FlagSet) {
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	fs.StringVar(&f.action, "action", "", "the action to run: "+strings.Join(actions, ", "))
+	fs.StringVar(&f.properties, "properties", "", "JSON file with the desired resource properties")
+	fs.StringVar(&f.previous, "previous", "", "JSON file with the previous resource properties, for an UPDATE")
+	fs.StringVar(&f.callbackContext, "callback-context", "", "JSON file with a callback context, to resume an operation")
+	fs.StringVar(&f.typeConfiguration, "type-configuration", "", "JSON file with the type configuration")
+	fs.StringVar(&f.nextToken, "next-token", "", "the page token for a LIST")
+	fs.StringVar(&f.region, "region", region, "the region of the request")
+	fs.StringVar(&f.accountID, "account-id", "123456789012", "the account ID of the request")
+	fs.StringVar(&f.stackName, "stack-name", "cfnresource-local", "the stack name of the request")
+	fs.StringVar(&f.logicalResourceID, "logical-id", "Resource", "the logical resource ID of the request")
+	fs.StringVar(&f.resourceType, "resource-type", "", "the resource type name")
+	fs.StringVar(&f.clientRequestToken, "client-request-token", "", "the client request token (default random)")
+}
+
+// event builds the invocation payload. Credentials are left out, so that the
+// handler uses the default credential chain.
+func (f *eventFlags) event() (map[string]any, error) {
+	action := strings.ToUpper(f.action)
+	if action == "" {
+		return nil, errors.New("--action is required")
+	}
+	if !contains(actions, action) {
+		return nil, fmt.Errorf("unknown action %q", f.action)
+	}
+
+	requestData := map[string]any{}
+
+	files := []struct {
+		key       string
+		path      string
+		stringify bool
+	}{
+		{"resourceProperties", f.properties, true},
+		{"previousResourceProperties", f.previous, true},
+		{"typeConfiguration", f.typeConfiguration, false},
+	}
+	for _, file := range files {
+		if file.path == "" {
+			continue
+		}
+		data, err := readJSON(file.path, file.stringify)
+		if err != nil {
+			return nil, err
+		}
+		requestData[file.key] = data
+	}
+
+	stack := invocation.Stack{
+		Region:            f.region,
+		AccountID:         f.accountID,
+		StackName:         f.stackName,
+		LogicalResourceID: f.logicalResourceID,
+		ResourceType:      f.resourceType,
+	}
+	event := stack.Event(action, f.clientRequestToken, f.nextToken, requestData)
+
+	if f.callbackContext != "" {
+		data, err := readJSON(f.callbackContext, false)
+		if err != nil {
+			return nil, err
+		}
+		event["callbackContext"] = data
+	}
+
+	return event, nil
+}
+
+func runEvent(ctx context.Context, args []string, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("event", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var f eventFlags
+	f.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+
+	event, err := f.event()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitUsage
+	}
+
+	if err := printJSON(stdout, event); err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitFailed
+	}
+	return ExitOK
+}
+
+func runInvoke(ctx context.Context, args []string, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("invoke", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var f eventFlags
+	f.register(fs)
+	loop := fs.Bool("loop", false, "invoke the handler again while it returns IN_PROGRESS")
+	wait := fs.Bool("wait", false, "with --loop, wait for the callback delay before each invocation")
+	maxInvocations := fs.Int("max-invocations", 100, "with --loop, the most invocations to make")
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+
+	if registered == nil {
+		fmt.Fprintln(stderr, "no handler is registered: call cli.Register from the handler's main and run it with go run")
+		return ExitUsage
+	}
+
+	event, err := f.event()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitUsage
+	}
+
+	invocations := 1
+	if *loop {
+		invocations = *maxInvocations
+	}
+
+	resp, _, err := invocation.Run(ctx, invocation.Invoker(registered), event, invocations, func(_ int, out []byte, resp *invocation.Response) (bool, error) {
+		var pretty bytes.Buffer
+		if err := json.Indent(&pretty, out, "", "  "); err != nil {
+			pretty.Write(out)
+		}
+		fmt.Fprintln(stdout, pretty.String())
+
+		if !*loop || resp.Status != cfnTypes.OperationStatusInProgress {
+			return false, nil
+		}
+
+		if *wait && resp.CallbackDelaySeconds > 0 {
+			fmt.Fprintf(stderr, "waiting %ds for the callback\n", resp.CallbackDelaySeconds)
+			select {
+			case <-ctx.Done():
+				return false, ctx.Err()
+			case <-time.After(time.Duration(resp.CallbackDelaySeconds) * time.Second):
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitFailed
+	}
+
+	if resp.Status == cfnTypes.OperationStatusFailed {
+		return ExitFailed
+	}
+	return ExitOK
+}
+
+// readJSON reads a JSON file, checking that it is valid. Models are
+// stringified, as CloudFormation sends every value as a string.
+func readJSON(path string, stringify bool) (json.RawMessage, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid(data) {
+		return nil, fmt.Errorf("%s does not contain valid JSON", path)
+	}
+	if !stringify {
+		return json.RawMessage(data), nil
+	}
+
+	// numbers are kept as they were written, rather than as float64
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return encoding.Marshal(v)
+}
+func printJSON(w io.Writer, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,14 @@
+// Command cfnresource generates projects for new resource types, and builds
+// events for resource handlers. Handlers are compiled into their own main to
+// be invoked, see the cli package:
+//
+//	cfnresource init --type Org::Service::Resource
+//	cd org-service-resource
+//	go run . invoke --action CREATE --properties props.json
+package main
+
+import "github.com/webdestroya/cfnresource/cli"
+
+func main() {
+	cli.Main()
+}
@@ -0,0 +1,127 @@
+package cfnresource
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/webdestroya/cfnresource/cfncontext"
+)
+
+// maxCachedConfigs bounds the config cache, which has an entry for each kind
+// of config and region
+const maxCachedConfigs = 16
+
+// sharedHTTPClient is used by every config, so that connections can be reused
+// even when credentials change
+var sharedHTTPClient = awshttp.NewBuildableClient()
+
+type configKind string
+
+const (
+	providerConfig configKind = "provider"
+	callerConfig   configKind = "caller"
+)
+
+// cachedConfig is an aws.Config along with the SDK clients built from it.
+// The clients hold the credentials of the config, so they are only reused by
+// invocations with the same credentials.
+type cachedConfig struct {
+	cfg      aws.Config
+	clients  *cfncontext.ClientCache
+	credsKey string
+}
+
+type configCache struct {
+	mu      sync.Mutex
+	entries map[string]*cachedConfig
+	order   []string
+}
+
+var awsConfigs = &configCache{entries: make(map[string]*cachedConfig)}
+
+// load returns the config for the credentials and region, loading it with
+// the given options. The options are applied after the region, so they may
+// override it.
+//
+// When cache is set, a config loaded for the same kind and region is reused
+// with its credentials replaced, so the options are not requested again.
+// CloudFormation sends new session credentials with every stack operation,
+// so the credentials cannot be part of the key.
+func (c *configCache) load(ctx context.Context, kind configKind, creds *credProvider, region string, cache bool, optFns func() []loadOptionsFunc) (*cachedConfig, error) {
+	key := string(kind) + "/" + region
+	if creds == nil {
+		// the default credential chain is resolved by the loaded config
+		key += "/default"
+	}
+	credsKey := credentialsKey(creds)
+
+	if cache {
+		c.mu.Lock()
+		entry, ok := c.entries[key]
+		c.mu.Unlock()
+
+		switch {
+		case ok && entry.credsKey == credsKey:
+			return entry, nil
+		case ok:
+			cfg := entry.cfg.Copy()
+			cfg.Credentials = aws.NewCredentialsCache(creds)
+			return c.store(key, &cachedConfig{cfg: cfg, clients: cfncontext.NewClientCache(), credsKey: credsKey}), nil
+		}
+	}
+
+	opts := []loadOptionsFunc{config.WithHTTPClient(sharedHTTPClient)}
+	if creds != nil {
+		opts = append(opts, config.WithCredentialsProvider(creds))
+	}
+	if region != "" {
+		opts = append(opts, config.WithRegion(region))
+	}
+	if optFns != nil {
+		opts = append(opts, optFns()...)
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &cachedConfig{cfg: cfg, clients: cfncontext.NewClientCache(), credsKey: credsKey}
+	if !cache {
+		return entry, nil
+	}
+	return c.store(key, entry), nil
+}
+
+// store caches entry under key, replacing any previous entry, and evicts the
+// oldest entry if the cache is full
+func (c *configCache) store(key string, entry *cachedConfig) *cachedConfig {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[key]; !ok {
+		if len(c.order) >= maxCachedConfigs {
+			delete(c.entries, c.order[0])
+			c.order = c.order[1:]
+		}
+		c.order = append(c.order, key)
+	}
+	c.entries[key] = entry
+
+	return entry
+}
+
+// credentialsKey identifies a set of credentials without keeping the secret
+// values themselves in the cache
+func credentialsKey(creds *credProvider) string {
+	if creds == nil {
+		return ""
+	}
+	h := sha256.Sum256([]byte(creds.AccessKeyID + "\x00" + creds.SecretAccessKey + "\x00" + creds.SessionToken))
+	return hex.EncodeToString(h[:])
+}
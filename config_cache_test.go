@@ -0,0 +1,91 @@
+package cfnresource
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigCache(t *testing.T) {
+	cache := &configCache{entries: make(map[string]*cachedConfig)}
+	ctx := context.Background()
+
+	creds := &credProvider{AccessKeyID: "AKIA1", SecretAccessKey: "secret", SessionToken: "token"}
+
+	optionCalls := 0
+	opts := func() []loadOptionsFunc {
+		optionCalls++
+		return nil
+	}
+
+	accessKey := func(t *testing.T, entry *cachedConfig) string {
+		v, err := entry.cfg.Credentials.Retrieve(ctx)
+		require.NoError(t, err)
+		return v.AccessKeyID
+	}
+
+	first, err := cache.load(ctx, callerConfig, creds, "us-east-1", true, opts)
+	require.NoError(t, err)
+	require.Equal(t, "us-east-1", first.cfg.Region)
+	require.Equal(t, "AKIA1", accessKey(t, first))
+
+	again, err := cache.load(ctx, callerConfig, &credProvider{AccessKeyID: "AKIA1", SecretAccessKey: "secret", SessionToken: "token"}, "us-east-1", true, opts)
+	require.NoError(t, err)
+	require.Same(t, first, again)
+	require.Equal(t, 1, optionCalls)
+
+	t.Run("new credentials", func(t *testing.T) {
+		creds2 := &credProvider{AccessKeyID: "AKIA2", SecretAccessKey: "secret", SessionToken: "token"}
+
+		entry, err := cache.load(ctx, callerConfig, creds2, "us-east-1", true, opts)
+		require.NoError(t, err)
+		require.Equal(t, 1, optionCalls)
+		require.Equal(t, "us-east-1", entry.cfg.Region)
+		require.Equal(t, "AKIA2", accessKey(t, entry))
+		require.NotSame(t, first.clients, entry.clients)
+
+		// the first config keeps its own credentials
+		require.Equal(t, "AKIA1", accessKey(t, first))
+
+		again, err := cache.load(ctx, callerConfig, creds2, "us-east-1", true, opts)
+		require.NoError(t, err)
+		require.Same(t, entry, again)
+	})
+
+	t.Run("other kinds and regions", func(t *testing.T) {
+		for _, other := range []struct {
+			kind   configKind
+			creds  *credProvider
+			region string
+		}{
+			{providerConfig, creds, "us-east-1"},
+			{callerConfig, creds, "us-west-2"},
+			{callerConfig, nil, "us-east-1"},
+		} {
+			entry, err := cache.load(ctx, other.kind, other.creds, other.region, true, nil)
+			require.NoError(t, err)
+			require.NotSame(t, first, entry)
+			require.Equal(t, other.region, entry.cfg.Region)
+			require.NotSame(t, first.clients, entry.clients)
+		}
+	})
+
+	t.Run("bounded", func(t *testing.T) {
+		for i := 0; i < maxCachedConfigs+4; i++ {
+			_, err := cache.load(ctx, callerConfig, creds, fmt.Sprintf("region-%d", i), true, nil)
+			require.NoError(t, err)
+		}
+		require.Len(t, cache.entries, maxCachedConfigs)
+		require.Len(t, cache.order, maxCachedConfigs)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		a, err := cache.load(ctx, callerConfig, creds, "eu-west-1", false, nil)
+		require.NoError(t, err)
+		b, err := cache.load(ctx, callerConfig, creds, "eu-west-1", false, nil)
+		require.NoError(t, err)
+		require.NotSame(t, a, b)
+	})
+}
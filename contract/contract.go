@@ -0,0 +1,370 @@
+// Package contract runs the resource provider contract tests against a
+// handler in-process, in the same way as the CloudFormation CLI's contract
+// tests, so that a handler can be checked with go test against local fakes of
+// the services it calls.
+//
+//	func TestContract(t *testing.T) {
+//		suite := &contract.Suite[Model, CallbackContext]{
+//			Handler: newHandler(fakeClient()),
+//			Create: func(t *testing.T) *Model {
+//				return &Model{Name: aws.String("test-" + strings.ToLower(t.Name()))}
+//			},
+//			Update: func(t *testing.T, current *Model) *Model {
+//				m := *current
+//				m.Size = aws.Int(2)
+//				return &m
+//			},
+//		}
+//		suite.Run(t)
+//	}
+package contract
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	cfnTypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/stretchr/testify/require"
+	"github.com/webdestroya/cfnresource"
+	"github.com/webdestroya/cfnresource/cfntest"
+	"github.com/webdestroya/cfnresource/schema"
+)
+
+// Suite describes a resource type to run the contract tests against
+type Suite[Model any, Ctx any] struct {
+	Handler cfnresource.Handler[Model, Ctx]
+
+	// Schema is the resource schema. If it is nil, the handler must implement
+	// cfnresource.ResourceSchemaProvider.
+	Schema *schema.Schema
+
+	// Create returns the desired model for a new resource. It is called for
+	// each test that creates a resource.
+	Create func(t *testing.T) *Model
+
+	// Update returns the desired model to update a resource to. The primary
+	// identifier and read-only properties that are not create-only are copied
+	// from the current model.
+	Update func(t *testing.T, current *Model) *Model
+
+	// UpdateCreateOnly returns a desired model that changes a create-only
+	// property. The test is skipped if it is nil.
+	UpdateCreateOnly func(t *testing.T, current *Model) *Model
+
+	// Driver invokes the handler. One is made with cfntest.New if it is nil.
+	Driver *cfntest.Driver[Model, Ctx]
+}
+
+// Run runs each contract test as a subtest of t
+func (s *Suite[Model, Ctx]) Run(t *testing.T) {
+	t.Helper()
+
+	if s.Handler == nil || s.Create == nil {
+		t.Fatal("contract: Suite requires a Handler and a Create function")
+	}
+
+	if s.Schema == nil {
+		provider, ok := s.Handler.(cfnresource.ResourceSchemaProvider)
+		if !ok || provider.ResourceSchema() == nil {
+			t.Fatal("contract: Suite requires a Schema, or a handler that implements ResourceSchemaProvider")
+		}
+		s.Schema = provider.ResourceSchema()
+	}
+
+	if s.Driver == nil {
+		s.Driver = cfntest.New(s.Handler)
+		s.Driver.ResourceType = s.Schema.TypeName
+	}
+
+	t.Run("CreateReadUpdateListDelete", s.testLifecycle)
+	t.Run("CreateDuplicate", s.testCreateDuplicate)
+	t.Run("UpdateNotFound", s.testUpdateNotFound)
+	t.Run("DeleteTwice", s.testDeleteTwice)
+	t.Run("UpdateCreateOnly", s.testUpdateCreateOnly)
+}
+
+func (s *Suite[Model, Ctx]) testLifecycle(t *testing.T) {
+	ctx := context.Background()
+	desired := s.Create(t)
+
+	created := s.create(t, desired)
+	s.requireContains(t, desired, created)
+
+	read := s.read(t, created)
+	s.requireContains(t, created, read)
+
+	if s.Update == nil {
+		t.Log("Update is not set, skipping the update steps")
+	} else {
+		desiredUpdate := s.desiredUpdate(t, read, s.Update(t, read))
+
+		result, err := s.Driver.Update(ctx, read, desiredUpdate)
+		requireStatus(t, "UPDATE", result, err, cfnTypes.OperationStatusSuccess, "")
+		s.requireContains(t, desiredUpdate, result.Model)
+		s.requireSameIdentifier(t, read, result.Model)
+
+		read = s.read(t, result.Model)
+		s.requireContains(t, result.Model, read)
+	}
+
+	s.requireListed(t, read)
+
+	result, err := s.Driver.Delete(ctx, read)
+	requireStatus(t, "DELETE", result, err, cfnTypes.OperationStatusSuccess, "")
+
+	result, err = s.Driver.Read(ctx, read)
+	requireStatus(t, "READ after DELETE", result, err, cfnTypes.OperationStatusFailed, cfnTypes.HandlerErrorCodeNotFound)
+}
+
+func (s *Suite[Model, Ctx]) testCreateDuplicate(t *testing.T) {
+	if s.generatedIdentifier() {
+		t.Skip("the primary identifier is read-only, so a duplicate cannot be requested")
+	}
+
+	desired := s.Create(t)
+	s.create(t, desired)
+
+	result, err := s.Driver.Create(context.Background(), desired)
+	if err == nil && result.Status == cfnTypes.OperationStatusSuccess {
+		t.Cleanup(func() {
+			_, _ = s.Driver.Delete(context.Background(), result.Model)
+		})
+	}
+	requireStatus(t, "duplicate CREATE", result, err, cfnTypes.OperationStatusFailed, cfnTypes.HandlerErrorCodeAlreadyExists)
+}
+
+func (s *Suite[Model, Ctx]) testUpdateNotFound(t *testing.T) {
+	if s.Update == nil {
+		t.Skip("Update is not set")
+	}
+
+	ctx := context.Background()
+	created := s.create(t, s.Create(t))
+
+	result, err := s.Driver.Delete(ctx, created)
+	requireStatus(t, "DELETE", result, err, cfnTypes.OperationStatusSuccess, "")
+
+	desired := s.desiredUpdate(t, created, s.Update(t, created))
+	result, err = s.Driver.Update(ctx, created, desired)
+	requireStatus(t, "UPDATE after DELETE", result, err, cfnTypes.OperationStatusFailed, cfnTypes.HandlerErrorCodeNotFound)
+}
+
+func (s *Suite[Model, Ctx]) testDeleteTwice(t *testing.T) {
+	ctx := context.Background()
+	created := s.create(t, s.Create(t))
+
+	result, err := s.Driver.Delete(ctx, created)
+	requireStatus(t, "DELETE", result, err, cfnTypes.OperationStatusSuccess, "")
+
+	result, err = s.Driver.Delete(ctx, created)
+	requireStatus(t, "second DELETE", result, err, cfnTypes.OperationStatusFailed, cfnTypes.HandlerErrorCodeNotFound)
+}
+
+func (s *Suite[Model, Ctx]) testUpdateCreateOnly(t *testing.T) {
+	if s.UpdateCreateOnly == nil || len(s.Schema.CreateOnlyProperties) == 0 {
+		t.Skip("UpdateCreateOnly is not set, or the schema has no create-only properties")
+	}
+
+	created := s.create(t, s.Create(t))
+	desired := s.desiredUpdate(t, created, s.UpdateCreateOnly(t, created))
+
+	result, err := s.Driver.Update(context.Background(), created, desired)
+	requireStatus(t, "UPDATE of a create-only property", result, err, cfnTypes.OperationStatusFailed, cfnTypes.HandlerErrorCodeNotUpdatable)
+}
+
+// create creates a resource that is deleted when the test finishes, unless
+// the test deleted it already
+func (s *Suite[Model, Ctx]) create(t *testing.T, desired *Model) *Model {
+	t.Helper()
+
+	result, err := s.Driver.Create(context.Background(), desired)
+	requireStatus(t, "CREATE", result, err, cfnTypes.OperationStatusSuccess, "")
+
+	created := result.Model
+	s.requireIdentifier(t, created)
+
+	t.Cleanup(func() {
+		_, _ = s.Driver.Delete(context.Background(), created)
+	})
+
+	return created
+}
+
+func (s *Suite[Model, Ctx]) read(t *testing.T, model *Model) *Model {
+	t.Helper()
+
+	result, err := s.Driver.Read(context.Background(), model)
+	requireStatus(t, "READ", result, err, cfnTypes.OperationStatusSuccess, "")
+	s.requireSameIdentifier(t, model, result.Model)
+	return result.Model
+}
+
+// requireListed pages through LIST until it finds the model
+func (s *Suite[Model, Ctx]) requireListed(t *testing.T, model *Model) {
+	t.Helper()
+
+	want := s.identifier(t, model)
+	nextToken := ""
+	for page := 1; page <= cfntest.DefaultMaxInvocations; page++ {
+		result, err := s.Driver.List(context.Background(), new(Model), nextToken)
+		requireStatus(t, "LIST", result, err, cfnTypes.OperationStatusSuccess, "")
+
+		for _, listed := range result.Models {
+			if reflect.DeepEqual(want, s.identifier(t, listed)) {
+				return
+			}
+		}
+
+		if result.NextToken == "" {
+			break
+		}
+		nextToken = result.NextToken
+	}
+
+	t.Fatalf("LIST did not return the resource %v", want)
+}
+
+// desiredUpdate copies the primary identifier and read-only properties of the
+// current model into the desired model, as CloudFormation does, unless they
+// are also create-only
+func (s *Suite[Model, Ctx]) desiredUpdate(t *testing.T, current *Model, desired *Model) *Model {
+	t.Helper()
+
+	currentProps := properties(t, current)
+	desiredProps := properties(t, desired)
+
+	for _, pointers := range [][]string{s.Schema.PrimaryIdentifier, s.Schema.ReadOnlyProperties} {
+		for _, pointer := range pointers {
+			// changes to create-only properties are left for the handler to reject
+			if contains(s.Schema.CreateOnlyProperties, pointer) {
+				continue
+			}
+			if v, ok := schema.GetProperty(currentProps, pointer); ok {
+				schema.SetProperty(desiredProps, pointer, v)
+			}
+		}
+	}
+
+	data, err := json.Marshal(desiredProps)
+	require.NoError(t, err)
+
+	out := new(Model)
+	require.NoError(t, json.Unmarshal(data, out))
+	return out
+}
+
+// generatedIdentifier reports whether the primary identifier is only known
+// after the resource is created
+func (s *Suite[Model, Ctx]) generatedIdentifier() bool {
+	for _, pointer := range s.Schema.PrimaryIdentifier {
+		if !contains(s.Schema.ReadOnlyProperties, pointer) {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(pointers []string, pointer string) bool {
+	for _, p := range pointers {
+		if p == pointer {
+			return true
+		}
+	}
+	return false
+}
+
+// identifier returns the values of the primary identifier properties of a model
+func (s *Suite[Model, Ctx]) identifier(t *testing.T, model *Model) map[string]any {
+	t.Helper()
+
+	props := properties(t, model)
+	id := make(map[string]any, len(s.Schema.PrimaryIdentifier))
+	for _, pointer := range s.Schema.PrimaryIdentifier {
+		if v, ok := schema.GetProperty(props, pointer); ok {
+			id[pointer] = v
+		}
+	}
+	return id
+}
+
+func (s *Suite[Model, Ctx]) requireIdentifier(t *testing.T, model *Model) {
+	t.Helper()
+
+	id := s.identifier(t, model)
+	for _, pointer := range s.Schema.PrimaryIdentifier {
+		require.Containsf(t, id, pointer, "primary identifier %s is not set", pointer)
+	}
+}
+
+func (s *Suite[Model, Ctx]) requireSameIdentifier(t *testing.T, want *Model, got *Model) {
+	t.Helper()
+	require.Equal(t, s.identifier(t, want), s.identifier(t, got), "primary identifier changed")
+}
+
+// requireContains checks that every property of want, other than write-only
+// properties, has the same value in got
+func (s *Suite[Model, Ctx]) requireContains(t *testing.T, want *Model, got *Model) {
+	t.Helper()
+
+	wantProps := properties(t, want)
+	for _, pointer := range s.Schema.WriteOnlyProperties {
+		schema.RemoveProperty(wantProps, pointer)
+	}
+	requireSubset(t, wantProps, properties(t, got), "#")
+}
+
+func requireSubset(t *testing.T, want map[string]any, got map[string]any, path string) {
+	t.Helper()
+
+	for key, wantValue := range want {
+		keyPath := path + "/" + key
+
+		gotValue, ok := got[key]
+		require.Truef(t, ok, "%s is missing from the returned model", keyPath)
+
+		switch w := wantValue.(type) {
+		case map[string]any:
+			g, ok := gotValue.(map[string]any)
+			require.Truef(t, ok, "%s should be an object, got %v", keyPath, gotValue)
+			requireSubset(t, w, g, keyPath)
+		case []any:
+			require.ElementsMatchf(t, w, gotValue, "%s does not match", keyPath)
+		default:
+			require.Equalf(t, wantValue, gotValue, "%s does not match", keyPath)
+		}
+	}
+}
+
+// properties returns a model as it is decoded from JSON
+func properties(t *testing.T, model any) map[string]any {
+	t.Helper()
+
+	require.NotNil(t, model, "model is nil")
+
+	data, err := json.Marshal(model)
+	require.NoError(t, err)
+
+	props := map[string]any{}
+	require.NoError(t, json.Unmarshal(data, &props))
+	return props
+}
+
+func requireStatus[Model any](t *testing.T, op string, result *cfntest.Result[Model], err error, status cfnTypes.OperationStatus, code cfnTypes.HandlerErrorCode) {
+	t.Helper()
+
+	require.NoError(t, err, op)
+	require.Equal(t, status, result.Status, describe(op, result))
+	if code != "" {
+		require.Equal(t, code, result.ErrorCode, describe(op, result))
+	}
+}
+
+func describe[Model any](op string, result *cfntest.Result[Model]) string {
+	if result.ErrorCode == "" {
+		return fmt.Sprintf("%s returned %s", op, result.Status)
+	}
+	return fmt.Sprintf("%s returned %s %s: %s", op, result.Status, result.ErrorCode, result.Message)
+}
@@ -0,0 +1,158 @@
+package contract
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/webdestroya/cfnresource"
+	"github.com/webdestroya/cfnresource/cfnerr"
+	"github.com/webdestroya/cfnresource/schema"
+)
+
+//go:embed testdata/thing.json
+var schemaFS embed.FS
+
+var thingSchema = schema.MustLoad(schemaFS, "testdata/thing.json")
+
+type model struct {
+	Name     *string `json:",omitempty"`
+	Arn      *string `json:",omitempty"`
+	Size     *int    `json:",omitempty"`
+	Password *string `json:",omitempty"`
+}
+
+type callbackCtx struct {
+	Pending bool `json:",omitempty"`
+}
+
+type requestType = *cfnresource.Request[model, callbackCtx]
+type progEventType = *cfnresource.ProgressEvent[model, callbackCtx]
+
+// storeHandler keeps resources in memory, in place of a service API
+type storeHandler struct {
+	mu     sync.Mutex
+	things map[string]model
+}
+
+func newStoreHandler() *storeHandler {
+	return &storeHandler{things: make(map[string]model)}
+}
+
+func (h *storeHandler) ResourceSchema() *schema.Schema {
+	return thingSchema
+}
+
+func (h *storeHandler) Create(ctx context.Context, req requestType) (progEventType, error) {
+	m := req.ResourceProperties
+
+	// creation takes a callback, like most real resources
+	if req.CallbackContext == nil {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		if _, ok := h.things[*m.Name]; ok {
+			return nil, cfnerr.NewMessage(cfnerr.AlreadyExists, fmt.Sprintf("%s already exists", *m.Name))
+		}
+		arn := req.ARN("example", "thing/"+*m.Name)
+		m.Arn = &arn
+		h.things[*m.Name] = *m
+		return req.InProgressResponse(m, &callbackCtx{Pending: true}), nil
+	}
+
+	return h.Read(ctx, req)
+}
+
+func (h *storeHandler) Update(ctx context.Context, req requestType) (progEventType, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	prev, desired := req.PreviousResourceProperties, req.ResourceProperties
+	if *prev.Name != *desired.Name {
+		return nil, cfnerr.NewMessage(cfnerr.NotUpdatable, "Name cannot be updated")
+	}
+
+	current, ok := h.things[*desired.Name]
+	if !ok {
+		return nil, cfnerr.NewMessage(cfnerr.NotFound, "not found")
+	}
+
+	desired.Arn = current.Arn
+	h.things[*desired.Name] = *desired
+	return req.SuccessResponse(withoutPassword(*desired)), nil
+}
+
+func (h *storeHandler) Delete(ctx context.Context, req requestType) (progEventType, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	name := *req.ResourceProperties.Name
+	if _, ok := h.things[name]; !ok {
+		return nil, cfnerr.NewMessage(cfnerr.NotFound, "not found")
+	}
+	delete(h.things, name)
+	return req.SuccessResponse(nil), nil
+}
+
+func (h *storeHandler) Read(ctx context.Context, req requestType) (progEventType, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	thing, ok := h.things[*req.ResourceProperties.Name]
+	if !ok {
+		return nil, cfnerr.NewMessage(cfnerr.NotFound, "not found")
+	}
+	return req.SuccessResponse(withoutPassword(thing)), nil
+}
+
+// List returns one resource per page, to exercise paging
+func (h *storeHandler) List(ctx context.Context, req requestType) (progEventType, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	names := make([]string, 0, len(h.things))
+	for name := range h.things {
+		if name > req.NextToken {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	pe := req.SuccessResponse(nil).WithModels()
+	if len(names) > 0 {
+		pe = pe.WithModels(withoutPassword(h.things[names[0]]))
+		pe.NextToken = names[0]
+	}
+	return pe, nil
+}
+
+func withoutPassword(m model) *model {
+	m.Password = nil
+	return &m
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestSuite(t *testing.T) {
+	count := 0
+
+	suite := &Suite[model, callbackCtx]{
+		Handler: newStoreHandler(),
+		Create: func(t *testing.T) *model {
+			count++
+			return &model{Name: ptr(fmt.Sprintf("thing-%d", count)), Size: ptr(1), Password: ptr("secret")}
+		},
+		Update: func(t *testing.T, current *model) *model {
+			return &model{Name: current.Name, Size: ptr(*current.Size + 1)}
+		},
+		UpdateCreateOnly: func(t *testing.T, current *model) *model {
+			return &model{Name: ptr(*current.Name + "-renamed"), Size: current.Size}
+		},
+	}
+	suite.Run(t)
+}
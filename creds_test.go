@@ -1,10 +1,13 @@
 package cfnresource
 
 import (
+	"context"
 	"encoding/json"
 	"testing"
 
+	cfnTypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
 	"github.com/stretchr/testify/require"
+	"github.com/webdestroya/cfnresource/internal/handlerutil"
 )
 
 func TestCredentialRedaction(t *testing.T) {
@@ -38,3 +41,99 @@ func TestCredentialRedaction(t *testing.T) {
 	require.Equal(t, "fake", newCreds.SecretAccessKey)
 	require.Equal(t, "fake", newCreds.SessionToken)
 }
+
+func TestCredentialRetrieveMissing(t *testing.T) {
+	var creds *credProvider
+	require.False(t, creds.Valid())
+
+	_, err := creds.Retrieve(context.Background())
+	require.ErrorIs(t, err, handlerutil.ErrMissingCredentials)
+
+	require.False(t, (&credProvider{AccessKeyID: "fake"}).Valid())
+	require.True(t, (&credProvider{AccessKeyID: "fake", SecretAccessKey: "fake"}).Valid())
+}
+
+func TestEventValidation(t *testing.T) {
+	tests := []struct {
+		name         string
+		modify       func(*event)
+		allowDefault bool
+		errorCode    cfnTypes.HandlerErrorCode
+		message      string
+	}{
+		{
+			name:   "valid",
+			modify: func(e *event) {},
+		},
+		{
+			name: "missing bearer token and region",
+			modify: func(e *event) {
+				e.BearerToken = ""
+				e.Region = ""
+			},
+			errorCode: cfnTypes.HandlerErrorCodeInvalidRequest,
+			message:   "request is missing required fields: bearerToken, region",
+		},
+		{
+			name:      "missing caller credentials",
+			modify:    func(e *event) { e.RequestData.CallerCredentials = nil },
+			errorCode: cfnTypes.HandlerErrorCodeInvalidCredentials,
+			message:   "request does not include callerCredentials",
+		},
+		{
+			name:      "empty caller credentials",
+			modify:    func(e *event) { e.RequestData.CallerCredentials = &credProvider{} },
+			errorCode: cfnTypes.HandlerErrorCodeInvalidCredentials,
+			message:   "request includes incomplete callerCredentials",
+		},
+		{
+			name:      "incomplete provider credentials",
+			modify:    func(e *event) { e.RequestData.ProviderCredentials = &credProvider{AccessKeyID: "fake"} },
+			errorCode: cfnTypes.HandlerErrorCodeInvalidCredentials,
+			message:   "request includes incomplete providerCredentials",
+		},
+		{
+			name:   "missing provider credentials",
+			modify: func(e *event) { e.RequestData.ProviderCredentials = nil },
+		},
+		{
+			name: "default credentials allowed",
+			modify: func(e *event) {
+				e.RequestData.CallerCredentials = nil
+				e.RequestData.ProviderCredentials = &credProvider{}
+			},
+			allowDefault: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ev := newTestEvent(readAction, `{}`)
+			tt.modify(ev)
+
+			fn := makeEventFunc[model, callbackCtx](&readHandler{}, func(o *RuntimeOptions) {
+				o.AllowDefaultCredentials = tt.allowDefault
+			})
+			resp, err := fn(context.Background(), ev)
+			require.NoError(t, err)
+
+			if tt.errorCode == "" {
+				require.Equal(t, cfnTypes.OperationStatusSuccess, resp.OperationStatus)
+				return
+			}
+
+			require.Equal(t, cfnTypes.OperationStatusFailed, resp.OperationStatus)
+			require.EqualValues(t, tt.errorCode, resp.ErrorCode)
+			require.Equal(t, tt.message, resp.Message)
+		})
+	}
+}
+
+// readHandler reads whatever it is given
+type readHandler struct {
+	basicHandler
+}
+
+func (readHandler) Read(ctx context.Context, req requestType) (progEventType, error) {
+	return req.SuccessResponse(req.ResourceProperties), nil
+}
@@ -0,0 +1,184 @@
+package customresource
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type properties struct {
+	Name  string `json:",omitempty"`
+	Count int    `json:",omitempty"`
+	Slow  bool   `json:",omitempty"`
+}
+
+type testHandler struct{}
+
+var _ Handler[properties] = (*testHandler)(nil)
+
+func (testHandler) Create(ctx context.Context, req *Request[properties]) (*Result, error) {
+	if req.ResourceProperties.Slow {
+		<-ctx.Done()
+		time.Sleep(time.Second)
+		return &Result{}, nil
+	}
+
+	return &Result{
+		PhysicalResourceID: "thing-" + req.ResourceProperties.Name,
+		Data:               map[string]any{"Count": req.ResourceProperties.Count * 2},
+		NoEcho:             true,
+	}, nil
+}
+
+func (testHandler) Update(ctx context.Context, req *Request[properties]) (*Result, error) {
+	if req.OldResourceProperties.Name != req.ResourceProperties.Name {
+		return nil, errors.New("name cannot be changed")
+	}
+	return nil, nil
+}
+
+func (testHandler) Delete(ctx context.Context, req *Request[properties]) (*Result, error) {
+	panic("delete exploded")
+}
+
+type presignedServer struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	failures  int
+	responses []map[string]any
+}
+
+func newPresignedServer(t *testing.T, failures int) *presignedServer {
+	s := &presignedServer{failures: failures}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		require.Equal(t, http.MethodPut, r.Method)
+		require.Empty(t, r.Header.Get("Content-Type"))
+
+		if s.failures > 0 {
+			s.failures--
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		var resp map[string]any
+		require.NoError(t, json.Unmarshal(body, &resp))
+		s.responses = append(s.responses, resp)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *presignedServer) last() map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.responses[len(s.responses)-1]
+}
+
+func testEvent(url string, requestType string, props string, oldProps string) *event {
+	ev := &event{
+		RequestType:        requestType,
+		ResponseURL:        url,
+		StackID:            "arn:aws:cloudformation:us-east-1:123456789012:stack/SampleStack/e722ae60-fe62-11e8-9a0e-0ae8cc519968",
+		RequestID:          "req-1",
+		ResourceType:       "Custom::Thing",
+		LogicalResourceID:  "MyThing",
+		ResourceProperties: json.RawMessage(props),
+	}
+	if oldProps != "" {
+		ev.OldResourceProperties = json.RawMessage(oldProps)
+		ev.PhysicalResourceID = "thing-existing"
+	}
+	return ev
+}
+
+func TestCustomResource(t *testing.T) {
+	ResponseRetryDelay = time.Millisecond
+	fn := makeEventFunc[properties](testHandler{})
+
+	t.Run("create", func(t *testing.T) {
+		srv := newPresignedServer(t, 2)
+		err := fn(context.Background(), testEvent(srv.URL, "Create", `{"ServiceToken": "arn", "Name": "a", "Count": "21"}`, ""))
+		require.NoError(t, err)
+
+		resp := srv.last()
+		require.Equal(t, "SUCCESS", resp["Status"])
+		require.Equal(t, "thing-a", resp["PhysicalResourceId"])
+		require.Equal(t, "req-1", resp["RequestId"])
+		require.Equal(t, "MyThing", resp["LogicalResourceId"])
+		require.Equal(t, true, resp["NoEcho"])
+		require.Equal(t, map[string]any{"Count": "42"}, resp["Data"])
+	})
+
+	t.Run("update keeps id", func(t *testing.T) {
+		srv := newPresignedServer(t, 0)
+		err := fn(context.Background(), testEvent(srv.URL, "Update", `{"Name": "a"}`, `{"Name": "a"}`))
+		require.NoError(t, err)
+
+		resp := srv.last()
+		require.Equal(t, "SUCCESS", resp["Status"])
+		require.Equal(t, "thing-existing", resp["PhysicalResourceId"])
+	})
+
+	t.Run("update failure", func(t *testing.T) {
+		srv := newPresignedServer(t, 0)
+		err := fn(context.Background(), testEvent(srv.URL, "Update", `{"Name": "b"}`, `{"Name": "a"}`))
+		require.NoError(t, err)
+
+		resp := srv.last()
+		require.Equal(t, "FAILED", resp["Status"])
+		require.Equal(t, "name cannot be changed", resp["Reason"])
+		require.Equal(t, "thing-existing", resp["PhysicalResourceId"])
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		srv := newPresignedServer(t, 0)
+		err := fn(context.Background(), testEvent(srv.URL, "Delete", `{"Name": "a"}`, `{"Name": "a"}`))
+		require.NoError(t, err)
+
+		resp := srv.last()
+		require.Equal(t, "FAILED", resp["Status"])
+		require.Equal(t, "delete exploded", resp["Reason"])
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		TimeoutMargin = 100 * time.Millisecond
+		t.Cleanup(func() { TimeoutMargin = 5 * time.Second })
+
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+
+		srv := newPresignedServer(t, 0)
+		err := fn(ctx, testEvent(srv.URL, "Create", `{"Name": "a", "Slow": "true"}`, ""))
+		require.NoError(t, err)
+
+		resp := srv.last()
+		require.Equal(t, "FAILED", resp["Status"])
+		require.Equal(t, "handler timed out before completing", resp["Reason"])
+		require.Equal(t, "SampleStack-MyThing-req-1", resp["PhysicalResourceId"])
+	})
+
+	t.Run("upload failure", func(t *testing.T) {
+		srv := newPresignedServer(t, 10)
+		err := fn(context.Background(), testEvent(srv.URL, "Create", `{"Name": "a"}`, ""))
+		require.NoError(t, err)
+
+		// every upload attempt was made, but the handler is not run again
+		require.Equal(t, 10-ResponseAttempts, srv.failures)
+		require.Empty(t, srv.responses)
+	})
+}
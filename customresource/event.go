@@ -0,0 +1,25 @@
+package customresource
+
+import (
+	"encoding/json"
+)
+
+const (
+	createRequest = "Create"
+	updateRequest = "Update"
+	deleteRequest = "Delete"
+)
+
+// event is the payload CloudFormation sends to a custom resource
+type event struct {
+	RequestType           string          `json:"RequestType"`
+	ResponseURL           string          `json:"ResponseURL"`
+	StackID               string          `json:"StackId"`
+	RequestID             string          `json:"RequestId"`
+	ResourceType          string          `json:"ResourceType"`
+	LogicalResourceID     string          `json:"LogicalResourceId"`
+	PhysicalResourceID    string          `json:"PhysicalResourceId,omitempty"`
+	ServiceToken          string          `json:"ServiceToken,omitempty"`
+	ResourceProperties    json.RawMessage `json:"ResourceProperties,omitempty"`
+	OldResourceProperties json.RawMessage `json:"OldResourceProperties,omitempty"`
+}
@@ -0,0 +1,44 @@
+/*
+Package customresource lets you create lambdas that back classic CloudFormation
+custom resources (AWS::CloudFormation::CustomResource or Custom::MyThing).
+
+Unlike resource types, custom resources receive their request directly from the
+stack, and report the result by uploading a response document to a presigned
+S3 URL. This package handles decoding the request, managing the physical
+resource ID and reliably uploading the response.
+*/
+package customresource
+
+import (
+	"context"
+)
+
+// Handler is implemented by a custom resource. Properties is the shape of the
+// resource's properties, and is decoded using the encoding package, so
+// stringified values are supported.
+type Handler[Properties any] interface {
+	Create(context.Context, *Request[Properties]) (*Result, error)
+	Update(context.Context, *Request[Properties]) (*Result, error)
+	Delete(context.Context, *Request[Properties]) (*Result, error)
+}
+
+type handlerFunc[Properties any] func(context.Context, *Request[Properties]) (*Result, error)
+
+// Result is returned by a handler when an operation succeeds
+type Result struct {
+	// PhysicalResourceID identifies the resource. If blank, the ID from the
+	// request is kept, or one is generated for a Create.
+	//
+	// Returning a different ID from an Update tells CloudFormation the resource
+	// was replaced, and it will Delete the old one.
+	PhysicalResourceID string
+
+	// Data is made available to the stack with Fn::GetAtt
+	Data map[string]any
+
+	// NoEcho masks Data when it is retrieved by Fn::GetAtt
+	NoEcho bool
+
+	// Reason is an optional message to include in the response
+	Reason string
+}
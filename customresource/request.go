@@ -0,0 +1,73 @@
+package customresource
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/webdestroya/cfnresource/cfnutils"
+	"github.com/webdestroya/cfnresource/encoding"
+)
+
+type Request[Properties any] struct {
+	// RequestType is Create, Update or Delete
+	RequestType string
+
+	RequestID    string
+	ResourceType string
+	ServiceToken string
+
+	StackId   string
+	StackName string
+
+	LogicalResourceID string
+
+	// PhysicalResourceID is blank for Create requests
+	PhysicalResourceID string
+
+	ResourceProperties    *Properties
+	OldResourceProperties *Properties
+
+	event *event
+}
+
+func (r *Request[Properties]) UnmarshalJSON(data []byte) error {
+	return errors.New("dont marshal the request object directly")
+}
+
+// defaultPhysicalResourceID is used when a Create does not return an ID
+func (r *Request[Properties]) defaultPhysicalResourceID() string {
+	if r.StackName == "" {
+		return fmt.Sprintf("%s-%s", r.LogicalResourceID, r.RequestID)
+	}
+	return fmt.Sprintf("%s-%s-%s", r.StackName, r.LogicalResourceID, r.RequestID)
+}
+
+func newRequest[Properties any](event *event) (*Request[Properties], error) {
+	req := &Request[Properties]{
+		RequestType:        event.RequestType,
+		RequestID:          event.RequestID,
+		ResourceType:       event.ResourceType,
+		ServiceToken:       event.ServiceToken,
+		StackId:            event.StackID,
+		StackName:          cfnutils.GetStackNameFromArn(event.StackID),
+		LogicalResourceID:  event.LogicalResourceID,
+		PhysicalResourceID: event.PhysicalResourceID,
+		event:              event,
+	}
+
+	if len(event.ResourceProperties) > 0 {
+		req.ResourceProperties = new(Properties)
+		if err := encoding.Unmarshal(event.ResourceProperties, req.ResourceProperties); err != nil {
+			return nil, fmt.Errorf("invalid ResourceProperties: %w", err)
+		}
+	}
+
+	if len(event.OldResourceProperties) > 0 {
+		req.OldResourceProperties = new(Properties)
+		if err := encoding.Unmarshal(event.OldResourceProperties, req.OldResourceProperties); err != nil {
+			return nil, fmt.Errorf("invalid OldResourceProperties: %w", err)
+		}
+	}
+
+	return req, nil
+}
@@ -0,0 +1,143 @@
+package customresource
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/webdestroya/cfnresource/encoding"
+)
+
+const (
+	statusSuccess = "SUCCESS"
+	statusFailed  = "FAILED"
+
+	// CloudFormation rejects response documents larger than 4096 bytes, so
+	// failure reasons are kept well below that
+	maxReasonLength = 1024
+)
+
+var (
+	// HTTPClient is used to upload responses to the presigned URL
+	HTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+	// ResponseAttempts is the number of times an upload is attempted before giving up
+	ResponseAttempts = 5
+
+	// ResponseRetryDelay is the delay before the first retry of an upload, and doubles for each retry
+	ResponseRetryDelay = 500 * time.Millisecond
+)
+
+// response is the document uploaded to the ResponseURL
+type response struct {
+	Status             string `json:"Status"`
+	Reason             string `json:"Reason,omitempty"`
+	PhysicalResourceID string `json:"PhysicalResourceId"`
+	StackID            string `json:"StackId"`
+	RequestID          string `json:"RequestId"`
+	LogicalResourceID  string `json:"LogicalResourceId"`
+	NoEcho             bool   `json:"NoEcho,omitempty"`
+	Data               any    `json:"Data,omitempty"`
+}
+
+func newResponse(event *event, status string, physicalID string) *response {
+	return &response{
+		Status:             status,
+		PhysicalResourceID: physicalID,
+		StackID:            event.StackID,
+		RequestID:          event.RequestID,
+		LogicalResourceID:  event.LogicalResourceID,
+	}
+}
+
+// newSuccessResponse builds the response for a successful operation
+func newSuccessResponse(event *event, physicalID string, result *Result) (*response, error) {
+	resp := newResponse(event, statusSuccess, physicalID)
+
+	if result != nil {
+		resp.Reason = truncateReason(result.Reason)
+		resp.NoEcho = result.NoEcho
+
+		if len(result.Data) > 0 {
+			data, err := encoding.Stringify(result.Data)
+			if err != nil {
+				return nil, fmt.Errorf("unable to encode Data: %w", err)
+			}
+			resp.Data = data
+		}
+	}
+
+	return resp, nil
+}
+
+// newFailedResponse builds the response for a failed operation
+func newFailedResponse(event *event, physicalID string, err error) *response {
+	resp := newResponse(event, statusFailed, physicalID)
+	resp.Reason = truncateReason(err.Error())
+	return resp
+}
+
+func truncateReason(reason string) string {
+	if len(reason) <= maxReasonLength {
+		return reason
+	}
+	return reason[:maxReasonLength-3] + "..."
+}
+
+// send uploads the response to the presigned URL, retrying on failure
+func (r *response) send(ctx context.Context, url string) error {
+	body, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	delay := ResponseRetryDelay
+	for attempt := 1; ; attempt++ {
+		err = putResponse(ctx, url, body)
+		if err == nil {
+			return nil
+		}
+
+		if attempt >= ResponseAttempts {
+			return fmt.Errorf("unable to send response after %d attempts: %w", attempt, err)
+		}
+
+		log.Printf("Failed to send response (attempt %d of %d): %v", attempt, ResponseAttempts, err)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("unable to send response: %w", err)
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+func putResponse(ctx context.Context, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	// The presigned URL is signed without a content type
+	req.Header.Set("Content-Type", "")
+	req.ContentLength = int64(len(body))
+
+	resp, err := HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, msg)
+	}
+
+	return nil
+}
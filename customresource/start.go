@@ -0,0 +1,144 @@
+package customresource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/webdestroya/cfnresource/cfnerr"
+	"github.com/webdestroya/cfnresource/internal/handlerutil"
+)
+
+// TimeoutMargin is how long before the Lambda deadline a handler is abandoned,
+// so that a FAILED response can still be sent. Without a response, the stack
+// would wait for up to an hour.
+var TimeoutMargin = 5 * time.Second
+
+// Start starts the Lambda runtime for a custom resource handler
+func Start[Properties any](handler Handler[Properties]) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Handler panicked: %s", r)
+			panic(r) // Continue the panic
+		}
+	}()
+
+	log.Printf("Handler starting")
+	lambda.Start(makeEventFunc(handler))
+}
+
+func makeEventFunc[Properties any](handler Handler[Properties]) func(context.Context, *event) error {
+	return func(ctx context.Context, event *event) error {
+		resp := run(ctx, handler, event)
+
+		// the response must be sent even if the handler used up the whole deadline
+		sendCtx := context.WithoutCancel(ctx)
+		if err := resp.send(sendCtx, event.ResponseURL); err != nil {
+			// returning the error would make Lambda retry the whole event,
+			// running the handler again, so only the upload is retried
+			log.Printf("Unable to send %s response: %v", resp.Status, err)
+			return nil
+		}
+
+		log.Printf("Sent %s response for %s", resp.Status, resp.PhysicalResourceID)
+		return nil
+	}
+}
+
+// run invokes the handler and builds the response to send
+func run[Properties any](ctx context.Context, handler Handler[Properties], event *event) *response {
+	physicalID := event.PhysicalResourceID
+
+	req, err := newRequest[Properties](event)
+	if err != nil {
+		return newFailedResponse(event, fallbackPhysicalID(event), err)
+	}
+
+	if physicalID == "" {
+		physicalID = req.defaultPhysicalResourceID()
+	}
+
+	handlerFn, err := router(event.RequestType, handler)
+	if err != nil {
+		return newFailedResponse(event, physicalID, err)
+	}
+
+	result, err := invoke(handlerFn, ctx, req)
+	if err != nil {
+		return newFailedResponse(event, physicalID, err)
+	}
+
+	if result != nil && result.PhysicalResourceID != "" {
+		physicalID = result.PhysicalResourceID
+	}
+
+	resp, err := newSuccessResponse(event, physicalID, result)
+	if err != nil {
+		return newFailedResponse(event, physicalID, err)
+	}
+	return resp
+}
+
+func fallbackPhysicalID(event *event) string {
+	if event.PhysicalResourceID != "" {
+		return event.PhysicalResourceID
+	}
+	return fmt.Sprintf("%s-%s", event.LogicalResourceID, event.RequestID)
+}
+
+func router[Properties any](requestType string, handler Handler[Properties]) (handlerFunc[Properties], error) {
+	switch requestType {
+	case createRequest:
+		return handler.Create, nil
+	case updateRequest:
+		return handler.Update, nil
+	case deleteRequest:
+		return handler.Delete, nil
+	default:
+		return nil, cfnerr.NewMessage(cfnerr.InvalidRequest, fmt.Sprintf("Unsupported request type %q", requestType))
+	}
+}
+
+// invoke calls the handler, converting panics into errors and giving up
+// TimeoutMargin before the context's deadline
+func invoke[Properties any](handlerFn handlerFunc[Properties], ctx context.Context, req *Request[Properties]) (*Result, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline.Add(-TimeoutMargin))
+		defer cancel()
+	}
+
+	type outcome struct {
+		result *Result
+		err    error
+	}
+
+	ch := make(chan outcome, 1)
+
+	go func() {
+		defer func() {
+			// Catch any panics and report a failure
+			if r := recover(); r != nil {
+				err := handlerutil.PanicError(r)
+				log.Printf("Trapped error in handler: %v", err)
+				ch <- outcome{err: err}
+			}
+		}()
+
+		result, err := handlerFn(ctx, req)
+		ch <- outcome{result, err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, errors.New("handler timed out before completing")
+		}
+		return nil, ctx.Err()
+	case out := <-ch:
+		return out.result, out.err
+	}
+}
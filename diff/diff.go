@@ -0,0 +1,305 @@
+// Package diff compares two resource models and reports which properties
+// changed, so that update handlers can decide which API calls to make.
+package diff
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ChangeType describes how a property changed
+type ChangeType string
+
+const (
+	Added    ChangeType = "Added"
+	Removed  ChangeType = "Removed"
+	Modified ChangeType = "Modified"
+)
+
+// Path is the location of a property within a model, using json names and
+// list indexes, such as ["Tags", "0", "Key"]
+type Path []string
+
+func (p Path) String() string {
+	return strings.Join(p, ".")
+}
+
+// hasPrefix reports whether p is prefix or is nested underneath it
+func (p Path) hasPrefix(prefix Path) bool {
+	return len(p) >= len(prefix) && slices.Equal(p[:len(prefix)], prefix)
+}
+
+// Change is a single difference between the old and new model. Old is nil for
+// added values and New is nil for removed values.
+//
+// Lists are compared without regard to position: elements that only appear in
+// the old list are Removed and those that only appear in the new list are
+// Added, with the path holding the element's index in its own list. A list
+// that only changed order is reported as Modified.
+type Change struct {
+	Type ChangeType
+	Path Path
+	Old  any
+	New  any
+}
+
+func (c Change) String() string {
+	switch c.Type {
+	case Added:
+		return fmt.Sprintf("%s %s: %v", c.Type, c.Path, c.New)
+	case Removed:
+		return fmt.Sprintf("%s %s: %v", c.Type, c.Path, c.Old)
+	default:
+		return fmt.Sprintf("%s %s: %v -> %v", c.Type, c.Path, c.Old, c.New)
+	}
+}
+
+// Changes is the set of differences between two models
+type Changes []Change
+
+// Empty reports whether the models were equal
+func (c Changes) Empty() bool {
+	return len(c) == 0
+}
+
+// Changed reports whether anything at or below any of the given paths
+// changed. Paths are dot separated json names, such as "Tags" or
+// "Config.Retention".
+func (c Changes) Changed(paths ...string) bool {
+	return len(c.Under(paths...)) > 0
+}
+
+// OnlyChanged reports whether there were changes, and all of them are at or
+// below the given paths. This is useful to detect updates that can be
+// applied with a single API call.
+func (c Changes) OnlyChanged(paths ...string) bool {
+	return len(c) > 0 && len(c.Under(paths...)) == len(c)
+}
+
+// Under returns the changes at or below any of the given paths
+func (c Changes) Under(paths ...string) Changes {
+	prefixes := make([]Path, 0, len(paths))
+	for _, p := range paths {
+		prefixes = append(prefixes, strings.Split(p, "."))
+	}
+
+	var out Changes
+	for _, change := range c {
+		for _, prefix := range prefixes {
+			if change.Path.hasPrefix(prefix) {
+				out = append(out, change)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// Compare returns the changes needed to turn old into new. Both values must be
+// the same type, and either may be nil. Struct fields are named by their json
+// tag, and fields tagged `json:"-"` are skipped.
+func Compare(old any, new any) Changes {
+	var changes Changes
+	compare(&changes, Path{}, reflect.ValueOf(old), reflect.ValueOf(new), false)
+	return changes
+}
+
+// compare appends the differences between a and b to changes. Invalid values
+// are treated as absent.
+func compare(changes *Changes, path Path, a reflect.Value, b reflect.Value, omitEmpty bool) {
+	a = indirect(a, omitEmpty)
+	b = indirect(b, omitEmpty)
+
+	switch {
+	case !a.IsValid() && !b.IsValid():
+		return
+	case isStruct(a) || isStruct(b):
+		if !a.IsValid() || !b.IsValid() || a.Type() == b.Type() {
+			compareStruct(changes, path, a, b)
+			return
+		}
+	}
+
+	switch {
+	case !a.IsValid():
+		*changes = append(*changes, Change{Type: Added, Path: path, New: b.Interface()})
+		return
+	case !b.IsValid():
+		*changes = append(*changes, Change{Type: Removed, Path: path, Old: a.Interface()})
+		return
+	case a.Type() != b.Type():
+		*changes = append(*changes, Change{Type: Modified, Path: path, Old: a.Interface(), New: b.Interface()})
+		return
+	}
+
+	switch a.Kind() {
+	case reflect.Map:
+		keys := make([]string, 0, a.Len()+b.Len())
+		values := make(map[string]reflect.Value, a.Len()+b.Len())
+		for _, m := range []reflect.Value{a, b} {
+			for _, k := range m.MapKeys() {
+				key := fmt.Sprint(k.Interface())
+				if _, ok := values[key]; !ok {
+					keys = append(keys, key)
+					values[key] = k
+				}
+			}
+		}
+		slices.Sort(keys)
+
+		for _, key := range keys {
+			k := values[key]
+			compare(changes, path.child(key), a.MapIndex(k), b.MapIndex(k), false)
+		}
+
+	case reflect.Slice, reflect.Array:
+		compareList(changes, path, a, b)
+
+	default:
+		if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+			*changes = append(*changes, Change{Type: Modified, Path: path, Old: a.Interface(), New: b.Interface()})
+		}
+	}
+}
+
+// compareStruct compares each field of two structs, either of which may be
+// absent, so that a whole struct being added or removed is still reported
+// per property
+func compareStruct(changes *Changes, path Path, a reflect.Value, b reflect.Value) {
+	var t reflect.Type
+	if a.IsValid() {
+		t = a.Type()
+	} else {
+		t = b.Type()
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		name, omitEmpty, skip := jsonName(f)
+		if skip {
+			continue
+		}
+
+		var fa, fb reflect.Value
+		if a.IsValid() {
+			fa = a.Field(i)
+		}
+		if b.IsValid() {
+			fb = b.Field(i)
+		}
+
+		compare(changes, path.child(name), fa, fb, omitEmpty)
+	}
+}
+
+func isStruct(v reflect.Value) bool {
+	return v.IsValid() && v.Kind() == reflect.Struct
+}
+
+// compareList matches equal elements regardless of their position, and
+// reports the rest as added or removed
+func compareList(changes *Changes, path Path, a reflect.Value, b reflect.Value) {
+	matched := make([]bool, b.Len())
+	var removed []int
+
+	for i := 0; i < a.Len(); i++ {
+		found := false
+		for j := 0; j < b.Len(); j++ {
+			if !matched[j] && reflect.DeepEqual(a.Index(i).Interface(), b.Index(j).Interface()) {
+				matched[j] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			removed = append(removed, i)
+		}
+	}
+
+	added := 0
+	for j := range matched {
+		if !matched[j] {
+			added++
+		}
+	}
+
+	if len(removed) == 0 && added == 0 {
+		if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+			*changes = append(*changes, Change{Type: Modified, Path: path, Old: a.Interface(), New: b.Interface()})
+		}
+		return
+	}
+
+	for _, i := range removed {
+		*changes = append(*changes, Change{Type: Removed, Path: path.child(strconv.Itoa(i)), Old: a.Index(i).Interface()})
+	}
+	for j := range matched {
+		if !matched[j] {
+			*changes = append(*changes, Change{Type: Added, Path: path.child(strconv.Itoa(j)), New: b.Index(j).Interface()})
+		}
+	}
+}
+
+// indirect dereferences pointers and interfaces, returning an invalid value
+// for nil and, if omitEmpty is set, for empty values
+func indirect(v reflect.Value, omitEmpty bool) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+
+	if !v.IsValid() {
+		return v
+	}
+
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice:
+		if v.IsNil() || (omitEmpty && v.Len() == 0) {
+			return reflect.Value{}
+		}
+	default:
+		if omitEmpty && v.IsZero() {
+			return reflect.Value{}
+		}
+	}
+
+	return v
+}
+
+// jsonName returns the name a struct field is encoded as
+func jsonName(f reflect.StructField) (name string, omitEmpty bool, skip bool) {
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+
+	name, opts, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = f.Name
+	}
+
+	for _, opt := range strings.Split(opts, ",") {
+		if opt == "omitempty" {
+			omitEmpty = true
+		}
+	}
+
+	return name, omitEmpty, false
+}
+
+// child returns a copy of p with name appended, so that sibling paths never
+// share a backing array
+func (p Path) child(name string) Path {
+	out := make(Path, len(p), len(p)+1)
+	copy(out, p)
+	return append(out, name)
+}
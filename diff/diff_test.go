@@ -0,0 +1,125 @@
+package diff_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/webdestroya/cfnresource/diff"
+)
+
+type tag struct {
+	Key   string `json:"Key"`
+	Value string `json:"Value"`
+}
+
+type config struct {
+	Retention int  `json:",omitempty"`
+	Enabled   bool `json:",omitempty"`
+}
+
+type model struct {
+	Name        *string           `json:"Name,omitempty"`
+	Description string            `json:",omitempty"`
+	Ports       []int             `json:",omitempty"`
+	Tags        []tag             `json:",omitempty"`
+	Labels      map[string]string `json:",omitempty"`
+	Config      *config           `json:",omitempty"`
+	Secret      string            `json:"-"`
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func summarize(changes diff.Changes) []string {
+	out := make([]string, 0, len(changes))
+	for _, c := range changes {
+		out = append(out, c.String())
+	}
+	return out
+}
+
+func TestCompare(t *testing.T) {
+	old := &model{
+		Name:        ptr("a"),
+		Description: "old",
+		Ports:       []int{80, 443},
+		Tags:        []tag{{"Env", "dev"}, {"Team", "core"}},
+		Labels:      map[string]string{"keep": "1", "drop": "2"},
+		Secret:      "x",
+	}
+	updated := &model{
+		Name:        ptr("a"),
+		Description: "new",
+		Ports:       []int{443, 80},
+		Tags:        []tag{{"Team", "core"}, {"Env", "prod"}},
+		Labels:      map[string]string{"keep": "1", "add": "3"},
+		Config:      &config{Retention: 7},
+		Secret:      "y",
+	}
+
+	changes := diff.Compare(old, updated)
+	require.Equal(t, []string{
+		"Modified Description: old -> new",
+		"Modified Ports: [80 443] -> [443 80]",
+		"Removed Tags.0: {Env dev}",
+		"Added Tags.1: {Env prod}",
+		"Added Labels.add: 3",
+		"Removed Labels.drop: 2",
+		"Added Config.Retention: 7",
+	}, summarize(changes))
+
+	require.True(t, changes.Changed("Tags"))
+	require.True(t, changes.Changed("Config.Retention"))
+	require.False(t, changes.Changed("Name", "Config.Enabled"))
+	require.False(t, changes.OnlyChanged("Description"))
+	require.True(t, changes.OnlyChanged("Description", "Ports", "Tags", "Labels", "Config"))
+	require.Len(t, changes.Under("Labels"), 2)
+
+	t.Run("equal", func(t *testing.T) {
+		changes := diff.Compare(old, old)
+		require.True(t, changes.Empty())
+		require.False(t, changes.OnlyChanged("Description"))
+	})
+
+	t.Run("created", func(t *testing.T) {
+		changes := diff.Compare(nil, &model{Name: ptr("a"), Ports: []int{1}})
+		require.Equal(t, []string{"Added Name: a", "Added Ports: [1]"}, summarize(changes))
+
+		var none *model
+		require.Equal(t, []string{"Removed Name: a"}, summarize(diff.Compare(&model{Name: ptr("a")}, none)))
+	})
+
+	t.Run("untyped", func(t *testing.T) {
+		changes := diff.Compare(
+			map[string]any{"List": []any{"a", "b"}, "Nested": map[string]any{"X": 1.0}},
+			map[string]any{"List": []any{"a"}, "Nested": map[string]any{"X": "1"}},
+		)
+		require.Equal(t, []string{"Removed List.1: b", "Modified Nested.X: 1 -> 1"}, summarize(changes))
+	})
+}
+
+func TestTags(t *testing.T) {
+	changes := diff.Tags(
+		[]tag{{"Env", "dev"}, {"Team", "core"}, {"Old", "x"}},
+		[]tag{{"Env", "prod"}, {"Team", "core"}, {"New", "y"}},
+	)
+	require.Equal(t, map[string]string{"Env": "prod", "New": "y"}, changes.Set)
+	require.Equal(t, []string{"Old"}, changes.Remove)
+
+	changes = diff.Tags(nil, map[string]string{"a": "b"})
+	require.Equal(t, map[string]string{"a": "b"}, changes.Set)
+	require.Empty(t, changes.Remove)
+
+	changes = diff.Tags([]any{map[string]any{"Key": "a", "Value": "b"}}, []map[string]string{{"Key": "a", "Value": "b"}})
+	require.True(t, changes.Empty())
+
+	t.Run("reserved", func(t *testing.T) {
+		changes := diff.Tags(
+			map[string]string{"aws:cloudformation:stack-name": "old", "AWS:Old": "x", "Env": "dev"},
+			map[string]string{"aws:cloudformation:stack-name": "new", "aws:new": "y", "Env": "prod"},
+		)
+		require.Equal(t, map[string]string{"Env": "prod"}, changes.Set)
+		require.Empty(t, changes.Remove)
+	})
+}
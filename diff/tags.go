@@ -0,0 +1,132 @@
+package diff
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/webdestroya/cfnresource/tags"
+)
+
+// TagChanges are the differences between two sets of tags, in the shape that
+// most tagging APIs expect
+type TagChanges struct {
+	// Set holds tags that were added or had their value changed
+	Set map[string]string
+
+	// Remove holds the keys of tags that were removed
+	Remove []string
+}
+
+// Empty reports whether the tags were equal
+func (t TagChanges) Empty() bool {
+	return len(t.Set) == 0 && len(t.Remove) == 0
+}
+
+// Tags compares two tag collections. Each may be a map of string keys to
+// values, or a list of structs (or maps) with Key and Value fields, as is
+// usual for resource models. Nil collections are treated as empty.
+//
+// Reserved aws: tags (see tags.IsReserved) are ignored, as they cannot be
+// changed after the resource is created.
+func Tags(old any, new any) TagChanges {
+	before := tagMap(reflect.ValueOf(old))
+	after := tagMap(reflect.ValueOf(new))
+
+	changes := TagChanges{Set: map[string]string{}}
+	for k, v := range after {
+		if prev, ok := before[k]; !ok || prev != v {
+			changes.Set[k] = v
+		}
+	}
+	for k := range before {
+		if _, ok := after[k]; !ok {
+			changes.Remove = append(changes.Remove, k)
+		}
+	}
+	slices.Sort(changes.Remove)
+
+	return changes
+}
+
+// tagMap flattens a tag collection into a map, leaving out reserved tags
+func tagMap(v reflect.Value) map[string]string {
+	out := map[string]string{}
+
+	v = indirect(v, false)
+	if !v.IsValid() {
+		return out
+	}
+
+	switch v.Kind() {
+	case reflect.Map:
+		for _, k := range v.MapKeys() {
+			out[fmt.Sprint(k.Interface())] = tagString(v.MapIndex(k))
+		}
+
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			key, value, ok := tagPair(v.Index(i))
+			if ok {
+				out[key] = value
+			}
+		}
+	}
+
+	maps.DeleteFunc(out, func(k string, _ string) bool { return tags.IsReserved(k) })
+
+	return out
+}
+
+// tagPair extracts the key and value of a single tag in a list
+func tagPair(v reflect.Value) (string, string, bool) {
+	v = indirect(v, false)
+	if !v.IsValid() {
+		return "", "", false
+	}
+
+	var key, value reflect.Value
+
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			name, _, skip := jsonName(t.Field(i))
+			if skip || !t.Field(i).IsExported() {
+				continue
+			}
+			switch {
+			case strings.EqualFold(name, "Key"):
+				key = v.Field(i)
+			case strings.EqualFold(name, "Value"):
+				value = v.Field(i)
+			}
+		}
+
+	case reflect.Map:
+		for _, k := range v.MapKeys() {
+			switch {
+			case strings.EqualFold(fmt.Sprint(k.Interface()), "Key"):
+				key = v.MapIndex(k)
+			case strings.EqualFold(fmt.Sprint(k.Interface()), "Value"):
+				value = v.MapIndex(k)
+			}
+		}
+	}
+
+	if !indirect(key, false).IsValid() {
+		return "", "", false
+	}
+
+	return tagString(key), tagString(value), true
+}
+
+func tagString(v reflect.Value) string {
+	v = indirect(v, false)
+	if !v.IsValid() {
+		return ""
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,330 @@
+package encoding
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// normalizeTagName is the struct tag that holds normalization options
+const normalizeTagName = "cfn"
+
+// normalizeOptions are the options from a field's cfn struct tag
+type normalizeOptions struct {
+	// unordered lists are reordered to match the desired list
+	unordered bool
+
+	// caseInsensitive strings are replaced by the desired value if they only differ by case
+	caseInsensitive bool
+
+	// defaultValue is used when the actual value is empty
+	defaultValue *string
+}
+
+// parseNormalizeTag parses a tag such as `cfn:"unordered,caseInsensitive"` or
+// `cfn:"default=abc"`. A default must be the last option, as its value may
+// contain commas.
+func parseNormalizeTag(tag string) (normalizeOptions, error) {
+	var opts normalizeOptions
+
+	for tag != "" {
+		if v, ok := strings.CutPrefix(tag, "default="); ok {
+			opts.defaultValue = &v
+			break
+		}
+
+		var opt string
+		opt, tag, _ = strings.Cut(tag, ",")
+
+		switch strings.TrimSpace(opt) {
+		case "unordered":
+			opts.unordered = true
+		case "caseInsensitive":
+			opts.caseInsensitive = true
+		case "":
+		default:
+			return opts, fmt.Errorf("unknown %s tag option %q", normalizeTagName, opt)
+		}
+	}
+
+	return opts, nil
+}
+
+// Normalize adjusts a model read from a service so that it does not differ
+// from the desired model in ways that are not meaningful, which would
+// otherwise be reported as drift. The behaviour is controlled by cfn struct tags:
+//
+//	Name  string   `cfn:"caseInsensitive"`  // use the desired value if they only differ by case
+//	Ports []int    `cfn:"unordered"`        // reorder the list to match the desired order
+//	Mode  *string  `cfn:"default=standard"` // fill an empty value with the documented default
+//
+// Options can be combined, separated by commas, with default always last.
+// Defaults are only allowed on pointer, slice and map fields, where an unset
+// value can be told apart from an explicit false, 0 or "". Slice and map
+// defaults are written as JSON, such as `cfn:"default=[\"a\",\"b\"]"`.
+// The actual argument must be a non-nil pointer. The desired
+// argument must be the same type, or nil, in which case only defaults are applied.
+func Normalize(actual any, desired any) error {
+	av := reflect.ValueOf(actual)
+	if av.Kind() != reflect.Ptr || av.IsNil() {
+		return fmt.Errorf("Normalize requires a non-nil pointer, got %T", actual)
+	}
+
+	var dv reflect.Value
+	if desired != nil {
+		dv = reflect.ValueOf(desired)
+		if dv.Type() != av.Type() {
+			return fmt.Errorf("Normalize requires matching types, got %T and %T", actual, desired)
+		}
+		if dv.IsNil() {
+			dv = reflect.Value{}
+		}
+	}
+
+	return normalizeValue(av, dv, normalizeOptions{})
+}
+
+// normalizeValue normalizes the settable value a against the (possibly invalid) value d
+func normalizeValue(a reflect.Value, d reflect.Value, opts normalizeOptions) error {
+	switch a.Kind() {
+	case reflect.Ptr:
+		if a.IsNil() {
+			return nil
+		}
+		if d.IsValid() {
+			if d.IsNil() {
+				d = reflect.Value{}
+			} else {
+				d = d.Elem()
+			}
+		}
+		return normalizeValue(a.Elem(), d, opts)
+
+	case reflect.Struct:
+		t := a.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+
+			fieldOpts, err := parseNormalizeTag(f.Tag.Get(normalizeTagName))
+			if err != nil {
+				return fmt.Errorf("%s.%s: %w", t.Name(), f.Name, err)
+			}
+
+			fa := a.Field(i)
+			var fd reflect.Value
+			if d.IsValid() {
+				fd = d.Field(i)
+			}
+
+			if fieldOpts.defaultValue != nil {
+				switch f.Type.Kind() {
+				case reflect.Ptr, reflect.Slice, reflect.Map:
+				default:
+					return fmt.Errorf("%s.%s: %s tag default is only supported on pointer, slice and map fields", t.Name(), f.Name, normalizeTagName)
+				}
+			}
+
+			if fieldOpts.defaultValue != nil && fa.IsZero() {
+				if err := setDefault(fa, *fieldOpts.defaultValue); err != nil {
+					return fmt.Errorf("%s.%s: %w", t.Name(), f.Name, err)
+				}
+			}
+
+			if err := normalizeValue(fa, fd, fieldOpts); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case reflect.Slice:
+		elemOpts := normalizeOptions{caseInsensitive: opts.caseInsensitive}
+
+		if opts.unordered && d.IsValid() {
+			if err := reorderSlice(a, d, elemOpts); err != nil {
+				return err
+			}
+		}
+
+		for i := 0; i < a.Len(); i++ {
+			var de reflect.Value
+			if d.IsValid() && i < d.Len() {
+				de = d.Index(i)
+			}
+			if err := normalizeValue(a.Index(i), de, elemOpts); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case reflect.Map:
+		elemOpts := normalizeOptions{caseInsensitive: opts.caseInsensitive}
+
+		for _, key := range a.MapKeys() {
+			var de reflect.Value
+			if d.IsValid() {
+				de = d.MapIndex(key)
+			}
+
+			// map values are not addressable, so normalize a copy
+			v := reflect.New(a.Type().Elem()).Elem()
+			v.Set(a.MapIndex(key))
+			if err := normalizeValue(v, de, elemOpts); err != nil {
+				return err
+			}
+			a.SetMapIndex(key, v)
+		}
+		return nil
+
+	case reflect.String:
+		if opts.caseInsensitive && d.IsValid() && a.String() != d.String() && strings.EqualFold(a.String(), d.String()) {
+			a.SetString(d.String())
+		}
+		return nil
+	}
+
+	return nil
+}
+
+// reorderSlice sorts the elements of a so that those equivalent to an element
+// of d appear in the same order as in d. Other elements keep their relative
+// order, after the matched ones.
+func reorderSlice(a reflect.Value, d reflect.Value, elemOpts normalizeOptions) error {
+	used := make([]bool, a.Len())
+	ordered := reflect.MakeSlice(a.Type(), 0, a.Len())
+
+	for j := 0; j < d.Len(); j++ {
+		for i := 0; i < a.Len(); i++ {
+			if used[i] {
+				continue
+			}
+
+			same, err := equivalent(a.Index(i), d.Index(j), elemOpts)
+			if err != nil {
+				return err
+			}
+
+			if same {
+				used[i] = true
+				ordered = reflect.Append(ordered, a.Index(i))
+				break
+			}
+		}
+	}
+
+	for i := 0; i < a.Len(); i++ {
+		if !used[i] {
+			ordered = reflect.Append(ordered, a.Index(i))
+		}
+	}
+
+	reflect.Copy(a, ordered)
+	return nil
+}
+
+// equivalent reports whether a would equal d after being normalized against it
+func equivalent(a reflect.Value, d reflect.Value, opts normalizeOptions) (bool, error) {
+	trial := deepCopy(a)
+	if err := normalizeValue(trial, d, opts); err != nil {
+		return false, err
+	}
+	return reflect.DeepEqual(trial.Interface(), d.Interface()), nil
+}
+
+// deepCopy returns a settable copy of v that shares no pointers, slices or maps with it
+func deepCopy(v reflect.Value) reflect.Value {
+	out := reflect.New(v.Type()).Elem()
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			p := reflect.New(v.Type().Elem())
+			p.Elem().Set(deepCopy(v.Elem()))
+			out.Set(p)
+		}
+	case reflect.Slice:
+		if !v.IsNil() {
+			s := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+			for i := 0; i < v.Len(); i++ {
+				s.Index(i).Set(deepCopy(v.Index(i)))
+			}
+			out.Set(s)
+		}
+	case reflect.Map:
+		if !v.IsNil() {
+			m := reflect.MakeMapWithSize(v.Type(), v.Len())
+			for _, key := range v.MapKeys() {
+				m.SetMapIndex(key, deepCopy(v.MapIndex(key)))
+			}
+			out.Set(m)
+		}
+	case reflect.Struct:
+		out.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if out.Field(i).CanSet() {
+				out.Field(i).Set(deepCopy(v.Field(i)))
+			}
+		}
+	default:
+		out.Set(v)
+	}
+
+	return out
+}
+
+// setDefault parses the tag value into the kind of v
+func setDefault(v reflect.Value, value string) error {
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Map {
+		p := reflect.New(v.Type())
+		if err := json.Unmarshal([]byte(value), p.Interface()); err != nil {
+			return err
+		}
+		v.Set(p.Elem())
+		return nil
+	}
+
+	if v.Kind() == reflect.Ptr {
+		p := reflect.New(v.Type().Elem())
+		if err := setDefault(p.Elem(), value); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+
+	default:
+		return errors.New("defaults are only supported for string, bool, int and float fields")
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package encoding_test
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/require"
+	"github.com/webdestroya/cfnresource/encoding"
+)
+
+type normalizeTag struct {
+	Key   string `cfn:"caseInsensitive"`
+	Value string
+}
+
+type normalizeModel struct {
+	Name        string         `cfn:"caseInsensitive"`
+	Engine      *string        `cfn:"default=postgres"`
+	Port        *int           `cfn:"default=5432"`
+	Encrypted   *bool          `cfn:"default=true"`
+	Description *string        `cfn:"default=a, b and c"`
+	Modes       []string       `cfn:"default=[\"read\", \"write\"]"`
+	Zones       []string       `cfn:"unordered,caseInsensitive"`
+	Ports       []int          `cfn:"unordered"`
+	Ordered     []string       `json:",omitempty"`
+	Tags        []normalizeTag `cfn:"unordered"`
+	Labels      map[string]string
+	Nested      *normalizeModel
+}
+
+func TestNormalize(t *testing.T) {
+	desired := &normalizeModel{
+		Name:    "MyDatabase",
+		Zones:   []string{"us-east-1a", "US-EAST-1B"},
+		Ports:   []int{80, 443},
+		Ordered: []string{"a", "b"},
+		Tags: []normalizeTag{
+			{Key: "Env", Value: "prod"},
+			{Key: "Team", Value: "core"},
+		},
+		Nested: &normalizeModel{Name: "Inner", Ports: []int{1, 2}},
+	}
+
+	actual := &normalizeModel{
+		Name:    "mydatabase",
+		Port:    aws.Int(3306),
+		Zones:   []string{"us-east-1c", "us-east-1b", "us-east-1a"},
+		Ports:   []int{443, 8080, 80},
+		Ordered: []string{"b", "a"},
+		Tags: []normalizeTag{
+			{Key: "team", Value: "core"},
+			{Key: "Env", Value: "prod"},
+		},
+		Nested: &normalizeModel{Name: "INNER", Ports: []int{2, 1}},
+	}
+
+	require.NoError(t, encoding.Normalize(actual, desired))
+
+	require.Equal(t, "MyDatabase", actual.Name)
+	require.Equal(t, "postgres", *actual.Engine)
+	require.Equal(t, 3306, *actual.Port)
+	require.True(t, *actual.Encrypted)
+	require.Equal(t, "a, b and c", *actual.Description)
+	require.Equal(t, []string{"read", "write"}, actual.Modes)
+	require.Equal(t, []string{"us-east-1a", "US-EAST-1B", "us-east-1c"}, actual.Zones)
+	require.Equal(t, []int{80, 443, 8080}, actual.Ports)
+	require.Equal(t, []string{"b", "a"}, actual.Ordered)
+	require.Equal(t, desired.Tags, actual.Tags)
+	require.Equal(t, "Inner", actual.Nested.Name)
+	require.Equal(t, []int{1, 2}, actual.Nested.Ports)
+	require.Equal(t, 5432, *actual.Nested.Port)
+
+	t.Run("explicit zero values", func(t *testing.T) {
+		actual := &normalizeModel{Encrypted: aws.Bool(false), Port: aws.Int(0), Description: aws.String(""), Modes: []string{}}
+		require.NoError(t, encoding.Normalize(actual, nil))
+		require.False(t, *actual.Encrypted)
+		require.Equal(t, 0, *actual.Port)
+		require.Equal(t, "", *actual.Description)
+		require.Empty(t, actual.Modes)
+	})
+
+	t.Run("no desired", func(t *testing.T) {
+		actual := &normalizeModel{Name: "x", Ports: []int{2, 1}}
+		require.NoError(t, encoding.Normalize(actual, nil))
+		require.Equal(t, "postgres", *actual.Engine)
+		require.Equal(t, []int{2, 1}, actual.Ports)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		require.Error(t, encoding.Normalize(normalizeModel{}, nil))
+		require.Error(t, encoding.Normalize(&normalizeModel{}, &normalizeTag{}))
+
+		type badTag struct {
+			Name string `cfn:"sorted"`
+		}
+		require.ErrorContains(t, encoding.Normalize(&badTag{}, nil), `unknown cfn tag option "sorted"`)
+
+		type badDefault struct {
+			Count *int `cfn:"default=many"`
+		}
+		require.Error(t, encoding.Normalize(&badDefault{}, nil))
+
+		type valueDefault struct {
+			Enabled bool `cfn:"default=true"`
+		}
+		require.ErrorContains(t, encoding.Normalize(&valueDefault{}, nil), "valueDefault.Enabled: cfn tag default is only supported on pointer, slice and map fields")
+	})
+}
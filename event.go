@@ -1,27 +1,135 @@
 package cfnresource
 
 import (
-	"context"
 	"encoding/json"
+	"fmt"
+	"strings"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/webdestroya/cfnresource/cfnerr"
+	"github.com/webdestroya/cfnresource/internal/handlerutil"
+	"github.com/webdestroya/cfnresource/tags"
 )
 
 // Tags are stored as key/value paired strings
-type Tags map[string]string
+type Tags = tags.Tags
 
 type event struct {
 	AWSAccountID        string          `json:"awsAccountId"`
 	BearerToken         string          `json:"bearerToken" validate:"nonzero"`
+	ClientRequestToken  string          `json:"clientRequestToken"`
 	Region              string          `json:"region" validate:"nonzero"`
 	Action              string          `json:"action"`
 	ResourceType        string          `json:"resourceType"`
-	ResourceTypeVersion string          `json:"resourceTypeVersion"` // encoding.Float
+	ResourceTypeVersion typeVersion     `json:"resourceTypeVersion"`
 	CallbackContext     json.RawMessage `json:"callbackContext,omitempty"`
 	RequestData         requestData     `json:"requestData"`
 	StackID             string          `json:"stackId"`
+	StackName           string          `json:"stackName"`
 	NextToken           string
+	SnapshotRequested   bool            `json:"snapshotRequested"`
+	Rollback            bool            `json:"rollback"`
+	Driftable           bool            `json:"driftable"`
+	Features            map[string]any  `json:"features"`
+	UpdatePolicy        json.RawMessage `json:"updatePolicy"`
+	CreationPolicy      json.RawMessage `json:"creationPolicy"`
+
+	// raw is the payload the event was decoded from
+	raw json.RawMessage
+}
+
+func (e *event) UnmarshalJSON(data []byte) error {
+	type plainEvent event
+	if err := json.Unmarshal(data, (*plainEvent)(e)); err != nil {
+		return err
+	}
+	e.raw = append(json.RawMessage(nil), data...)
+	return nil
+}
+
+// validate checks that the event has the fields every invocation needs,
+// before anything is done with it
+func (e *event) validate() error {
+	if missing := handlerutil.MissingFields(e); len(missing) > 0 {
+		return cfnerr.NewMessage(cfnerr.InvalidRequest, "request is missing required fields: "+strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// callerCredentials returns the credentials to make the caller config with,
+// or nil to use the default credential chain, which allowDefault permits
+// when the credentials are missing or incomplete
+func (e *event) callerCredentials(allowDefault bool) (*credProvider, error) {
+	return resolveCredentials("callerCredentials", e.RequestData.CallerCredentials, true, allowDefault)
+}
+
+// providerCredentials returns the credentials to make the provider config
+// with, or nil to use the default credential chain. Provider credentials are
+// only sent when the resource type has a logging configuration.
+func (e *event) providerCredentials(allowDefault bool) (*credProvider, error) {
+	return resolveCredentials("providerCredentials", e.RequestData.ProviderCredentials, false, allowDefault)
+}
+
+func resolveCredentials(name string, creds *credProvider, required bool, allowDefault bool) (*credProvider, error) {
+	switch {
+	case creds.Valid():
+		return creds, nil
+	case allowDefault:
+		return nil, nil
+	case creds == nil && !required:
+		return nil, nil
+	case creds == nil:
+		return nil, cfnerr.NewMessage(cfnerr.InvalidCredentials, fmt.Sprintf("request does not include %s", name))
+	default:
+		return nil, cfnerr.NewMessage(cfnerr.InvalidCredentials, fmt.Sprintf("request includes incomplete %s", name))
+	}
+}
+
+// redactedPayload returns the raw payload with any credentials removed, or
+// nil if the event was not decoded from JSON
+func (e *event) redactedPayload() json.RawMessage {
+	if len(e.raw) == 0 {
+		return nil
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(e.raw, &payload); err != nil {
+		return nil
+	}
+
+	if data, ok := payload["requestData"].(map[string]any); ok {
+		for k := range data {
+			if strings.HasSuffix(k, "Credentials") {
+				data[k] = redacted
+			}
+		}
+	}
+
+	out, err := json.Marshal(payload)
+	if err != nil {
+		return nil
+	}
+	return out
+}
+
+const redacted = "REDACTED"
+
+// typeVersion is the resource type version, which can be sent as either a
+// string or a number
+type typeVersion string
+
+func (v *typeVersion) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*v = typeVersion(s)
+		return nil
+	}
+
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	*v = typeVersion(n.String())
+	return nil
 }
 
 // requestData is internal to the RPDK. It contains a number of fields that are for
@@ -40,21 +148,11 @@ type requestData struct {
 	ProviderLogGroupName string          `json:"providerLogGroupName"`
 	StackTags            Tags            `json:"stackTags"`
 	SystemTags           Tags            `json:"systemTags"`
+	PreviousStackTags    Tags            `json:"previousStackTags"`
+	PreviousSystemTags   Tags            `json:"previousSystemTags"`
+	DesiredResourceTags  Tags            `json:"desiredResourceTags"`
+	PreviousResourceTags Tags            `json:"previousResourceTags"`
 	TypeConfiguration    json.RawMessage `json:"typeConfiguration"`
 }
 
-type credProvider struct {
-	AccessKeyID     string `json:"accessKeyId"`
-	SecretAccessKey string `json:"secretAccessKey"`
-	SessionToken    string `json:"sessionToken"`
-}
-
-var _ aws.CredentialsProvider = (*credProvider)(nil)
-
-func (c *credProvider) Retrieve(ctx context.Context) (aws.Credentials, error) {
-	return credentials.NewStaticCredentialsProvider(c.AccessKeyID, c.SecretAccessKey, c.SessionToken).Retrieve(ctx)
-}
-
-func (credProvider) MarshalJSON() ([]byte, error) {
-	return []byte(`"REDACTED"`), nil
-}
+type credProvider = handlerutil.Credentials
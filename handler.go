@@ -11,3 +11,25 @@ type Handler[Model any, CallbackCtx any] interface {
 	Read(context.Context, *Request[Model, CallbackCtx]) (*ProgressEvent[Model, CallbackCtx], error)
 	List(context.Context, *Request[Model, CallbackCtx]) (*ProgressEvent[Model, CallbackCtx], error)
 }
+
+// HandlerFunc is a single handler operation, such as Handler.Create
+type HandlerFunc[Model any, CallbackCtx any] func(context.Context, *Request[Model, CallbackCtx]) (*ProgressEvent[Model, CallbackCtx], error)
+
+// Middleware wraps the handler operation chosen for an invocation, so that
+// behaviour can be added around every operation of a handler.
+type Middleware[Model any, CallbackCtx any] func(next HandlerFunc[Model, CallbackCtx]) HandlerFunc[Model, CallbackCtx]
+
+// applyMiddleware wraps handlerFn with the middleware of handlers that
+// implement MiddlewareProvider. The first middleware is the outermost.
+func applyMiddleware[Model any, CallbackCtx any](handlerFn HandlerFunc[Model, CallbackCtx], handler Handler[Model, CallbackCtx]) HandlerFunc[Model, CallbackCtx] {
+	h, ok := handler.(MiddlewareProvider[Model, CallbackCtx])
+	if !ok {
+		return handlerFn
+	}
+
+	middleware := h.Middleware()
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handlerFn = middleware[i](handlerFn)
+	}
+	return handlerFn
+}
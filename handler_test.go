@@ -11,6 +11,8 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/webdestroya/cfnresource/cfncontext"
+	"github.com/webdestroya/cfnresource/cfnerr"
+	"github.com/webdestroya/cfnresource/schema"
 )
 
 type fancyStr string
@@ -77,6 +79,13 @@ func (basicHandler) Create(ctx context.Context, req requestType) (resp progEvent
 
 	assert.Equal(t, "logically", req.LogicalResourceID)
 
+	info, err := cfncontext.RequestInfo(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, req.Info(), info)
+
+	_, err = cfncontext.GetProviderAwsConfig(ctx)
+	assert.NoError(t, err)
+
 	// return req.ErrorResponse("oops"), nil
 
 	if t.Failed() {
@@ -209,3 +218,263 @@ func TestPanicker(t *testing.T) {
 	require.EqualValues(t, cfnTypes.HandlerErrorCodeInternalFailure, resp.ErrorCode)
 	require.Contains(t, resp.Message, "invalid memory address")
 }
+
+func TestRequestTags(t *testing.T) {
+	ev := new(event)
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"action": "UPDATE",
+		"requestData": {
+			"resourceProperties": {},
+			"systemTags": {"aws:cloudformation:stack-name": "SampleStack"},
+			"stackTags": {"Env": "prod", "Team": "core"},
+			"desiredResourceTags": {"Env": "staging"},
+			"previousSystemTags": {"aws:cloudformation:stack-name": "SampleStack"},
+			"previousStackTags": {"Env": "prod"},
+			"previousResourceTags": {"Old": "yes"}
+		}
+	}`), ev))
+
+	req, err := newRequest[model, callbackCtx](ev)
+	require.NoError(t, err)
+
+	require.Equal(t, Tags{"aws:cloudformation:stack-name": "SampleStack", "Env": "staging", "Team": "core"}, req.DesiredTags())
+	require.Equal(t, Tags{"aws:cloudformation:stack-name": "SampleStack", "Env": "prod", "Old": "yes"}, req.PreviousTags())
+}
+
+func TestRequestPayload(t *testing.T) {
+	ev := new(event)
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"action": "DELETE",
+		"bearerToken": "bearer-123",
+		"clientRequestToken": "token-123",
+		"resourceType": "Dummy::Thing::Basic",
+		"resourceTypeVersion": 3,
+		"stackName": "SampleStack",
+		"snapshotRequested": true,
+		"rollback": true,
+		"driftable": true,
+		"features": {"preventIdempotentResourceAdoption": true},
+		"updatePolicy": {"UseOnlineResharding": true},
+		"somethingNew": "surprise",
+		"requestData": {
+			"resourceProperties": {"Name": "thing"},
+			"callerCredentials": {"accessKeyId": "AKIA", "secretAccessKey": "secret", "sessionToken": "session"},
+			"providerCredentials": {"accessKeyId": "AKIA", "secretAccessKey": "secret", "sessionToken": "session"}
+		}
+	}`), ev))
+
+	req, err := newRequest[model, callbackCtx](ev)
+	require.NoError(t, err)
+
+	require.Equal(t, "token-123", req.ClientRequestToken)
+	require.Equal(t, "3", req.ResourceTypeVersion)
+	require.Equal(t, "SampleStack", req.StackName)
+	require.True(t, req.SnapshotRequested)
+	require.True(t, req.Rollback)
+	require.True(t, req.Driftable)
+	require.Equal(t, map[string]any{"preventIdempotentResourceAdoption": true}, req.Features)
+	require.JSONEq(t, `{"UseOnlineResharding": true}`, string(req.UpdatePolicy))
+	require.Nil(t, req.CreationPolicy)
+
+	raw := string(req.Raw())
+	require.Contains(t, raw, `"somethingNew":"surprise"`)
+	require.Contains(t, raw, `"callerCredentials":"REDACTED"`)
+	require.NotContains(t, raw, "secret")
+
+	t.Run("fallbacks", func(t *testing.T) {
+		ev := newTestEvent(createAction, `{}`)
+		ev.RequestData.SystemTags = Tags{stackNameSystemTag: "TaggedStack"}
+
+		req, err := newRequest[model, callbackCtx](ev)
+		require.NoError(t, err)
+		require.Empty(t, req.ClientRequestToken)
+		require.Equal(t, "SampleStack", req.StackName)
+		require.Nil(t, req.Raw())
+
+		ev.StackID = ""
+		req, err = newRequest[model, callbackCtx](ev)
+		require.NoError(t, err)
+		require.Equal(t, "TaggedStack", req.StackName)
+	})
+}
+
+type middlewareHandler struct {
+	basicHandler
+	calls *[]string
+}
+
+func (h middlewareHandler) Middleware() []Middleware[model, callbackCtx] {
+	tag := func(name string) Middleware[model, callbackCtx] {
+		return func(next HandlerFunc[model, callbackCtx]) HandlerFunc[model, callbackCtx] {
+			return func(ctx context.Context, req requestType) (progEventType, error) {
+				*h.calls = append(*h.calls, name)
+				return next(ctx, req)
+			}
+		}
+	}
+	return []Middleware[model, callbackCtx]{tag("outer"), tag("inner")}
+}
+
+// loggingHandler records the events it is asked to log
+type loggingHandler struct {
+	readHandler
+	logged *[]any
+}
+
+func (h loggingHandler) LogEvent(ctx context.Context, ev any) {
+	*h.logged = append(*h.logged, ev)
+}
+
+func TestLogEventBeforeRequest(t *testing.T) {
+	var logged []any
+	fn := makeEventFunc[model, callbackCtx](loggingHandler{logged: &logged})
+
+	// an event whose model cannot be decoded is still logged
+	ev := newTestEvent(readAction, `{"Name": ["not", "a", "string"]}`)
+	resp, err := fn(context.Background(), ev)
+	require.Error(t, err)
+	require.Equal(t, cfnTypes.OperationStatusFailed, resp.OperationStatus)
+	require.Equal(t, []any{ev}, logged)
+}
+
+func TestApplyMiddleware(t *testing.T) {
+	var calls []string
+	h := middlewareHandler{calls: &calls}
+
+	fn := applyMiddleware(func(ctx context.Context, req requestType) (progEventType, error) {
+		calls = append(calls, "handler")
+		return req.SuccessResponse(req.ResourceProperties), nil
+	}, h)
+
+	_, err := fn(context.Background(), &Request[model, callbackCtx]{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"outer", "inner", "handler"}, calls)
+}
+
+type validatedModel struct {
+	Name *string `json:",omitempty" schema:"required,maxLength=5" validate:"min=1"`
+}
+
+func TestValidateModel(t *testing.T) {
+	tests := []struct {
+		name       string
+		action     string
+		properties string
+		callback   string
+		errorCode  cfnTypes.HandlerErrorCode
+	}{
+		{name: "valid create", action: createAction, properties: `{"Name": "abc"}`},
+		{name: "invalid create", action: createAction, properties: `{"Name": "abcdef"}`, errorCode: cfnTypes.HandlerErrorCodeInvalidRequest},
+		{name: "invalid update", action: updateAction, properties: `{}`, errorCode: cfnTypes.HandlerErrorCodeInvalidRequest},
+		{name: "callbacks are not validated", action: createAction, properties: `{}`, callback: `{}`},
+		{name: "delete is not validated", action: deleteAction, properties: `{}`},
+		{name: "read is not validated", action: readAction, properties: `{}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ev := newTestEvent(tt.action, tt.properties)
+			if tt.callback != "" {
+				ev.CallbackContext = json.RawMessage(tt.callback)
+			}
+
+			req, err := newRequest[validatedModel, callbackCtx](ev)
+			require.NoError(t, err)
+
+			err = validateModel(req)
+			if tt.errorCode == "" {
+				require.NoError(t, err)
+				return
+			}
+
+			ce, ok := cfnerr.As(err)
+			require.True(t, ok)
+			require.Equal(t, tt.errorCode, ce.Code())
+			require.Contains(t, ce.Message(), "Model validation failed (#/Name: ")
+		})
+	}
+
+	t.Run("untagged model", func(t *testing.T) {
+		req, err := newRequest[model, callbackCtx](newTestEvent(createAction, `{}`))
+		require.NoError(t, err)
+		require.NoError(t, validateModel(req))
+	})
+}
+
+// schemaHandler echoes the desired model, and checks it against a schema
+type schemaHandler struct {
+	readHandler
+	schema *schema.Schema
+}
+
+func (h schemaHandler) ResourceSchema() *schema.Schema {
+	return h.schema
+}
+
+func (schemaHandler) Create(ctx context.Context, req requestType) (progEventType, error) {
+	return req.SuccessResponse(req.ResourceProperties), nil
+}
+
+func TestResourceSchemaValidation(t *testing.T) {
+	s, err := schema.Parse([]byte(`{
+		"properties": {
+			"Name": {"type": "string", "maxLength": 5},
+			"IntVal": {"type": "integer"}
+		},
+		"required": ["Name", "IntVal"],
+		"readOnlyProperties": ["/properties/IntVal"],
+		"additionalProperties": false
+	}`))
+	require.NoError(t, err)
+
+	fn := makeEventFunc[model, callbackCtx](schemaHandler{schema: s})
+
+	tests := []struct {
+		name       string
+		action     string
+		properties string
+		status     cfnTypes.OperationStatus
+		errorCode  cfnTypes.HandlerErrorCode
+		message    string
+	}{
+		{
+			name:       "invalid input",
+			action:     createAction,
+			properties: `{"Name": "too long", "BoolVal": "true"}`,
+			status:     cfnTypes.OperationStatusFailed,
+			errorCode:  cfnTypes.HandlerErrorCodeInvalidRequest,
+			message:    "Model validation failed (#: extraneous key [BoolVal] is not permitted; #/Name: expected maxLength: 5, actual: 8)",
+		},
+		{
+			// the resource was created, so failing would leak it
+			name:       "invalid output is logged",
+			action:     createAction,
+			properties: `{"Name": "abc"}`,
+			status:     cfnTypes.OperationStatusSuccess,
+		},
+		{
+			name:       "valid",
+			action:     readAction,
+			properties: `{"Name": "abc", "IntVal": "3"}`,
+			status:     cfnTypes.OperationStatusSuccess,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := fn(context.Background(), newTestEvent(tt.action, tt.properties))
+			require.NoError(t, err)
+			require.Equal(t, tt.status, resp.OperationStatus)
+			require.EqualValues(t, tt.errorCode, resp.ErrorCode)
+			require.Equal(t, tt.message, resp.Message)
+		})
+	}
+
+	t.Run("strict", func(t *testing.T) {
+		fn := makeEventFunc[model, callbackCtx](schemaHandler{schema: s}, func(o *RuntimeOptions) { o.StrictContract = true })
+
+		require.Panics(t, func() {
+			_, _ = fn(context.Background(), newTestEvent(createAction, `{"Name": "abc"}`))
+		})
+	})
+}
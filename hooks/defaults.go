@@ -0,0 +1,5 @@
+package hooks
+
+import "time"
+
+var DefaultCallbackDelay = 30 * time.Second
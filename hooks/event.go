@@ -0,0 +1,49 @@
+package hooks
+
+import (
+	"encoding/json"
+
+	"github.com/webdestroya/cfnresource/internal/handlerutil"
+)
+
+// event is the payload CloudFormation sends when invoking a hook
+type event struct {
+	ClientRequestToken    string          `json:"clientRequestToken"`
+	AWSAccountID          string          `json:"awsAccountId"`
+	StackID               string          `json:"stackId"`
+	ChangeSetID           string          `json:"changeSetId"`
+	HookTypeName          string          `json:"hookTypeName"`
+	HookTypeVersion       string          `json:"hookTypeVersion"`
+	HookModel             json.RawMessage `json:"hookModel"`
+	ActionInvocationPoint string          `json:"actionInvocationPoint"`
+	RequestData           requestData     `json:"requestData"`
+	RequestContext        requestContext  `json:"requestContext"`
+}
+
+type requestData struct {
+	TargetName      string      `json:"targetName"`
+	TargetType      string      `json:"targetType"`
+	TargetLogicalID string      `json:"targetLogicalId"`
+	TargetModel     targetModel `json:"targetModel"`
+
+	CallerCredentials   *handlerutil.Credentials `json:"callerCredentials"`
+	ProviderCredentials *handlerutil.Credentials `json:"providerCredentials"`
+
+	ProviderLogGroupName  string `json:"providerLogGroupName"`
+	HookEncryptionKeyArn  string `json:"hookEncryptionKeyArn"`
+	HookEncryptionKeyRole string `json:"hookEncryptionKeyRole"`
+}
+
+// targetModel holds resource properties for resource targets, and the
+// template for stack and change set targets
+type targetModel struct {
+	ResourceProperties         json.RawMessage `json:"resourceProperties"`
+	PreviousResourceProperties json.RawMessage `json:"previousResourceProperties"`
+	Template                   json.RawMessage `json:"template"`
+	PreviousTemplate           json.RawMessage `json:"previousTemplate"`
+}
+
+type requestContext struct {
+	Invocation      int             `json:"invocation"`
+	CallbackContext json.RawMessage `json:"callbackContext,omitempty"`
+}
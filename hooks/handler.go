@@ -0,0 +1,22 @@
+/*
+Package hooks lets you create lambdas that can be used as CloudFormation Hooks.
+
+A hook receives the target of a stack operation before it is provisioned, and
+reports whether the target is compliant with the hook's rules.
+*/
+package hooks
+
+import (
+	"context"
+)
+
+// Handler is implemented by a hook. TargetModel is the shape of the resource
+// properties of the targets the hook is registered for, and Config is the
+// hook's type configuration.
+type Handler[TargetModel any, Config any] interface {
+	PreCreate(context.Context, *Request[TargetModel, Config]) (*ProgressEvent, error)
+	PreUpdate(context.Context, *Request[TargetModel, Config]) (*ProgressEvent, error)
+	PreDelete(context.Context, *Request[TargetModel, Config]) (*ProgressEvent, error)
+}
+
+type handlerFunc[TargetModel any, Config any] func(context.Context, *Request[TargetModel, Config]) (*ProgressEvent, error)
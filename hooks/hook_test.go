@@ -0,0 +1,138 @@
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	cfnTypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/stretchr/testify/require"
+	"github.com/webdestroya/cfnresource/cfnerr"
+)
+
+type bucketModel struct {
+	BucketName string `json:",omitempty"`
+	Versioning bool   `json:",omitempty"`
+}
+
+type hookConfig struct {
+	RequireVersioning bool `json:",omitempty"`
+}
+
+type bucketHook struct{}
+
+var _ Handler[bucketModel, hookConfig] = (*bucketHook)(nil)
+
+func (bucketHook) PreCreate(ctx context.Context, req *Request[bucketModel, hookConfig]) (*ProgressEvent, error) {
+	if req.TargetType == TargetTypeStack {
+		return req.Compliant("stack ok"), nil
+	}
+
+	if req.Config.RequireVersioning && !req.TargetModel.Versioning {
+		return req.NonCompliant("bucket " + req.TargetModel.BucketName + " must enable versioning"), nil
+	}
+	return req.Compliant("ok"), nil
+}
+
+func (bucketHook) PreUpdate(ctx context.Context, req *Request[bucketModel, hookConfig]) (*ProgressEvent, error) {
+	if req.CallbackContext == nil {
+		return req.InProgressResponse(map[string]any{"checked": true}), nil
+	}
+	return nil, cfnerr.NewMessage(cfnerr.AccessDenied, "nope")
+}
+
+func (bucketHook) PreDelete(ctx context.Context, req *Request[bucketModel, hookConfig]) (*ProgressEvent, error) {
+	var m map[string]string
+	m["boom"] = "panic"
+	return nil, nil
+}
+
+func hookEvent(t *testing.T, point string, properties string) *event {
+	t.Helper()
+
+	payload := `{
+		"clientRequestToken": "token-123",
+		"awsAccountId": "123456789012",
+		"stackId": "arn:aws:cloudformation:us-east-1:123456789012:stack/SampleStack/e722ae60-fe62-11e8-9a0e-0ae8cc519968",
+		"hookTypeName": "Org::Compliance::Buckets",
+		"hookTypeVersion": "00000001",
+		"hookModel": {"RequireVersioning": "true"},
+		"actionInvocationPoint": "` + point + `",
+		"requestData": {
+			"targetName": "AWS::S3::Bucket",
+			"targetType": "RESOURCE",
+			"targetLogicalId": "MyBucket",
+			"targetModel": {"resourceProperties": ` + properties + `},
+			"callerCredentials": {"accessKeyId": "fake", "secretAccessKey": "fake", "sessionToken": "fake"}
+		},
+		"requestContext": {"invocation": 1}
+	}`
+
+	ev := new(event)
+	require.NoError(t, json.Unmarshal([]byte(payload), ev))
+	return ev
+}
+
+func TestHookInvocation(t *testing.T) {
+	fn := makeEventFunc[bucketModel, hookConfig](bucketHook{})
+
+	t.Run("compliant", func(t *testing.T) {
+		resp, err := fn(context.Background(), hookEvent(t, "CREATE_PRE_PROVISION", `{"BucketName": "b", "Versioning": "true"}`))
+		require.NoError(t, err)
+		require.Equal(t, cfnTypes.OperationStatusSuccess, resp.HookStatus)
+		require.Equal(t, "token-123", resp.ClientRequestToken)
+	})
+
+	t.Run("non compliant", func(t *testing.T) {
+		resp, err := fn(context.Background(), hookEvent(t, "CREATE_PRE_PROVISION", `{"BucketName": "b"}`))
+		require.NoError(t, err)
+		require.Equal(t, cfnTypes.OperationStatusFailed, resp.HookStatus)
+		require.Equal(t, "NonCompliant", resp.ErrorCode)
+		require.Equal(t, "bucket b must enable versioning", resp.Message)
+	})
+
+	t.Run("stack target", func(t *testing.T) {
+		ev := hookEvent(t, "CREATE_PRE_PROVISION", `null`)
+		ev.RequestData.TargetType = "STACK"
+		ev.RequestData.TargetModel.Template = json.RawMessage(`{"Resources": {}}`)
+
+		req, err := newRequest[bucketModel, hookConfig](ev)
+		require.NoError(t, err)
+		require.Equal(t, TargetTypeStack, req.TargetType)
+		require.JSONEq(t, `{"Resources": {}}`, string(req.Template))
+
+		resp, err := fn(context.Background(), ev)
+		require.NoError(t, err)
+		require.Equal(t, "stack ok", resp.Message)
+	})
+
+	t.Run("in progress", func(t *testing.T) {
+		ev := hookEvent(t, "UPDATE_PRE_PROVISION", `{"BucketName": "b"}`)
+		resp, err := fn(context.Background(), ev)
+		require.NoError(t, err)
+		require.Equal(t, cfnTypes.OperationStatusInProgress, resp.HookStatus)
+		require.Equal(t, 30, resp.CallbackDelaySeconds)
+
+		ev.RequestContext.CallbackContext, err = json.Marshal(resp.CallbackContext)
+		require.NoError(t, err)
+
+		resp, err = fn(context.Background(), ev)
+		require.NoError(t, err)
+		require.Equal(t, cfnTypes.OperationStatusFailed, resp.HookStatus)
+		require.Equal(t, "AccessDenied", resp.ErrorCode)
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		resp, err := fn(context.Background(), hookEvent(t, "DELETE_PRE_PROVISION", `{}`))
+		require.NoError(t, err)
+		require.Equal(t, cfnTypes.OperationStatusFailed, resp.HookStatus)
+		require.Equal(t, "HandlerInternalFailure", resp.ErrorCode)
+	})
+
+	t.Run("unknown invocation point", func(t *testing.T) {
+		resp, err := fn(context.Background(), hookEvent(t, "SOMETHING_ELSE", `{}`))
+		require.NoError(t, err)
+		require.Equal(t, cfnTypes.OperationStatusFailed, resp.HookStatus)
+		require.Equal(t, "InvalidRequest", resp.ErrorCode)
+	})
+}
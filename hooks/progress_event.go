@@ -0,0 +1,50 @@
+package hooks
+
+import (
+	"time"
+
+	cfnTypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/webdestroya/cfnresource/cfnerr"
+)
+
+// ProgressEvent is the result of a hook invocation
+type ProgressEvent struct {
+	// Status is SUCCESS when the target is compliant, FAILED when it is not
+	// (or the hook failed), and IN_PROGRESS when the hook needs to be called again.
+	Status cfnTypes.OperationStatus
+
+	// ErrorCode should be provided when Status is FAILED. Use NonCompliant for
+	// targets that break the hook's rules.
+	ErrorCode cfnTypes.HandlerErrorCode
+
+	// Message explains the result, and is shown to the user for non-compliant targets.
+	Message string
+
+	// CallbackContext is returned to the hook on the next invocation when Status is IN_PROGRESS.
+	CallbackContext map[string]any
+
+	// CallbackDelaySeconds is how long to wait before invoking the hook again.
+	CallbackDelaySeconds int
+}
+
+func (pe *ProgressEvent) WithMessage(v string) *ProgressEvent {
+	pe.Message = v
+	return pe
+}
+
+func (pe *ProgressEvent) WithCallbackDelay(v time.Duration) *ProgressEvent {
+	pe.CallbackDelaySeconds = int(v.Seconds())
+	return pe
+}
+
+func (pe *ProgressEvent) WithErrorCode(code cfnTypes.HandlerErrorCode) *ProgressEvent {
+	pe.ErrorCode = code
+	return pe
+}
+
+func (pe *ProgressEvent) WithError(err error) *ProgressEvent {
+	ce := cfnerr.FromAWSError(err)
+	pe.Message = ce.Message()
+	pe.ErrorCode = ce.Code()
+	return pe
+}
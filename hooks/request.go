@@ -0,0 +1,172 @@
+package hooks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	cfnTypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/webdestroya/cfnresource/cfnerr"
+	"github.com/webdestroya/cfnresource/cfnutils"
+	"github.com/webdestroya/cfnresource/encoding"
+)
+
+// TargetType is the kind of target a hook is invoked for
+type TargetType string
+
+const (
+	TargetTypeResource  TargetType = "RESOURCE"
+	TargetTypeStack     TargetType = "STACK"
+	TargetTypeChangeSet TargetType = "CHANGE_SET"
+)
+
+// InvocationPoint is the point in a stack operation at which the hook is invoked
+type InvocationPoint string
+
+const (
+	PreCreate InvocationPoint = "CREATE_PRE_PROVISION"
+	PreUpdate InvocationPoint = "UPDATE_PRE_PROVISION"
+	PreDelete InvocationPoint = "DELETE_PRE_PROVISION"
+)
+
+type Request[TargetModel any, Config any] struct {
+	ClientRequestToken string
+	AWSAccountId       string
+
+	StackId     string
+	StackName   string
+	ChangeSetId string
+
+	HookTypeName    string
+	HookTypeVersion string
+
+	InvocationPoint InvocationPoint
+
+	// TargetType is RESOURCE, STACK or CHANGE_SET
+	TargetType TargetType
+
+	// TargetName is the resource type (such as AWS::S3::Bucket) for resource
+	// targets, or the stack name for stack targets.
+	TargetName      string
+	TargetLogicalID string
+
+	// TargetModel and PreviousTargetModel are the resource properties of a RESOURCE target.
+	TargetModel         *TargetModel
+	PreviousTargetModel *TargetModel
+
+	// Template and PreviousTemplate are the templates of a STACK or CHANGE_SET target.
+	Template         json.RawMessage
+	PreviousTemplate json.RawMessage
+
+	// Config is the hook's type configuration
+	Config *Config
+
+	// CallbackContext is the context returned by a previous IN_PROGRESS event
+	CallbackContext map[string]any
+
+	// Invocation is the number of times the hook has been invoked for this target
+	Invocation int
+
+	event *event
+}
+
+func (r *Request[TargetModel, Config]) UnmarshalJSON(data []byte) error {
+	return errors.New("dont marshal the request object directly")
+}
+
+// Compliant returns an event reporting that the target passed the hook
+func (r *Request[TargetModel, Config]) Compliant(message string) *ProgressEvent {
+	return &ProgressEvent{
+		Status:  cfnTypes.OperationStatusSuccess,
+		Message: message,
+	}
+}
+
+// NonCompliant returns an event reporting that the target failed the hook
+func (r *Request[TargetModel, Config]) NonCompliant(message string) *ProgressEvent {
+	return &ProgressEvent{
+		Status:    cfnTypes.OperationStatusFailed,
+		ErrorCode: cfnerr.NonCompliant,
+		Message:   message,
+	}
+}
+
+// InProgressResponse returns an event asking CloudFormation to invoke the hook again
+func (r *Request[TargetModel, Config]) InProgressResponse(callbackContext map[string]any) *ProgressEvent {
+	return &ProgressEvent{
+		Status:               cfnTypes.OperationStatusInProgress,
+		CallbackContext:      callbackContext,
+		CallbackDelaySeconds: int(DefaultCallbackDelay.Abs().Seconds()),
+	}
+}
+
+// ErrorResponse returns a failed event for an error. The error is classified
+// using cfnerr.FromAWSError.
+func (r *Request[TargetModel, Config]) ErrorResponse(err any) *ProgressEvent {
+	pe := &ProgressEvent{
+		Status:    cfnTypes.OperationStatusFailed,
+		ErrorCode: cfnerr.InternalFailure,
+	}
+
+	switch v := err.(type) {
+	case string:
+		pe = pe.WithMessage(v)
+	case error:
+		pe = pe.WithError(v)
+	}
+
+	return pe
+}
+
+func newRequest[TargetModel any, Config any](event *event) (*Request[TargetModel, Config], error) {
+	req := &Request[TargetModel, Config]{
+		ClientRequestToken: event.ClientRequestToken,
+		AWSAccountId:       event.AWSAccountID,
+		StackId:            event.StackID,
+		StackName:          cfnutils.GetStackNameFromArn(event.StackID),
+		ChangeSetId:        event.ChangeSetID,
+		HookTypeName:       event.HookTypeName,
+		HookTypeVersion:    event.HookTypeVersion,
+		InvocationPoint:    InvocationPoint(event.ActionInvocationPoint),
+		TargetType:         TargetType(event.RequestData.TargetType),
+		TargetName:         event.RequestData.TargetName,
+		TargetLogicalID:    event.RequestData.TargetLogicalID,
+		Template:           event.RequestData.TargetModel.Template,
+		PreviousTemplate:   event.RequestData.TargetModel.PreviousTemplate,
+		Invocation:         event.RequestContext.Invocation,
+		event:              event,
+	}
+
+	if req.TargetType == "" {
+		req.TargetType = TargetTypeResource
+	}
+
+	if len(event.RequestContext.CallbackContext) > 0 {
+		if err := json.Unmarshal(event.RequestContext.CallbackContext, &req.CallbackContext); err != nil {
+			return nil, cfnerr.Wrap(cfnerr.InternalFailure, fmt.Errorf("invalid callback context: %w", err))
+		}
+	}
+
+	if len(event.HookModel) > 0 {
+		req.Config = new(Config)
+		if err := encoding.Unmarshal(event.HookModel, req.Config); err != nil {
+			return nil, cfnerr.Wrap(cfnerr.InvalidTypeConfiguration, err)
+		}
+	}
+
+	if len(event.RequestData.TargetModel.ResourceProperties) > 0 {
+		req.TargetModel = new(TargetModel)
+		if err := encoding.Unmarshal(event.RequestData.TargetModel.ResourceProperties, req.TargetModel); err != nil {
+			return nil, cfnerr.Wrap(cfnerr.InvalidRequest, err)
+		}
+	}
+
+	if len(event.RequestData.TargetModel.PreviousResourceProperties) > 0 {
+		req.PreviousTargetModel = new(TargetModel)
+		if err := encoding.Unmarshal(event.RequestData.TargetModel.PreviousResourceProperties, req.PreviousTargetModel); err != nil {
+			return nil, cfnerr.Wrap(cfnerr.InvalidRequest, err)
+		}
+	}
+
+	return req, nil
+}
@@ -0,0 +1,38 @@
+package hooks
+
+import (
+	cfnTypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/webdestroya/cfnresource/cfnerr"
+)
+
+// response is what a hook returns to CloudFormation
+type response struct {
+	HookStatus           cfnTypes.OperationStatus `json:"hookStatus"`
+	ErrorCode            string                   `json:"errorCode,omitempty"`
+	Message              string                   `json:"message,omitempty"`
+	CallbackContext      map[string]any           `json:"callbackContext,omitempty"`
+	CallbackDelaySeconds int                      `json:"callbackDelaySeconds,omitempty"`
+	ClientRequestToken   string                   `json:"clientRequestToken"`
+}
+
+func newResponse(pe *ProgressEvent, clientRequestToken string) response {
+	return response{
+		HookStatus:           pe.Status,
+		ErrorCode:            string(pe.ErrorCode),
+		Message:              pe.Message,
+		CallbackContext:      pe.CallbackContext,
+		CallbackDelaySeconds: pe.CallbackDelaySeconds,
+		ClientRequestToken:   clientRequestToken,
+	}
+}
+
+// newFailedResponse returns a response pre-filled with the supplied error
+func newFailedResponse(err error, clientRequestToken string) response {
+	ce := cfnerr.FromAWSError(err)
+	return response{
+		HookStatus:         cfnTypes.OperationStatusFailed,
+		ErrorCode:          string(ce.Code()),
+		Message:            ce.Message(),
+		ClientRequestToken: clientRequestToken,
+	}
+}
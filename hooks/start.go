@@ -0,0 +1,102 @@
+package hooks
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/webdestroya/cfnresource/cfncontext"
+	"github.com/webdestroya/cfnresource/cfnerr"
+	"github.com/webdestroya/cfnresource/internal/handlerutil"
+)
+
+// StartHook starts the Lambda runtime for a hook handler
+func StartHook[TargetModel any, Config any](handler Handler[TargetModel, Config]) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Hook panicked: %s", r)
+			panic(r) // Continue the panic
+		}
+	}()
+
+	log.Printf("Hook starting")
+	lambda.Start(makeEventFunc(handler))
+}
+
+func makeEventFunc[TargetModel any, Config any](handler Handler[TargetModel, Config]) func(context.Context, *event) (response, error) {
+	return func(ctx context.Context, event *event) (response, error) {
+		token := event.ClientRequestToken
+
+		if event.RequestData.CallerCredentials != nil {
+			callerCfg, err := config.LoadDefaultConfig(ctx, config.WithCredentialsProvider(event.RequestData.CallerCredentials))
+			if err != nil {
+				return newFailedResponse(err, token), nil
+			}
+			ctx = cfncontext.SetAwsConfig(ctx, callerCfg)
+		}
+
+		if event.RequestData.ProviderCredentials != nil {
+			providerCfg, err := config.LoadDefaultConfig(ctx, config.WithCredentialsProvider(event.RequestData.ProviderCredentials))
+			if err != nil {
+				return newFailedResponse(err, token), nil
+			}
+			ctx = cfncontext.SetProviderAwsConfig(ctx, providerCfg)
+
+			if event.RequestData.ProviderLogGroupName != "" {
+				logStreamName := fmt.Sprintf("%s/%s", event.HookTypeName, token)
+				handlerutil.SetupLogging(ctx, providerCfg, event.RequestData.ProviderLogGroupName, logStreamName)
+			}
+		}
+
+		handlerFn, err := router(InvocationPoint(event.ActionInvocationPoint), handler)
+		if err != nil {
+			return newFailedResponse(err, token), nil
+		}
+
+		req, err := newRequest[TargetModel, Config](event)
+		if err != nil {
+			return newFailedResponse(err, token), nil
+		}
+
+		return newResponse(invoke(handlerFn, ctx, req), token), nil
+	}
+}
+
+func router[TargetModel any, Config any](point InvocationPoint, handler Handler[TargetModel, Config]) (handlerFunc[TargetModel, Config], error) {
+	switch point {
+	case PreCreate:
+		return handler.PreCreate, nil
+	case PreUpdate:
+		return handler.PreUpdate, nil
+	case PreDelete:
+		return handler.PreDelete, nil
+	default:
+		return nil, cfnerr.NewMessage(cfnerr.InvalidRequest, fmt.Sprintf("Unsupported invocation point %q", point))
+	}
+}
+
+func invoke[TargetModel any, Config any](handlerFn handlerFunc[TargetModel, Config], ctx context.Context, request *Request[TargetModel, Config]) (respPE *ProgressEvent) {
+	defer func() {
+		// Catch any panics and return a failed ProgressEvent
+		if r := recover(); r != nil {
+			err := handlerutil.PanicError(r)
+
+			log.Printf("Trapped error in hook: %v", err)
+
+			respPE = request.ErrorResponse(err).WithErrorCode(cfnerr.HandlerInternalFailure)
+		}
+	}()
+
+	pe, err := handlerFn(ctx, request)
+	if err != nil {
+		return request.ErrorResponse(err)
+	}
+
+	if pe == nil {
+		return request.ErrorResponse("hook returned a nil ProgressEvent").WithErrorCode(cfnerr.HandlerInternalFailure)
+	}
+
+	return pe
+}
@@ -0,0 +1,120 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileStore keeps one JSON file per record in a directory. It is intended for
+// local runs and tests within a single process. New records are claimed
+// atomically, but an expired record is replaced without a lock between
+// processes, so a directory should not be shared by several processes.
+type FileStore struct {
+	Dir string
+
+	mu sync.Mutex
+}
+
+var _ Store = (*FileStore)(nil)
+
+// NewFileStore returns a store that keeps records in dir, creating it if needed
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &FileStore{Dir: dir}, nil
+}
+
+func (s *FileStore) Claim(ctx context.Context, rec Record) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tmp, err := s.writeTemp(rec)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp)
+
+	// linking fails if the record exists, so a new record is only claimed once
+	err = os.Link(tmp, s.path(rec.Key))
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, fs.ErrExist) {
+		return nil, err
+	}
+
+	existing, err := s.read(rec.Key)
+	if err != nil {
+		return nil, err
+	}
+	if !existing.Expired(timeNow()) {
+		return existing, nil
+	}
+
+	return nil, os.Rename(tmp, s.path(rec.Key))
+}
+
+func (s *FileStore) Save(ctx context.Context, rec Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tmp, err := s.writeTemp(rec)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path(rec.Key))
+}
+
+// path returns the file for a key. Keys are hashed, as client request tokens
+// are not guaranteed to be valid file names.
+func (s *FileStore) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.Dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (s *FileStore) read(key string) (*Record, error) {
+	data, err := os.ReadFile(s.path(key))
+	if err != nil {
+		return nil, err
+	}
+
+	rec := new(Record)
+	if err := json.Unmarshal(data, rec); err != nil {
+		return nil, fmt.Errorf("invalid idempotency record for %q: %w", key, err)
+	}
+	return rec, nil
+}
+
+// writeTemp writes the record to a temporary file in the store directory, so
+// that it can be moved into place atomically
+func (s *FileStore) writeTemp(rec Record) (string, error) {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.CreateTemp(s.Dir, ".record-*")
+	if err != nil {
+		return "", err
+	}
+
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+
+	return f.Name(), nil
+}
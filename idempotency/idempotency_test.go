@@ -0,0 +1,214 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	cfnTypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/stretchr/testify/require"
+	"github.com/webdestroya/cfnresource"
+)
+
+type model struct {
+	Name string `json:",omitempty"`
+	Arn  string `json:",omitempty"`
+}
+
+type callbackCtx struct {
+	Step int `json:",omitempty"`
+}
+
+type requestType = *cfnresource.Request[model, callbackCtx]
+type progEventType = *cfnresource.ProgressEvent[model, callbackCtx]
+
+// creator needs two invocations to create a resource, and counts how often it ran
+type creator struct {
+	calls int
+	err   error
+}
+
+func (c *creator) handle(ctx context.Context, req requestType) (progEventType, error) {
+	c.calls++
+	if c.err != nil {
+		return nil, c.err
+	}
+	if req.CallbackContext == nil {
+		return req.InProgressResponse(req.ResourceProperties, &callbackCtx{Step: 1}).WithExtension("other", "kept"), nil
+	}
+	req.ResourceProperties.Arn = "arn:thing"
+	return req.SuccessResponse(req.ResourceProperties), nil
+}
+
+func newReq(action string) requestType {
+	return &cfnresource.Request[model, callbackCtx]{
+		Action:             action,
+		ClientRequestToken: "token-1",
+		LogicalResourceID:  "Resource",
+		ResourceProperties: &model{Name: "thing"},
+	}
+}
+
+func withClock(t *testing.T, now *time.Time) {
+	timeNow = func() time.Time { return *now }
+	t.Cleanup(func() { timeNow = time.Now })
+}
+
+func TestMiddleware(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	withClock(t, &now)
+
+	store := NewMemoryStore()
+	c := &creator{}
+	fn := Middleware[model, callbackCtx](store, nil)(c.handle)
+	ctx := context.Background()
+
+	pe, err := fn(ctx, newReq("CREATE"))
+	require.NoError(t, err)
+	require.Equal(t, cfnTypes.OperationStatusInProgress, pe.OperationStatus)
+	require.Equal(t, 1, c.calls)
+
+	rec, ok := store.Get("CREATE/Resource/token-1")
+	require.True(t, ok)
+	require.Equal(t, StatusInFlight, rec.Status)
+
+	// a repeated invocation waits for the operation, rather than replaying
+	// the IN_PROGRESS event and starting a second callback chain
+	dup, err := fn(ctx, newReq("CREATE"))
+	require.NoError(t, err)
+	require.Equal(t, 1, c.calls)
+	require.Equal(t, cfnTypes.OperationStatusInProgress, dup.OperationStatus)
+	require.Nil(t, dup.CallbackContext)
+
+	// the callback runs the handler
+	req, err := newReq("CREATE").CallbackRequest(pe)
+	require.NoError(t, err)
+	pe, err = fn(ctx, req)
+	require.NoError(t, err)
+	require.Equal(t, cfnTypes.OperationStatusSuccess, pe.OperationStatus)
+	require.Equal(t, 2, c.calls)
+
+	rec, _ = store.Get("CREATE/Resource/token-1")
+	require.Equal(t, StatusCompleted, rec.Status)
+
+	// the waiting invocation then gets the result
+	req, err = newReq("CREATE").CallbackRequest(dup)
+	require.NoError(t, err)
+	pe, err = fn(ctx, req)
+	require.NoError(t, err)
+	require.Equal(t, cfnTypes.OperationStatusSuccess, pe.OperationStatus)
+	require.Equal(t, 2, c.calls)
+
+	// once complete, repeated invocations are answered without running the handler
+	pe, err = fn(ctx, newReq("CREATE"))
+	require.NoError(t, err)
+	require.Equal(t, cfnTypes.OperationStatusSuccess, pe.OperationStatus)
+	require.Equal(t, "arn:thing", pe.ResourceModel.Arn)
+	require.Equal(t, 2, c.calls)
+
+	t.Run("other resource", func(t *testing.T) {
+		// every resource in a stack operation has the same token
+		req := newReq("CREATE")
+		req.LogicalResourceID = "Other"
+		pe, err := fn(ctx, req)
+		require.NoError(t, err)
+		require.Equal(t, cfnTypes.OperationStatusInProgress, pe.OperationStatus)
+		require.Equal(t, 3, c.calls)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		now = now.Add(25 * time.Hour)
+		_, err := fn(ctx, newReq("CREATE"))
+		require.NoError(t, err)
+		require.Equal(t, 4, c.calls)
+	})
+
+	t.Run("passthrough", func(t *testing.T) {
+		_, err := fn(ctx, newReq("READ"))
+		require.NoError(t, err)
+
+		req := newReq("CREATE")
+		req.ClientRequestToken = ""
+		_, err = fn(ctx, req)
+		require.NoError(t, err)
+
+		require.Equal(t, 6, c.calls)
+	})
+}
+
+func TestMiddlewareFailure(t *testing.T) {
+	store := NewMemoryStore()
+	c := &creator{err: errors.New("boom")}
+	fn := Middleware[model, callbackCtx](store, nil)(c.handle)
+
+	_, err := fn(context.Background(), newReq("DELETE"))
+	require.EqualError(t, err, "boom")
+
+	pe, err := fn(context.Background(), newReq("DELETE"))
+	require.NoError(t, err)
+	require.Equal(t, cfnTypes.OperationStatusFailed, pe.OperationStatus)
+	require.Equal(t, "boom", pe.Message)
+	require.Equal(t, 1, c.calls)
+}
+
+func TestMiddlewareInFlight(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	withClock(t, &now)
+
+	store := NewMemoryStore()
+	c := &creator{}
+	fn := Middleware[model, callbackCtx](store, &Options{InFlightTimeout: time.Minute})(c.handle)
+
+	// another invocation claimed the operation but has not finished
+	_, err := store.Claim(context.Background(), Record{Key: "UPDATE/Resource/token-1", Status: StatusInFlight, UpdatedAt: now})
+	require.NoError(t, err)
+
+	pe, err := fn(context.Background(), newReq("UPDATE"))
+	require.NoError(t, err)
+	require.Equal(t, cfnTypes.OperationStatusInProgress, pe.OperationStatus)
+	require.Equal(t, 10, pe.CallbackDelaySeconds)
+	require.Equal(t, 0, c.calls)
+
+	// the waiting callback takes over once the other invocation is abandoned
+	now = now.Add(2 * time.Minute)
+	req, err := newReq("UPDATE").CallbackRequest(pe)
+	require.NoError(t, err)
+	_, err = fn(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, 1, c.calls)
+}
+
+func TestFileStore(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	withClock(t, &now)
+	ctx := context.Background()
+
+	store, err := NewFileStore(t.TempDir())
+	require.NoError(t, err)
+
+	rec := Record{Key: "CREATE/a:b/c", Status: StatusInFlight, UpdatedAt: now, ExpiresAt: now.Add(time.Hour)}
+
+	existing, err := store.Claim(ctx, rec)
+	require.NoError(t, err)
+	require.Nil(t, existing)
+
+	existing, err = store.Claim(ctx, rec)
+	require.NoError(t, err)
+	require.NotNil(t, existing)
+	require.Equal(t, StatusInFlight, existing.Status)
+
+	rec.Status = StatusCompleted
+	rec.Result = []byte(`{"event":{}}`)
+	require.NoError(t, store.Save(ctx, rec))
+
+	existing, err = store.Claim(ctx, Record{Key: rec.Key})
+	require.NoError(t, err)
+	require.Equal(t, StatusCompleted, existing.Status)
+	require.JSONEq(t, `{"event":{}}`, string(existing.Result))
+
+	now = now.Add(2 * time.Hour)
+	existing, err = store.Claim(ctx, Record{Key: rec.Key, Status: StatusInFlight})
+	require.NoError(t, err)
+	require.Nil(t, existing)
+}
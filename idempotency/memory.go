@@ -0,0 +1,48 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryStore keeps records in memory. It is intended for tests, as records
+// do not survive between Lambda instances.
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]Record
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string]Record)}
+}
+
+func (s *MemoryStore) Claim(ctx context.Context, rec Record) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.records[rec.Key]; ok && !existing.Expired(timeNow()) {
+		return &existing, nil
+	}
+
+	s.records[rec.Key] = rec
+	return nil, nil
+}
+
+func (s *MemoryStore) Save(ctx context.Context, rec Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records[rec.Key] = rec
+	return nil
+}
+
+// Get returns the record for key, if there is one
+func (s *MemoryStore) Get(key string) (Record, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, ok := s.records[key]
+	return rec, ok
+}
@@ -0,0 +1,241 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	cfnTypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/webdestroya/cfnresource"
+)
+
+const (
+	// extensionKey marks callbacks that continue an operation this invocation's
+	// middleware already claimed
+	extensionKey = "idempotency"
+
+	// waitExtensionKey marks callbacks that are waiting for another invocation
+	// of the same operation to finish
+	waitExtensionKey = "idempotency:wait"
+)
+
+// Options controls how operations are recorded
+type Options struct {
+	// TTL is how long a record is kept after it was last updated
+	TTL time.Duration
+
+	// InFlightTimeout is how long an operation may go without saving a result
+	// before it is considered abandoned, such as after the Lambda timed out,
+	// and can be claimed by a repeated invocation.
+	InFlightTimeout time.Duration
+
+	// WaitDelay is the callback delay for a repeated invocation while the
+	// first one is still running
+	WaitDelay time.Duration
+
+	// Key returns the store key for a request. By default it is the action, the
+	// logical resource ID and the client request token, as every resource in a
+	// stack operation is sent the same token.
+	Key func(action string, clientRequestToken string, logicalResourceID string) string
+}
+
+// DefaultOptions are used when Middleware is called with nil options
+var DefaultOptions = Options{
+	TTL:             24 * time.Hour,
+	InFlightTimeout: 15 * time.Minute,
+	WaitDelay:       10 * time.Second,
+}
+
+// storedEvent is the Result of a Record
+type storedEvent[Model any, Ctx any] struct {
+	Event      *cfnresource.ProgressEvent[Model, Ctx] `json:"event"`
+	Extensions map[string]json.RawMessage             `json:"extensions,omitempty"`
+}
+
+// Middleware records CREATE, UPDATE and DELETE operations in store, keyed on
+// the request's ClientRequestToken, and answers repeated invocations of a
+// finished operation with its saved result:
+//
+//	func (h *Handler) Middleware() []cfnresource.Middleware[Model, CallbackCtx] {
+//		return []cfnresource.Middleware[Model, CallbackCtx]{
+//			idempotency.Middleware[Model, CallbackCtx](h.store, nil),
+//		}
+//	}
+//
+// Callbacks that continue an operation run the handler as normal. A repeated
+// invocation of an operation that is still in progress waits for it to
+// finish, rather than starting a second callback chain. READ and LIST, and
+// requests without a token, are passed straight through.
+func Middleware[Model any, Ctx any](store Store, opts *Options) cfnresource.Middleware[Model, Ctx] {
+	if opts == nil {
+		opts = &DefaultOptions
+	}
+
+	return func(next cfnresource.HandlerFunc[Model, Ctx]) cfnresource.HandlerFunc[Model, Ctx] {
+		return func(ctx context.Context, req *cfnresource.Request[Model, Ctx]) (*cfnresource.ProgressEvent[Model, Ctx], error) {
+			if !mutating(req.Action) || req.ClientRequestToken == "" {
+				return next(ctx, req)
+			}
+
+			key := opts.key(req.Action, req.ClientRequestToken, req.LogicalResourceID)
+
+			continuing, err := req.Extension(extensionKey, new(bool))
+			if err != nil {
+				return nil, err
+			}
+
+			if !continuing {
+				pe, err := claim(ctx, store, opts, key, req)
+				if pe != nil || err != nil {
+					return pe, err
+				}
+			}
+
+			pe, err := next(ctx, req)
+
+			result := pe
+			if err != nil {
+				result = req.ErrorResponse(err)
+			}
+			if result == nil {
+				return pe, err
+			}
+			if result.OperationStatus == cfnTypes.OperationStatusInProgress {
+				result.WithExtension(extensionKey, true)
+			}
+
+			if serr := save(ctx, store, opts, key, result); serr != nil {
+				// the operation already happened, so report its result rather than failing it
+				log.Printf("Unable to save idempotency record %q: %v", key, serr)
+			}
+
+			return pe, err
+		}
+	}
+}
+
+// claim records the start of an operation. If the operation was already
+// started, it returns the progress event to answer this invocation with.
+func claim[Model any, Ctx any](ctx context.Context, store Store, opts *Options, key string, req *cfnresource.Request[Model, Ctx]) (*cfnresource.ProgressEvent[Model, Ctx], error) {
+	now := timeNow()
+
+	existing, err := store.Claim(ctx, Record{
+		Key:       key,
+		Status:    StatusInFlight,
+		UpdatedAt: now,
+		ExpiresAt: now.Add(opts.ttl()),
+	})
+	if err != nil || existing == nil {
+		return nil, err
+	}
+
+	// only finished operations are replayed, as replaying an IN_PROGRESS
+	// event would start a second callback chain for the same operation
+	if existing.Status == StatusCompleted && len(existing.Result) > 0 {
+		log.Printf("Replaying saved result for operation %q", key)
+		return decode[Model, Ctx](existing.Result)
+	}
+
+	if now.Sub(existing.UpdatedAt) >= opts.inFlightTimeout() {
+		log.Printf("Taking over abandoned operation %q", key)
+		return nil, store.Save(ctx, Record{
+			Key:       key,
+			Status:    StatusInFlight,
+			UpdatedAt: now,
+			ExpiresAt: now.Add(opts.ttl()),
+		})
+	}
+
+	var waits int
+	if _, err := req.Extension(waitExtensionKey, &waits); err != nil {
+		return nil, err
+	}
+
+	return req.InProgressResponse(req.ResourceProperties, req.CallbackContext).
+		WithCallbackDelay(opts.waitDelay()).
+		WithMessage("waiting for another invocation of this operation to finish").
+		WithExtension(waitExtensionKey, waits+1), nil
+}
+
+func save[Model any, Ctx any](ctx context.Context, store Store, opts *Options, key string, pe *cfnresource.ProgressEvent[Model, Ctx]) error {
+	result, err := encode(pe)
+	if err != nil {
+		return err
+	}
+
+	status := StatusCompleted
+	if pe.OperationStatus == cfnTypes.OperationStatusInProgress {
+		status = StatusInFlight
+	}
+
+	now := timeNow()
+	return store.Save(ctx, Record{
+		Key:       key,
+		Status:    status,
+		Result:    result,
+		UpdatedAt: now,
+		ExpiresAt: now.Add(opts.ttl()),
+	})
+}
+
+func encode[Model any, Ctx any](pe *cfnresource.ProgressEvent[Model, Ctx]) (json.RawMessage, error) {
+	extensions, err := pe.Extensions()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(storedEvent[Model, Ctx]{Event: pe, Extensions: extensions})
+}
+
+func decode[Model any, Ctx any](data json.RawMessage) (*cfnresource.ProgressEvent[Model, Ctx], error) {
+	var stored storedEvent[Model, Ctx]
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("invalid idempotency result: %w", err)
+	}
+	if stored.Event == nil {
+		return nil, fmt.Errorf("invalid idempotency result: missing event")
+	}
+
+	pe := stored.Event
+	for k, v := range stored.Extensions {
+		pe = pe.WithExtension(k, v)
+	}
+	return pe, nil
+}
+
+func mutating(action string) bool {
+	switch action {
+	case "CREATE", "UPDATE", "DELETE":
+		return true
+	}
+	return false
+}
+
+func (o *Options) key(action string, token string, logicalID string) string {
+	if o.Key != nil {
+		return o.Key(action, token, logicalID)
+	}
+	return action + "/" + logicalID + "/" + token
+}
+
+func (o *Options) ttl() time.Duration {
+	if o.TTL > 0 {
+		return o.TTL
+	}
+	return DefaultOptions.TTL
+}
+
+func (o *Options) inFlightTimeout() time.Duration {
+	if o.InFlightTimeout > 0 {
+		return o.InFlightTimeout
+	}
+	return DefaultOptions.InFlightTimeout
+}
+
+func (o *Options) waitDelay() time.Duration {
+	if o.WaitDelay > 0 {
+		return o.WaitDelay
+	}
+	return DefaultOptions.WaitDelay
+}
@@ -0,0 +1,58 @@
+// Package idempotency keeps CloudFormation from creating duplicate resources
+// when a handler is invoked more than once for the same operation.
+//
+// Operations are keyed on the request's ClientRequestToken. The first
+// invocation claims the key in a Store, and the result of every invocation is
+// saved against it. A repeated invocation of an operation that already
+// completed is answered with the saved result instead of running the handler
+// again.
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Status is the state of an operation in a Store
+type Status string
+
+const (
+	// StatusInFlight is an operation that has been claimed but has not finished.
+	// It may have saved an IN_PROGRESS result.
+	StatusInFlight Status = "IN_FLIGHT"
+
+	// StatusCompleted is an operation that finished with SUCCESS or FAILED
+	StatusCompleted Status = "COMPLETED"
+)
+
+// Record is the stored state of one operation
+type Record struct {
+	Key    string          `json:"key"`
+	Status Status          `json:"status"`
+	Result json.RawMessage `json:"result,omitempty"`
+
+	UpdatedAt time.Time `json:"updatedAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Expired reports whether the record should be treated as absent
+func (r *Record) Expired(now time.Time) bool {
+	return !r.ExpiresAt.IsZero() && !now.Before(r.ExpiresAt)
+}
+
+// Store persists operation records. Implementations must make Claim atomic
+// across every process that shares the store. With DynamoDB, for example,
+// Claim is a PutItem with a condition that the key does not exist or has
+// expired, and Save is an unconditional PutItem.
+type Store interface {
+	// Claim saves rec if there is no unexpired record with the same key, and
+	// returns nil. Otherwise it returns the existing record, unchanged.
+	Claim(ctx context.Context, rec Record) (*Record, error)
+
+	// Save replaces the record with the same key
+	Save(ctx context.Context, rec Record) error
+}
+
+// timeNow is replaced in tests
+var timeNow = time.Now
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/webdestroya/cfnresource/schema"
 )
 
 type loadOptionsFunc = func(*config.LoadOptions) error
@@ -25,3 +26,34 @@ type eventLogger interface {
 type defaultCallbackDelayGetter interface {
 	DefaultCallbackDelay() time.Duration
 }
+
+// MiddlewareProvider can be implemented by a handler to wrap every operation
+// with middleware, such as idempotency.Middleware. Middleware runs outside of
+// the retry handling, so it sees the final result of each invocation.
+type MiddlewareProvider[Model any, CallbackCtx any] interface {
+	Middleware() []Middleware[Model, CallbackCtx]
+}
+
+// ResponseObserver can be implemented by a handler to see the final progress
+// event of every invocation, after the runtime has applied its contract,
+// schema and normalization checks, which is what CloudFormation receives.
+// It is used by record.Recorder.
+type ResponseObserver[Model any, CallbackCtx any] interface {
+	ObserveResponse(context.Context, *Request[Model, CallbackCtx], *ProgressEvent[Model, CallbackCtx])
+}
+
+// RuntimeOptioner can be implemented by a handler to change how the runtime
+// handles its invocations, such as to enable strict contract checks in tests
+type RuntimeOptioner interface {
+	RuntimeOptions() RuntimeOptions
+}
+
+// ResourceSchemaProvider can be implemented by a handler to have its models
+// checked against the resource schema at runtime. The desired model of a
+// CREATE or UPDATE is validated before the first invocation, and the model
+// returned by a successful CREATE, UPDATE or READ is validated afterwards.
+// The schema should be loaded once, such as with schema.MustLoad into a
+// package variable, rather than on every call.
+type ResourceSchemaProvider interface {
+	ResourceSchema() *schema.Schema
+}
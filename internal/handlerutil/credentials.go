@@ -0,0 +1,41 @@
+package handlerutil
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+)
+
+// ErrMissingCredentials is returned when credentials are retrieved from a
+// request that did not include any
+var ErrMissingCredentials = errors.New("credentials are missing from the request")
+
+// Credentials are the temporary credentials CloudFormation includes in a
+// handler invocation. They are redacted when marshaled back into JSON.
+type Credentials struct {
+	AccessKeyID     string `json:"accessKeyId"`
+	SecretAccessKey string `json:"secretAccessKey"`
+	SessionToken    string `json:"sessionToken"`
+}
+
+var _ aws.CredentialsProvider = (*Credentials)(nil)
+
+// Valid reports whether the credentials can be used to sign requests. The
+// session token is not required, so that long term credentials can be used
+// when testing locally.
+func (c *Credentials) Valid() bool {
+	return c != nil && c.AccessKeyID != "" && c.SecretAccessKey != ""
+}
+
+func (c *Credentials) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	if c == nil {
+		return aws.Credentials{}, ErrMissingCredentials
+	}
+	return credentials.NewStaticCredentialsProvider(c.AccessKeyID, c.SecretAccessKey, c.SessionToken).Retrieve(ctx)
+}
+
+func (Credentials) MarshalJSON() ([]byte, error) {
+	return []byte(`"REDACTED"`), nil
+}
@@ -0,0 +1,3 @@
+// Package handlerutil contains the pieces of the Lambda runtime that are shared
+// between resource, hook and other CloudFormation extension handlers.
+package handlerutil
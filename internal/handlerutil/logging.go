@@ -0,0 +1,24 @@
+package handlerutil
+
+import (
+	"context"
+	"io"
+	"log"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/webdestroya/cfnresource/cloudwatchwriter"
+)
+
+// SetupLogging sends the standard logger to the provider's CloudWatch log group,
+// using the provider credentials, and returns the writer that was installed.
+func SetupLogging(ctx context.Context, providerCfg aws.Config, logGroupName string, logStreamName string) io.Writer {
+	cwClient := cloudwatchlogs.NewFromConfig(providerCfg)
+	w := cloudwatchwriter.NewSync(ctx, cwClient, logGroupName, logStreamName)
+
+	log.SetOutput(w)
+	log.SetFlags(0)
+	log.SetPrefix("")
+
+	return w
+}
@@ -0,0 +1,14 @@
+package handlerutil
+
+import (
+	"errors"
+	"fmt"
+)
+
+// PanicError converts a value recovered from a panic into an error
+func PanicError(r any) error {
+	if err, ok := r.(error); ok {
+		return err
+	}
+	return errors.New(fmt.Sprint(r))
+}
@@ -0,0 +1,69 @@
+package handlerutil
+
+import (
+	"reflect"
+	"strings"
+)
+
+// MissingFields returns the JSON names of the fields of v that are tagged
+// with validate:"nonzero" but have their zero value. Nested structs are
+// checked too, and their fields are reported as parent.child.
+func MissingFields(v any) []string {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	return missingFields(rv, "")
+}
+
+func missingFields(rv reflect.Value, prefix string) []string {
+	var missing []string
+
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := prefix + jsonFieldName(field)
+		fv := rv.Field(i)
+
+		if hasValidateRule(field, "nonzero") && fv.IsZero() {
+			missing = append(missing, name)
+			continue
+		}
+
+		for fv.Kind() == reflect.Pointer && !fv.IsNil() {
+			fv = fv.Elem()
+		}
+		if fv.Kind() == reflect.Struct {
+			missing = append(missing, missingFields(fv, name+".")...)
+		}
+	}
+
+	return missing
+}
+
+func hasValidateRule(field reflect.StructField, rule string) bool {
+	for _, r := range strings.Split(field.Tag.Get("validate"), ",") {
+		if strings.TrimSpace(r) == rule {
+			return true
+		}
+	}
+	return false
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
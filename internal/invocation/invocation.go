@@ -0,0 +1,132 @@
+// Package invocation builds invocation payloads and drives a handler through
+// its callbacks the way CloudFormation does. It is shared by cfntest and the
+// cli package.
+package invocation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	cfnTypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+)
+
+// Stack holds the details of the stack that an invocation is made for
+type Stack struct {
+	Region            string
+	AccountID         string
+	StackName         string
+	LogicalResourceID string
+	ResourceType      string
+
+	// StackID is made from the region, account and stack name if it is empty
+	StackID string
+}
+
+// Event returns the invocation payload for an action. The logical resource ID
+// and system tags are added to requestData, which holds the rest of the
+// request data.
+func (s Stack) Event(action string, clientRequestToken string, nextToken string, requestData map[string]any) map[string]any {
+	if clientRequestToken == "" {
+		clientRequestToken = RandomToken()
+	}
+
+	requestData["logicalResourceId"] = s.LogicalResourceID
+	requestData["systemTags"] = map[string]string{"aws:cloudformation:stack-name": s.StackName}
+
+	return map[string]any{
+		"awsAccountId":       s.AccountID,
+		"bearerToken":        RandomToken(),
+		"clientRequestToken": clientRequestToken,
+		"region":             s.Region,
+		"action":             action,
+		"resourceType":       s.ResourceType,
+		"stackId":            s.stackID(),
+		"stackName":          s.StackName,
+		"nextToken":          nextToken,
+		"requestData":        requestData,
+	}
+}
+
+func (s Stack) stackID() string {
+	if s.StackID != "" {
+		return s.StackID
+	}
+	return fmt.Sprintf("arn:aws:cloudformation:%s:%s:stack/%s/00000000-0000-0000-0000-000000000000", s.Region, s.AccountID, s.StackName)
+}
+
+// Response is the payload returned by the runtime
+type Response struct {
+	Status               cfnTypes.OperationStatus `json:"status"`
+	ErrorCode            string                   `json:"errorCode"`
+	Message              string                   `json:"message"`
+	ResourceModel        json.RawMessage          `json:"resourceModel"`
+	ResourceModels       []json.RawMessage        `json:"resourceModels"`
+	NextToken            string                   `json:"nextToken"`
+	CallbackContext      json.RawMessage          `json:"callbackContext"`
+	CallbackDelaySeconds int                      `json:"callbackDelaySeconds"`
+}
+
+// Invoker handles a single invocation payload, such as cfnresource.Invoke
+type Invoker func(ctx context.Context, payload []byte) ([]byte, error)
+
+// Observer is called with every response. It returns false to stop before
+// the operation has finished.
+type Observer func(invocation int, out []byte, resp *Response) (bool, error)
+
+// Run invokes the handler with payload, and again with the model and callback
+// context of every IN_PROGRESS response, until the operation finishes or
+// observe stops it. It returns the last response and the number of
+// invocations. An error is returned if the operation has not finished after
+// maxInvocations.
+func Run(ctx context.Context, invoke Invoker, payload map[string]any, maxInvocations int, observe Observer) (*Response, int, error) {
+	for invocation := 1; invocation <= maxInvocations; invocation++ {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, invocation, err
+		}
+
+		out, err := invoke(ctx, data)
+		if err != nil {
+			return nil, invocation, fmt.Errorf("%s invocation %d: %w", payload["action"], invocation, err)
+		}
+
+		resp := new(Response)
+		if err := json.Unmarshal(out, resp); err != nil {
+			return nil, invocation, fmt.Errorf("%s invocation %d: invalid response: %w", payload["action"], invocation, err)
+		}
+
+		if observe != nil {
+			more, err := observe(invocation, out, resp)
+			if err != nil || !more {
+				return resp, invocation, err
+			}
+		}
+
+		if resp.Status != cfnTypes.OperationStatusInProgress {
+			return resp, invocation, nil
+		}
+
+		// CloudFormation sends back the model and callback context of the
+		// last progress event
+		if len(resp.CallbackContext) > 0 {
+			payload["callbackContext"] = resp.CallbackContext
+		} else {
+			delete(payload, "callbackContext")
+		}
+		if len(resp.ResourceModel) > 0 {
+			payload["requestData"].(map[string]any)["resourceProperties"] = resp.ResourceModel
+		}
+	}
+
+	return nil, maxInvocations, fmt.Errorf("%s did not finish after %d invocations", payload["action"], maxInvocations)
+}
+
+// RandomToken returns a random hex string, for use as a token
+func RandomToken() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,108 @@
+/*
+Package macro lets you create lambdas that back CloudFormation macros.
+
+A macro receives a template fragment, and returns a transformed fragment that
+CloudFormation will process in its place.
+*/
+package macro
+
+import (
+	"context"
+	"log"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/webdestroya/cfnresource/cfnerr"
+	"github.com/webdestroya/cfnresource/internal/handlerutil"
+)
+
+const (
+	statusSuccess = "success"
+	statusFailure = "failure"
+)
+
+// Transformer is implemented by a macro
+type Transformer interface {
+	// Transform returns the processed fragment. Returning an error fails the
+	// stack operation with the error's message.
+	Transform(context.Context, *Request) (any, error)
+}
+
+// TransformFunc allows a function to be used as a Transformer
+type TransformFunc func(context.Context, *Request) (any, error)
+
+func (f TransformFunc) Transform(ctx context.Context, req *Request) (any, error) {
+	return f(ctx, req)
+}
+
+// Request is the payload CloudFormation sends to a macro
+type Request struct {
+	AccountID   string `json:"accountId"`
+	Region      string `json:"region"`
+	TransformID string `json:"transformId"`
+	RequestID   string `json:"requestId"`
+
+	// Fragment is the template (for a template level Transform) or the
+	// sibling content of the Fn::Transform (for a snippet transform)
+	Fragment any `json:"fragment"`
+
+	// Params are the parameters given to the Fn::Transform
+	Params map[string]any `json:"params"`
+
+	// TemplateParameterValues are the values of the stack's template parameters
+	TemplateParameterValues map[string]any `json:"templateParameterValues"`
+}
+
+// response is what a macro returns to CloudFormation
+type response struct {
+	RequestID    string `json:"requestId"`
+	Status       string `json:"status"`
+	Fragment     any    `json:"fragment,omitempty"`
+	ErrorMessage string `json:"errorMessage,omitempty"`
+}
+
+// StartMacro starts the Lambda runtime for a macro
+func StartMacro(transformer Transformer) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Macro panicked: %s", r)
+			panic(r) // Continue the panic
+		}
+	}()
+
+	log.Printf("Macro starting")
+	lambda.Start(makeEventFunc(transformer))
+}
+
+func makeEventFunc(transformer Transformer) func(context.Context, *Request) (response, error) {
+	return func(ctx context.Context, req *Request) (response, error) {
+		fragment, err := invoke(transformer, ctx, req)
+		if err != nil {
+			ce := cfnerr.FromAWSError(err)
+			log.Printf("Transform %s failed (%s): %s", req.TransformID, ce.Code(), ce.Message())
+
+			return response{
+				RequestID:    req.RequestID,
+				Status:       statusFailure,
+				ErrorMessage: ce.Message(),
+			}, nil
+		}
+
+		return response{
+			RequestID: req.RequestID,
+			Status:    statusSuccess,
+			Fragment:  fragment,
+		}, nil
+	}
+}
+
+func invoke(transformer Transformer, ctx context.Context, req *Request) (fragment any, err error) {
+	defer func() {
+		// Catch any panics and report a failure
+		if r := recover(); r != nil {
+			err = handlerutil.PanicError(r)
+			log.Printf("Trapped error in macro: %v", err)
+		}
+	}()
+
+	return transformer.Transform(ctx, req)
+}
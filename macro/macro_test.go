@@ -0,0 +1,118 @@
+package macro
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/webdestroya/cfnresource/cfnerr"
+)
+
+const testTemplate = `{
+	"Resources": {
+		"Bucket": {
+			"Type": "AWS::S3::Bucket",
+			"Properties": {"BucketName": {"Org::Upper": "my-bucket"}, "Tags": [{"Key": "a", "Value": {"Org::Upper": "b"}}]}
+		},
+		"Queue": {"Type": "AWS::SQS::Queue"},
+		"Other": "bad"
+	}
+}`
+
+func decodeFragment(t *testing.T, data string) any {
+	t.Helper()
+	var fragment any
+	require.NoError(t, json.Unmarshal([]byte(data), &fragment))
+	return fragment
+}
+
+func upper(ctx context.Context, req *Request) (any, error) {
+	if req.Params["fail"] == "yes" {
+		return nil, cfnerr.NewMessage(cfnerr.InvalidRequest, "asked to fail")
+	}
+	if req.Params["panic"] == "yes" {
+		panic("transform exploded")
+	}
+
+	return ReplaceFunction(req.Fragment, "Org::Upper", func(path Path, args any) (any, error) {
+		s, ok := args.(string)
+		if !ok {
+			return nil, errors.New("Org::Upper requires a string")
+		}
+		return strings.ToUpper(s), nil
+	})
+}
+
+func TestMacro(t *testing.T) {
+	fn := makeEventFunc(TransformFunc(upper))
+
+	req := &Request{
+		RequestID: "req-1",
+		Fragment:  decodeFragment(t, testTemplate),
+		Params:    map[string]any{},
+	}
+
+	resp, err := fn(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, "success", resp.Status)
+	require.Equal(t, "req-1", resp.RequestID)
+
+	bucket := Resources(resp.Fragment)["Bucket"]["Properties"].(map[string]any)
+	require.Equal(t, "MY-BUCKET", bucket["BucketName"])
+	require.Equal(t, "B", bucket["Tags"].([]any)[0].(map[string]any)["Value"])
+
+	t.Run("error", func(t *testing.T) {
+		req.Params["fail"] = "yes"
+		t.Cleanup(func() { delete(req.Params, "fail") })
+
+		resp, err := fn(context.Background(), req)
+		require.NoError(t, err)
+		require.Equal(t, "failure", resp.Status)
+		require.Equal(t, "asked to fail", resp.ErrorMessage)
+		require.Nil(t, resp.Fragment)
+	})
+
+	t.Run("walk error", func(t *testing.T) {
+		req := &Request{RequestID: "req-2", Fragment: decodeFragment(t, `{"Outputs": {"Out": {"Value": {"Org::Upper": 5}}}}`)}
+
+		resp, err := fn(context.Background(), req)
+		require.NoError(t, err)
+		require.Equal(t, "failure", resp.Status)
+		require.Equal(t, "Outputs.Out.Value: Org::Upper requires a string", resp.ErrorMessage)
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		req.Params["panic"] = "yes"
+		t.Cleanup(func() { delete(req.Params, "panic") })
+
+		resp, err := fn(context.Background(), req)
+		require.NoError(t, err)
+		require.Equal(t, "failure", resp.Status)
+		require.Equal(t, "transform exploded", resp.ErrorMessage)
+	})
+}
+
+func TestWalk(t *testing.T) {
+	fragment := decodeFragment(t, testTemplate)
+
+	var paths []string
+	_, err := Walk(fragment, func(path Path, value any) (any, error) {
+		if len(path) == 2 && path[1] == "Bucket" {
+			return value, SkipChildren
+		}
+		paths = append(paths, path.String())
+		return value, nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"", "Resources", "Resources.Other", "Resources.Queue", "Resources.Queue.Type"}, paths)
+
+	require.Len(t, Resources(fragment), 2)
+	require.Len(t, Resources("nope"), 0)
+
+	queues := ResourcesOfType(fragment, "AWS::SQS::Queue")
+	require.Len(t, queues, 1)
+	require.Contains(t, queues, "Queue")
+}
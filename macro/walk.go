@@ -0,0 +1,122 @@
+package macro
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// SkipChildren can be returned by a WalkFunc to keep Walk from descending
+// into the value that was returned.
+var SkipChildren = errors.New("skip children")
+
+// Path is the location of a value within a fragment, such as
+// ["Resources", "MyBucket", "Properties", "Tags", "0"]
+type Path []string
+
+func (p Path) String() string {
+	return strings.Join(p, ".")
+}
+
+// WalkFunc is called for every value in a fragment. The value it returns
+// replaces the visited value, and is then walked in turn.
+type WalkFunc func(path Path, value any) (any, error)
+
+// Walk visits every value in the fragment depth-first, allowing each value to
+// be replaced, and returns the rewritten fragment. Map keys are visited in
+// sorted order so that rewrites are deterministic.
+func Walk(fragment any, fn WalkFunc) (any, error) {
+	return walk(Path{}, fragment, fn)
+}
+
+func walk(path Path, value any, fn WalkFunc) (any, error) {
+	value, err := fn(path, value)
+	if errors.Is(err, SkipChildren) {
+		return value, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			child, err := walk(append(slices.Clip(path), key), v[key], fn)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = child
+		}
+		return out, nil
+
+	case []any:
+		out := make([]any, len(v))
+		for i := range v {
+			child, err := walk(append(slices.Clip(path), fmt.Sprint(i)), v[i], fn)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = child
+		}
+		return out, nil
+	}
+
+	return value, nil
+}
+
+// ReplaceFunction rewrites every use of a custom intrinsic function, which is
+// a map with name as its only key, with the value returned by fn.
+//
+// For example, with name "Org::Upper", {"Org::Upper": "abc"} would call fn with "abc".
+func ReplaceFunction(fragment any, name string, fn func(path Path, args any) (any, error)) (any, error) {
+	return Walk(fragment, func(path Path, value any) (any, error) {
+		m, ok := value.(map[string]any)
+		if !ok || len(m) != 1 {
+			return value, nil
+		}
+
+		args, ok := m[name]
+		if !ok {
+			return value, nil
+		}
+
+		return fn(path, args)
+	})
+}
+
+// Resources returns the Resources section of a template fragment, by logical ID.
+// Entries that are not objects are skipped.
+func Resources(fragment any) map[string]map[string]any {
+	out := make(map[string]map[string]any)
+
+	template, ok := fragment.(map[string]any)
+	if !ok {
+		return out
+	}
+
+	resources, ok := template["Resources"].(map[string]any)
+	if !ok {
+		return out
+	}
+
+	for logicalID, v := range resources {
+		if resource, ok := v.(map[string]any); ok {
+			out[logicalID] = resource
+		}
+	}
+
+	return out
+}
+
+// ResourcesOfType returns the resources in a template fragment with the given Type.
+// The returned maps can be modified in place to rewrite the fragment.
+func ResourcesOfType(fragment any, resourceType string) map[string]map[string]any {
+	resources := Resources(fragment)
+	maps.DeleteFunc(resources, func(_ string, resource map[string]any) bool {
+		return resource["Type"] != resourceType
+	})
+	return resources
+}
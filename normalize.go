@@ -0,0 +1,34 @@
+package cfnresource
+
+import (
+	cfnTypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/webdestroya/cfnresource/encoding"
+)
+
+// normalizeModels applies the cfn struct tags of the model to the results of
+// a successful READ or LIST, so they don't report false drift.
+// See encoding.Normalize for the supported tags.
+func normalizeModels[Model any, Ctx any](action string, req *Request[Model, Ctx], pe *ProgressEvent[Model, Ctx]) error {
+	if pe.OperationStatus != cfnTypes.OperationStatusSuccess {
+		return nil
+	}
+
+	switch action {
+	case readAction:
+		if pe.ResourceModel != nil {
+			return encoding.Normalize(pe.ResourceModel, req.ResourceProperties)
+		}
+
+	case listAction:
+		for _, m := range pe.ResourceModels {
+			if m == nil {
+				continue
+			}
+			if err := encoding.Normalize(m, nil); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -1,6 +1,7 @@
 package cfnresource
 
 import (
+	"encoding/json"
 	"time"
 
 	cfnTypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
@@ -43,6 +44,9 @@ type ProgressEvent[Model any, CallbackCtx any] struct {
 
 	// NextToken is the token used to request additional pages of resources for a LIST operation
 	NextToken string `json:"nextToken,omitempty"`
+
+	// extensions is runtime state that is stored alongside the CallbackContext
+	extensions map[string]any
 }
 
 func (pe *ProgressEvent[Model, CallbackCtx]) WithMessage(v string) *ProgressEvent[Model, CallbackCtx] {
@@ -56,6 +60,10 @@ func (pe *ProgressEvent[Model, CallbackCtx]) WithCallbackDelay(v time.Duration)
 }
 
 func (pe *ProgressEvent[Model, CallbackCtx]) WithModels(models ...*Model) *ProgressEvent[Model, CallbackCtx] {
+	if models == nil {
+		// LIST must always return a list, even when there are no results
+		models = []*Model{}
+	}
 	pe.ResourceModels = models
 	return pe
 }
@@ -80,6 +88,16 @@ func (pe *ProgressEvent[Model, CallbackCtx]) WithError(err error) *ProgressEvent
 	return pe
 }
 
+// MarshalResponse returns the payload that is sent to CloudFormation for the
+// event, without a bearer token
+func (pe *ProgressEvent[Model, CallbackCtx]) MarshalResponse() ([]byte, error) {
+	resp, err := pe.toResponse("")
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(resp)
+}
+
 func (pe *ProgressEvent[Model, CallbackCtx]) toResponse(bearerToken string) (response, error) {
 	return newResponse(pe, bearerToken)
 }
@@ -0,0 +1,81 @@
+package cfnresource
+
+import (
+	"fmt"
+	"log"
+
+	cfnTypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/webdestroya/cfnresource/cfnerr"
+)
+
+// enforceContract checks the progress event returned by the handler and
+// replaces it with a failure event if it violates the resource provider
+// contract. With strict set, a violation panics instead.
+func enforceContract[Model any, Ctx any](action string, req *Request[Model, Ctx], pe *ProgressEvent[Model, Ctx], strict bool) *ProgressEvent[Model, Ctx] {
+	err := checkContract(action, pe)
+	if err == nil {
+		return pe
+	}
+
+	if strict {
+		panic(err)
+	}
+
+	log.Printf("Handler broke contract: %v", err)
+
+	return req.ErrorResponse(err)
+}
+
+// checkContract validates a progress event against the rules CloudFormation
+// enforces for the given action and status.
+func checkContract[Model any, Ctx any](action string, pe *ProgressEvent[Model, Ctx]) error {
+	if pe == nil {
+		return contractViolation("handler returned a nil ProgressEvent for %s", action)
+	}
+
+	switch pe.OperationStatus {
+	case cfnTypes.OperationStatusSuccess:
+		if pe.HandlerErrorCode != "" {
+			return contractViolation("%s SUCCESS must not include an error code (got %s)", action, pe.HandlerErrorCode)
+		}
+
+		switch action {
+		case createAction, updateAction, readAction:
+			if pe.ResourceModel == nil {
+				return contractViolation("%s SUCCESS must include a ResourceModel", action)
+			}
+		case deleteAction:
+			if pe.ResourceModel != nil {
+				return contractViolation("%s SUCCESS must not include a ResourceModel", action)
+			}
+		case listAction:
+			if pe.ResourceModels == nil {
+				return contractViolation("%s SUCCESS must include ResourceModels (use an empty slice for no results)", action)
+			}
+		}
+
+	case cfnTypes.OperationStatusFailed:
+		if pe.HandlerErrorCode == "" {
+			return contractViolation("%s FAILED must include a HandlerErrorCode", action)
+		}
+
+	case cfnTypes.OperationStatusInProgress:
+		switch action {
+		case readAction, listAction:
+			return contractViolation("%s must not return IN_PROGRESS", action)
+		}
+
+		if pe.CallbackDelaySeconds > 0 && pe.CallbackContext == nil && len(pe.extensions) == 0 {
+			return contractViolation("%s IN_PROGRESS with a callback delay must include a CallbackContext", action)
+		}
+
+	default:
+		return contractViolation("%s returned an unknown OperationStatus %q", action, pe.OperationStatus)
+	}
+
+	return nil
+}
+
+func contractViolation(format string, args ...any) error {
+	return cfnerr.NewMessage(cfnerr.InternalFailure, "handler contract violation: "+fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,101 @@
+package cfnresource
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	cfnTypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/stretchr/testify/require"
+	"github.com/webdestroya/cfnresource/cfnerr"
+)
+
+func TestCheckContract(t *testing.T) {
+	req := &Request[model, callbackCtx]{}
+	step := 1
+
+	tests := []struct {
+		name    string
+		action  string
+		pe      progEventType
+		wantErr string
+	}{
+		{"nil event", deleteAction, nil, "nil ProgressEvent"},
+		{"create success", createAction, req.SuccessResponse(&model{}), ""},
+		{"create success no model", createAction, req.SuccessResponse(nil), "must include a ResourceModel"},
+		{"read success no model", readAction, req.SuccessResponse(nil), "must include a ResourceModel"},
+		{"delete success", deleteAction, req.SuccessResponse(nil), ""},
+		{"delete success with model", deleteAction, req.SuccessResponse(&model{}), "must not include a ResourceModel"},
+		{"list success", listAction, req.SuccessResponse(nil).WithModels(), ""},
+		{"list success no models", listAction, req.SuccessResponse(nil), "must include ResourceModels"},
+		{"success with code", createAction, req.SuccessResponse(&model{}).WithErrorCode(cfnerr.NotFound), "must not include an error code"},
+		{"failed", updateAction, req.ErrorResponse("oops"), ""},
+		{"failed no code", updateAction, req.ErrorResponse("oops").WithErrorCode(""), "must include a HandlerErrorCode"},
+		{"in progress", updateAction, req.InProgressResponse(nil, &callbackCtx{Step: &step}), ""},
+		{"in progress no context", updateAction, req.InProgressResponse(nil, nil), "must include a CallbackContext"},
+		{"in progress no delay", updateAction, req.InProgressResponse(nil, nil).WithCallbackDelay(0), ""},
+		{"read in progress", readAction, req.InProgressResponse(nil, &callbackCtx{}), "must not return IN_PROGRESS"},
+		{"unknown status", createAction, &ProgressEvent[model, callbackCtx]{}, "unknown OperationStatus"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkContract(tt.action, tt.pe)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+
+			require.ErrorContains(t, err, tt.wantErr)
+			cerr, ok := cfnerr.As(err)
+			require.True(t, ok)
+			require.Equal(t, cfnerr.InternalFailure, cerr.Code())
+		})
+	}
+}
+
+func TestContractViolationResponse(t *testing.T) {
+	fn := makeEventFunc(basicHandler{})
+
+	creds := &credProvider{
+		AccessKeyID:     "fake",
+		SecretAccessKey: "fake",
+		SessionToken:    "fake",
+	}
+
+	ev := &event{
+		BearerToken: "xxxbearerxxx",
+		Region:      "us-east-1",
+		Action:      readAction,
+		RequestData: requestData{
+			CallerCredentials:   creds,
+			ProviderCredentials: creds,
+			LogicalResourceID:   "logically",
+			ResourceProperties:  json.RawMessage(`{"Name": "Test Thing"}`),
+		},
+		StackID: "arn:aws:cloudformation:us-east-1:123456789012:stack/SampleStack/e722ae60-fe62-11e8-9a0e-0ae8cc519968",
+	}
+
+	resp, err := fn(context.Background(), ev)
+	require.NoError(t, err)
+	require.Equal(t, cfnTypes.OperationStatusFailed, resp.OperationStatus)
+	require.EqualValues(t, cfnTypes.HandlerErrorCodeInternalFailure, resp.ErrorCode)
+	require.Contains(t, resp.Message, "nil ProgressEvent for READ")
+
+	t.Run("strict", func(t *testing.T) {
+		fn := makeEventFunc[model, callbackCtx](strictHandler{})
+
+		require.Panics(t, func() {
+			_, _ = fn(context.Background(), ev)
+		})
+	})
+}
+
+// strictHandler asks for strict contract checks
+type strictHandler struct {
+	basicHandler
+}
+
+func (strictHandler) RuntimeOptions() RuntimeOptions {
+	return RuntimeOptions{StrictContract: true}
+}
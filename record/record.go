@@ -0,0 +1,198 @@
+// Package record captures real invocations of a handler, so that they can be
+// replayed as regression tests with cfntest.Replay.
+package record
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"sync"
+
+	"github.com/webdestroya/cfnresource"
+	"github.com/webdestroya/cfnresource/schema"
+)
+
+// Redacted replaces the values that are removed from recordings
+const Redacted = "REDACTED"
+
+// callbackEnvelopeKey is where the runtime puts the handler's callback
+// context when it adds its own state to it
+const callbackEnvelopeKey = "__cfnresource"
+
+// Entry is one line of a recording
+type Entry struct {
+	// Event is the invocation payload, with credentials and the bearer token redacted
+	Event json.RawMessage `json:"event"`
+
+	// Response is the payload that was returned to CloudFormation for the event
+	Response json.RawMessage `json:"response"`
+}
+
+// Options controls what is recorded
+type Options struct {
+	// Redact lists the properties, as resource schema pointers such as
+	// /properties/Password, whose values are replaced in recorded models.
+	// Pointers with a "*" token are not supported.
+	Redact []string
+
+	// RedactCallbackContext lists the fields of the handler's callback
+	// context, as pointers in the same form as Redact, such as
+	// /properties/Token, whose values are replaced in recorded events and
+	// responses. A replayed callback then sees Redacted in their place.
+	RedactCallbackContext []string
+
+	// RedactTypeConfiguration lists the fields of the type configuration, as
+	// pointers in the same form as Redact, such as /properties/ApiKey, whose
+	// values are replaced in recorded events. Type configuration often holds
+	// credentials for other services, so every secret in it should be listed.
+	RedactTypeConfiguration []string
+}
+
+// Recorder writes each invocation of a handler to a writer as a line of JSON
+// holding an Entry. It implements cfnresource.ResponseObserver, so that it
+// records the response that CloudFormation receives, and is embedded in the
+// handler:
+//
+//	type Handler struct {
+//		*record.Recorder[Model, CallbackCtx]
+//	}
+//
+//	h := &Handler{
+//		Recorder: record.New[Model, CallbackCtx](f, &record.Options{
+//			Redact: []string{"/properties/Password"},
+//		}),
+//	}
+//
+// A nil Recorder records nothing. Errors writing are logged and do not fail
+// the operation.
+type Recorder[Model any, Ctx any] struct {
+	w    io.Writer
+	opts *Options
+	mu   sync.Mutex
+}
+
+// New returns a Recorder that writes to w
+func New[Model any, Ctx any](w io.Writer, opts *Options) *Recorder[Model, Ctx] {
+	if opts == nil {
+		opts = &Options{}
+	}
+	return &Recorder[Model, Ctx]{w: w, opts: opts}
+}
+
+// ObserveResponse records the invocation that req was made from, along with
+// its final progress event
+func (r *Recorder[Model, Ctx]) ObserveResponse(ctx context.Context, req *cfnresource.Request[Model, Ctx], pe *cfnresource.ProgressEvent[Model, Ctx]) {
+	// requests that were not made from an invocation payload can't be replayed
+	if r == nil || req.Raw() == nil || pe == nil {
+		return
+	}
+
+	line, err := r.opts.entry(req.Raw(), pe)
+	if err != nil {
+		log.Printf("record: unable to record %s: %v", req.Action, err)
+		return
+	}
+
+	r.mu.Lock()
+	_, err = r.w.Write(line)
+	r.mu.Unlock()
+	if err != nil {
+		log.Printf("record: unable to record %s: %v", req.Action, err)
+	}
+}
+
+// responder is a cfnresource.ProgressEvent of any model
+type responder interface {
+	MarshalResponse() ([]byte, error)
+}
+
+// entry returns the JSON line that records an invocation
+func (o *Options) entry(raw json.RawMessage, pe responder) ([]byte, error) {
+	var event map[string]any
+	if err := json.Unmarshal(raw, &event); err != nil {
+		return nil, err
+	}
+
+	if _, ok := event["bearerToken"]; ok {
+		event["bearerToken"] = Redacted
+	}
+	if data, ok := event["requestData"].(map[string]any); ok {
+		o.redactModel(data["resourceProperties"])
+		o.redactModel(data["previousResourceProperties"])
+		redact(data["typeConfiguration"], o.RedactTypeConfiguration)
+	}
+	o.redactCallbackContext(event["callbackContext"])
+
+	respData, err := pe.MarshalResponse()
+	if err != nil {
+		return nil, err
+	}
+
+	var resp map[string]any
+	if err := json.Unmarshal(respData, &resp); err != nil {
+		return nil, err
+	}
+	o.redactModel(resp["resourceModel"])
+	o.redactCallbackContext(resp["callbackContext"])
+	if models, ok := resp["resourceModels"].([]any); ok {
+		for _, m := range models {
+			o.redactModel(m)
+		}
+	}
+
+	eventData, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	respData, err = json.Marshal(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	line, err := json.Marshal(Entry{Event: eventData, Response: respData})
+	if err != nil {
+		return nil, err
+	}
+	return append(line, '\n'), nil
+}
+
+func (o *Options) redactModel(model any) {
+	redact(model, o.Redact)
+}
+
+// redactCallbackContext redacts the handler's callback context, which the
+// runtime wraps in an envelope when it also carries extension state
+func (o *Options) redactCallbackContext(cbCtx any) {
+	if m, ok := cbCtx.(map[string]any); ok {
+		if env, ok := m[callbackEnvelopeKey].(map[string]any); ok {
+			cbCtx = env["context"]
+		}
+	}
+
+	redact(cbCtx, o.RedactCallbackContext)
+}
+
+// redact replaces the values that the pointers refer to in v
+func redact(v any, pointers []string) {
+	for _, pointer := range pointers {
+		if _, ok := schema.GetProperty(v, pointer); ok {
+			schema.SetProperty(v, pointer, Redacted)
+		}
+	}
+}
+
+// Read returns the entries of a recording
+func Read(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+
+	dec := json.NewDecoder(r)
+	for dec.More() {
+		var entry Entry
+		if err := dec.Decode(&entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
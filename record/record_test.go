@@ -0,0 +1,131 @@
+package record_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/webdestroya/cfnresource"
+	"github.com/webdestroya/cfnresource/cfnerr"
+	"github.com/webdestroya/cfnresource/cfntest"
+	"github.com/webdestroya/cfnresource/record"
+)
+
+type model struct {
+	Name     *string `json:",omitempty"`
+	Password *string `json:",omitempty"`
+}
+
+type callbackCtx struct {
+	Token *string `json:",omitempty"`
+}
+
+type requestType = *cfnresource.Request[model, callbackCtx]
+type progEventType = *cfnresource.ProgressEvent[model, callbackCtx]
+
+type recordedHandler struct {
+	*record.Recorder[model, callbackCtx]
+}
+
+func (recordedHandler) Create(ctx context.Context, req requestType) (progEventType, error) {
+	if req.CallbackContext == nil {
+		token := "secret-token"
+		return req.InProgressResponse(req.ResourceProperties, &callbackCtx{Token: &token}).WithExtension("step", 1), nil
+	}
+	return req.SuccessResponse(req.ResourceProperties), nil
+}
+
+func (recordedHandler) Update(ctx context.Context, req requestType) (progEventType, error) {
+	return nil, cfnerr.NewMessage(cfnerr.NotUpdatable, "cannot update")
+}
+
+// Delete breaks the contract by returning a model
+func (recordedHandler) Delete(ctx context.Context, req requestType) (progEventType, error) {
+	return req.SuccessResponse(req.ResourceProperties), nil
+}
+
+func (recordedHandler) Read(ctx context.Context, req requestType) (progEventType, error) {
+	return req.SuccessResponse(req.ResourceProperties), nil
+}
+
+func (recordedHandler) List(ctx context.Context, req requestType) (progEventType, error) {
+	return req.SuccessResponse(nil).WithModels(), nil
+}
+
+func TestRecorder(t *testing.T) {
+	ctx := context.Background()
+	recording := &bytes.Buffer{}
+	d := cfntest.New[model, callbackCtx](recordedHandler{
+		Recorder: record.New[model, callbackCtx](recording, &record.Options{
+			Redact:                []string{"/properties/Password"},
+			RedactCallbackContext: []string{"/properties/Token"},
+		}),
+	})
+
+	name, password := "thing", "secret"
+	_, err := d.Create(ctx, &model{Name: &name, Password: &password})
+	require.NoError(t, err)
+	_, err = d.Update(ctx, &model{Name: &name}, &model{Name: &name})
+	require.NoError(t, err)
+	_, err = d.Delete(ctx, &model{Name: &name})
+	require.NoError(t, err)
+
+	entries, err := record.Read(recording)
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+
+	var event map[string]any
+	require.NoError(t, json.Unmarshal(entries[0].Event, &event))
+	require.Equal(t, "CREATE", event["action"])
+	require.Equal(t, record.Redacted, event["bearerToken"])
+
+	data := event["requestData"].(map[string]any)
+	require.Equal(t, record.Redacted, data["callerCredentials"])
+	require.Equal(t, record.Redacted, data["providerCredentials"])
+	require.Equal(t, map[string]any{"Name": "thing", "Password": record.Redacted}, data["resourceProperties"])
+
+	// callback contexts are redacted inside the runtime's envelope
+	require.NotContains(t, string(entries[0].Response), "secret-token")
+	require.Contains(t, string(entries[0].Response), `"Token":"REDACTED"`)
+	require.NotContains(t, string(entries[1].Event), "secret-token")
+	require.Contains(t, string(entries[1].Event), `"step":1`)
+
+	require.JSONEq(t, `{"status":"SUCCESS","resourceModel":{"Name":"thing","Password":"REDACTED"},"resourceModels":null}`, string(entries[1].Response))
+	require.JSONEq(t, `{"status":"FAILED","errorCode":"NotUpdatable","message":"cannot update","resourceModels":null}`, string(entries[2].Response))
+
+	// the response is recorded after the runtime's contract checks
+	var resp map[string]any
+	require.NoError(t, json.Unmarshal(entries[3].Response, &resp))
+	require.Equal(t, "FAILED", resp["status"])
+	require.Equal(t, "InternalFailure", resp["errorCode"])
+	require.Nil(t, resp["resourceModel"])
+}
+
+func TestRecorderTypeConfiguration(t *testing.T) {
+	recording := &bytes.Buffer{}
+	d := cfntest.New[model, callbackCtx](recordedHandler{
+		Recorder: record.New[model, callbackCtx](recording, &record.Options{
+			RedactTypeConfiguration: []string{"/properties/ApiKey"},
+		}),
+	})
+
+	name := "thing"
+	_, err := d.Run(context.Background(), cfntest.Request[model]{
+		Action:            "READ",
+		Model:             &model{Name: &name},
+		TypeConfiguration: map[string]any{"ApiKey": "secret-key", "Endpoint": "https://example.com"},
+	})
+	require.NoError(t, err)
+
+	entries, err := record.Read(recording)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.NotContains(t, string(entries[0].Event), "secret-key")
+
+	var event map[string]any
+	require.NoError(t, json.Unmarshal(entries[0].Event, &event))
+	data := event["requestData"].(map[string]any)
+	require.Equal(t, map[string]any{"ApiKey": record.Redacted, "Endpoint": "https://example.com"}, data["typeConfiguration"])
+}
@@ -4,8 +4,12 @@ import (
 	"encoding/json"
 	"errors"
 
+	"github.com/webdestroya/cfnresource/cfncontext"
+	"github.com/webdestroya/cfnresource/cfnerr"
 	"github.com/webdestroya/cfnresource/cfnutils"
+	"github.com/webdestroya/cfnresource/diff"
 	"github.com/webdestroya/cfnresource/encoding"
+	"github.com/webdestroya/cfnresource/tags"
 
 	cfnTypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
 )
@@ -16,6 +20,14 @@ type Request[Model any, Ctx any] struct {
 
 	Region string
 
+	// ClientRequestToken uniquely identifies this operation, and stays the same
+	// across callbacks and retries, so it can be used as an idempotency key.
+	// It is empty if the payload did not include one.
+	ClientRequestToken string
+
+	ResourceType        string
+	ResourceTypeVersion string
+
 	StackId   string
 	StackName string
 
@@ -28,12 +40,33 @@ type Request[Model any, Ctx any] struct {
 	StackTags  Tags
 	SystemTags Tags
 
+	// ResourceTags are the tags defined on the resource in the template
+	ResourceTags Tags
+
+	PreviousStackTags    Tags
+	PreviousSystemTags   Tags
+	PreviousResourceTags Tags
+
 	NextToken string
 
 	TypeConfiguration json.RawMessage
 
+	// SnapshotRequested is set on DELETE when the resource's DeletionPolicy is Snapshot
+	SnapshotRequested bool
+
+	// Rollback is set when the operation is part of a stack rollback
+	Rollback bool
+
+	Driftable bool
+	Features  map[string]any
+
+	// UpdatePolicy and CreationPolicy are the resource's template attributes, if any
+	UpdatePolicy   json.RawMessage
+	CreationPolicy json.RawMessage
+
 	bearerToken string
 	event       *event
+	extensions  map[string]json.RawMessage
 }
 
 func (r *Request[Model, Ctx]) UnmarshalJSON(data []byte) error {
@@ -67,7 +100,7 @@ func (r *Request[Model, Ctx]) ErrorResponse(err any) *ProgressEvent[Model, Ctx]
 	case string:
 		pe = pe.WithMessage(v)
 	case error:
-		pe = pe.WithError(v)
+		pe = pe.WithError(cfnerr.FromAWSError(v))
 	}
 
 	return pe
@@ -75,23 +108,42 @@ func (r *Request[Model, Ctx]) ErrorResponse(err any) *ProgressEvent[Model, Ctx]
 
 func newRequest[Model any, CallbackCtx any](event *event) (*Request[Model, CallbackCtx], error) {
 	req := &Request[Model, CallbackCtx]{
-		StackId:           event.StackID,
-		StackName:         cfnutils.GetStackNameFromArn(event.StackID),
-		Action:            event.Action,
-		AWSAccountId:      event.AWSAccountID,
-		bearerToken:       event.BearerToken,
-		Region:            event.Region,
-		LogicalResourceID: event.RequestData.LogicalResourceID,
-		StackTags:         event.RequestData.StackTags,
-		SystemTags:        event.RequestData.SystemTags,
-		NextToken:         event.NextToken,
-		TypeConfiguration: event.RequestData.TypeConfiguration,
-		event:             event,
+		StackId:              event.StackID,
+		StackName:            stackName(event),
+		ClientRequestToken:   event.ClientRequestToken,
+		ResourceType:         event.ResourceType,
+		ResourceTypeVersion:  string(event.ResourceTypeVersion),
+		Action:               event.Action,
+		AWSAccountId:         event.AWSAccountID,
+		bearerToken:          event.BearerToken,
+		Region:               event.Region,
+		LogicalResourceID:    event.RequestData.LogicalResourceID,
+		StackTags:            event.RequestData.StackTags,
+		SystemTags:           event.RequestData.SystemTags,
+		ResourceTags:         event.RequestData.DesiredResourceTags,
+		NextToken:            event.NextToken,
+		TypeConfiguration:    event.RequestData.TypeConfiguration,
+		PreviousStackTags:    event.RequestData.PreviousStackTags,
+		PreviousSystemTags:   event.RequestData.PreviousSystemTags,
+		PreviousResourceTags: event.RequestData.PreviousResourceTags,
+		SnapshotRequested:    event.SnapshotRequested,
+		Rollback:             event.Rollback,
+		Driftable:            event.Driftable,
+		Features:             event.Features,
+		UpdatePolicy:         event.UpdatePolicy,
+		CreationPolicy:       event.CreationPolicy,
+		event:                event,
+	}
+
+	cbCtx, extensions, err := unwrapCallbackContext(event.CallbackContext)
+	if err != nil {
+		return nil, err
 	}
+	req.extensions = extensions
 
-	if len(event.CallbackContext) > 0 {
+	if len(cbCtx) > 0 {
 		req.CallbackContext = new(CallbackCtx)
-		if err := encoding.Unmarshal(event.CallbackContext, req.CallbackContext); err != nil {
+		if err := encoding.Unmarshal(cbCtx, req.CallbackContext); err != nil {
 			return nil, err
 		}
 	}
@@ -112,3 +164,86 @@ func newRequest[Model any, CallbackCtx any](event *event) (*Request[Model, Callb
 
 	return req, nil
 }
+
+// Raw returns the payload the request was decoded from, with credentials
+// redacted. It gives access to fields that are not yet exposed on Request.
+// It is nil if the request was not created from a handler invocation.
+func (r *Request[Model, Ctx]) Raw() json.RawMessage {
+	if r.event == nil {
+		return nil
+	}
+	return r.event.redactedPayload()
+}
+
+// stackName uses the name sent by CloudFormation, falling back to the stack
+// ARN and then the stack name system tag.
+func stackName(event *event) string {
+	if event.StackName != "" {
+		return event.StackName
+	}
+	if name := cfnutils.GetStackNameFromArn(event.StackID); name != "" {
+		return name
+	}
+	return event.RequestData.SystemTags[stackNameSystemTag]
+}
+
+// Diff returns the properties that changed between PreviousResourceProperties
+// and ResourceProperties. On a CREATE every set property is reported as added.
+func (r *Request[Model, Ctx]) Diff() diff.Changes {
+	return diff.Compare(r.PreviousResourceProperties, r.ResourceProperties)
+}
+
+// DesiredTags returns the tags the resource should have, merging the system,
+// stack and resource tags with resource tags taking precedence. It includes
+// the reserved aws: system tags, which tags.As leaves out of SDK calls.
+func (r *Request[Model, Ctx]) DesiredTags() Tags {
+	return tags.Merge(r.SystemTags, r.StackTags, r.ResourceTags)
+}
+
+// PreviousTags returns the tags the resource had before an UPDATE, merged in
+// the same way as DesiredTags. Pass both to diff.Tags to find what to change.
+func (r *Request[Model, Ctx]) PreviousTags() Tags {
+	return tags.Merge(r.PreviousSystemTags, r.PreviousStackTags, r.PreviousResourceTags)
+}
+
+// NameInput allows the request to be passed to cfnutils.GenerateName
+func (r *Request[Model, Ctx]) NameInput() cfnutils.NameInput {
+	return cfnutils.NameInput{
+		StackName:          r.StackName,
+		StackID:            r.StackId,
+		LogicalResourceID:  r.LogicalResourceID,
+		ClientRequestToken: r.ClientRequestToken,
+	}
+}
+
+// Partition returns the partition of the request's region, such as aws or aws-cn
+func (r *Request[Model, Ctx]) Partition() string {
+	return cfnutils.PartitionForRegion(r.Region)
+}
+
+// ARN returns an ARN for a resource in the request's region and account
+func (r *Request[Model, Ctx]) ARN(service string, resource string) string {
+	return cfnutils.BuildARN(r.Region, r.AWSAccountId, service, resource)
+}
+
+// GlobalARN returns an ARN for a resource of a global service, such as IAM,
+// in the request's partition and account
+func (r *Request[Model, Ctx]) GlobalARN(service string, resource string) string {
+	return cfnutils.BuildGlobalARN(r.Region, r.AWSAccountId, service, resource)
+}
+
+// Info returns the metadata of the request, which is also available from the
+// handler's context with cfncontext.RequestInfo
+func (r *Request[Model, Ctx]) Info() cfncontext.RequestMetadata {
+	return cfncontext.RequestMetadata{
+		Action:              r.Action,
+		AWSAccountID:        r.AWSAccountId,
+		Region:              r.Region,
+		StackID:             r.StackId,
+		StackName:           r.StackName,
+		LogicalResourceID:   r.LogicalResourceID,
+		ResourceType:        r.ResourceType,
+		ResourceTypeVersion: r.ResourceTypeVersion,
+		ClientRequestToken:  r.ClientRequestToken,
+	}
+}
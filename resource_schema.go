@@ -0,0 +1,61 @@
+package cfnresource
+
+import (
+	"fmt"
+	"log"
+
+	cfnTypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/webdestroya/cfnresource/cfnerr"
+)
+
+// validateInputSchema checks the desired model of a new CREATE or UPDATE
+// against the schema of handlers that implement ResourceSchemaProvider
+func validateInputSchema[Model any, Ctx any](handler Handler[Model, Ctx], req *Request[Model, Ctx]) error {
+	h, ok := handler.(ResourceSchemaProvider)
+	if !ok || !isNewWrite(req) {
+		return nil
+	}
+
+	violations, err := h.ResourceSchema().ValidateInput(req.ResourceProperties)
+	if err != nil {
+		return cfnerr.Wrap(cfnerr.InternalFailure, err)
+	}
+	return violations.Err()
+}
+
+// enforceOutputSchema checks the model of a successful progress event against
+// the schema of a handler that implements ResourceSchemaProvider. A model that
+// does not match is logged rather than failed, as the operation has already
+// happened: failing a CREATE would leave behind a resource that CloudFormation
+// never deletes. With strict set the mismatch panics instead, so that it is
+// caught in tests. LIST results are not checked, as they may only include
+// the primary identifier of each resource.
+func enforceOutputSchema[Model any, Ctx any](handler Handler[Model, Ctx], action string, req *Request[Model, Ctx], pe *ProgressEvent[Model, Ctx], strict bool) *ProgressEvent[Model, Ctx] {
+	h, ok := handler.(ResourceSchemaProvider)
+	if !ok || pe == nil || pe.OperationStatus != cfnTypes.OperationStatusSuccess || pe.ResourceModel == nil {
+		return pe
+	}
+
+	switch action {
+	case createAction, updateAction, readAction:
+	default:
+		return pe
+	}
+
+	violations, err := h.ResourceSchema().ValidateOutput(pe.ResourceModel)
+	if err == nil && len(violations) > 0 {
+		err = contractViolation("%s returned a model that does not match the resource schema (%s)", action, violations)
+	}
+	if err != nil && !cfnerr.Is(err) {
+		err = fmt.Errorf("validating %s result: %w", action, err)
+	}
+	if err == nil {
+		return pe
+	}
+
+	if strict {
+		panic(err)
+	}
+	log.Printf("Ignoring resource schema mismatch in handler result: %v", err)
+	return pe
+}
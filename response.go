@@ -98,6 +98,11 @@ func newResponse[Model any, Ctx any](pevt *ProgressEvent[Model, Ctx], bearerToke
 		return response{}, err
 	}
 
+	cbCtx, err = wrapCallbackContext(cbCtx, pevt.extensions)
+	if err != nil {
+		return response{}, err
+	}
+
 	resp := response{
 		BearerToken:          bearerToken,
 		Message:              pevt.Message,
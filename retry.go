@@ -0,0 +1,175 @@
+package cfnresource
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"time"
+
+	cfnTypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/webdestroya/cfnresource/cfnerr"
+)
+
+const retryExtensionKey = "retry"
+
+// RetryPolicy controls how retryable handler failures are converted into
+// IN_PROGRESS events so that CloudFormation will invoke the handler again.
+// A failure is either a returned error or a returned FAILED event. Only
+// CREATE, UPDATE and DELETE are retried, as READ and LIST must not return
+// IN_PROGRESS; their failures are returned as they are.
+type RetryPolicy struct {
+	// MaxAttempts is the number of times a failure will be retried before the
+	// operation is failed. Set this to zero to disable retries.
+	MaxAttempts int
+
+	// InitialDelay is the callback delay for the first retry when the error
+	// does not request a specific delay.
+	InitialDelay time.Duration
+
+	// MaxDelay caps the callback delay between retries.
+	MaxDelay time.Duration
+
+	// Multiplier is applied to the delay for every subsequent attempt.
+	Multiplier float64
+
+	// Codes are error codes that are retried even when the error was not
+	// marked with cfnerr.Retryable.
+	Codes []cfnTypes.HandlerErrorCode
+}
+
+// DefaultRetryPolicy is used for handlers that do not implement RetryPolicyProvider
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:  5,
+	InitialDelay: 5 * time.Second,
+	MaxDelay:     5 * time.Minute,
+	Multiplier:   2,
+	Codes:        []cfnTypes.HandlerErrorCode{cfnerr.Throttling},
+}
+
+// RetryPolicyProvider can be implemented by a handler to override the DefaultRetryPolicy
+type RetryPolicyProvider interface {
+	RetryPolicy() RetryPolicy
+}
+
+// retryState is stored in the callback context between retries
+type retryState struct {
+	Attempt int `json:"attempt"`
+}
+
+// Retryable reports whether the error should be retried under the policy, and
+// the delay the error requested. An error is retried if it was marked with
+// cfnerr.Retryable, or its code is one of the policy's Codes. AWS SDK errors
+// are classified with cfnerr.FromAWSError to find their code.
+func (p RetryPolicy) Retryable(err error) (time.Duration, bool) {
+	if after, ok := cfnerr.RetryAfter(err); ok {
+		return after, true
+	}
+
+	if slices.Contains(p.Codes, cfnerr.FromAWSError(err).Code()) {
+		return 0, true
+	}
+
+	return 0, false
+}
+
+// delay returns the callback delay to use for the given (1-based) attempt
+func (p RetryPolicy) delay(attempt int, requested time.Duration) time.Duration {
+	if requested > 0 {
+		return backoffDelay(requested, p.MaxDelay, 1, 1)
+	}
+	return backoffDelay(p.InitialDelay, p.MaxDelay, p.Multiplier, attempt)
+}
+
+// backoffDelay returns an exponentially increasing callback delay for the
+// given (1-based) attempt, rounded to whole seconds as CloudFormation requires
+func backoffDelay(initial time.Duration, max time.Duration, multiplier float64, attempt int) time.Duration {
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	d := time.Duration(float64(initial) * math.Pow(multiplier, float64(attempt-1)))
+
+	if max > 0 && d > max {
+		d = max
+	}
+
+	if d < time.Second {
+		d = time.Second
+	}
+
+	return d.Round(time.Second)
+}
+
+func getRetryPolicy(handler any) RetryPolicy {
+	if h, ok := handler.(RetryPolicyProvider); ok {
+		return h.RetryPolicy()
+	}
+	return DefaultRetryPolicy
+}
+
+// withRetry wraps a handler function so that retryable failures are turned into
+// IN_PROGRESS events carrying the unchanged callback context and an attempt counter.
+func withRetry[Model any, Ctx any](handlerFn HandlerFunc[Model, Ctx], policy RetryPolicy) HandlerFunc[Model, Ctx] {
+	return func(ctx context.Context, req *Request[Model, Ctx]) (*ProgressEvent[Model, Ctx], error) {
+		pe, err := handlerFn(ctx, req)
+		if policy.MaxAttempts <= 0 || !retriedAction(req.Action) {
+			return pe, err
+		}
+
+		failure := err
+		if failure == nil && pe != nil && pe.OperationStatus == cfnTypes.OperationStatusFailed {
+			failure = cfnerr.NewMessage(pe.HandlerErrorCode, pe.Message)
+		}
+		if failure == nil {
+			return pe, err
+		}
+
+		after, ok := policy.Retryable(failure)
+		if !ok {
+			return pe, err
+		}
+
+		var state retryState
+		if _, xerr := req.Extension(retryExtensionKey, &state); xerr != nil {
+			return nil, xerr
+		}
+		state.Attempt++
+
+		if state.Attempt > policy.MaxAttempts {
+			log.Printf("Giving up after %d retries: %v", policy.MaxAttempts, failure)
+			if err != nil {
+				pe = req.ErrorResponse(err)
+			}
+			return pe.WithMessage(fmt.Sprintf("%s (gave up after %d retries)", pe.Message, policy.MaxAttempts)), nil
+		}
+
+		delay := policy.delay(state.Attempt, after)
+		log.Printf("Retrying in %s (attempt %d of %d): %v", delay, state.Attempt, policy.MaxAttempts, failure)
+
+		return req.InProgressResponse(req.ResourceProperties, req.CallbackContext).
+			WithCallbackDelay(delay).
+			WithMessage(cfnerr.FromAWSError(failure).Message()).
+			WithExtension(retryExtensionKey, state).
+			withRequestExtensions(req), nil
+	}
+}
+
+// retriedAction reports whether failures of the action can be retried with
+// an IN_PROGRESS event
+func retriedAction(action string) bool {
+	switch action {
+	case createAction, updateAction, deleteAction:
+		return true
+	}
+	return false
+}
+
+// RetryAttempt returns how many times the current operation has been retried
+// due to a retryable failure. It is zero on the first attempt.
+func (r *Request[Model, Ctx]) RetryAttempt() int {
+	var state retryState
+	_, _ = r.Extension(retryExtensionKey, &state)
+	return state.Attempt
+}
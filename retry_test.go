@@ -0,0 +1,163 @@
+package cfnresource
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	cfnTypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/require"
+	"github.com/webdestroya/cfnresource/cfnerr"
+)
+
+type retryHandler struct {
+	basicHandler
+	attempts []int
+}
+
+func (h *retryHandler) RetryPolicy() RetryPolicy {
+	policy := DefaultRetryPolicy
+	policy.MaxAttempts = 2
+	return policy
+}
+
+func (h *retryHandler) Update(ctx context.Context, req requestType) (progEventType, error) {
+	h.attempts = append(h.attempts, req.RetryAttempt())
+	return nil, cfnerr.NewMessage(cfnerr.Throttling, "slow down")
+}
+
+func (h *retryHandler) Delete(ctx context.Context, req requestType) (progEventType, error) {
+	return nil, cfnerr.Retryable(errors.New("still propagating"), 42*time.Second)
+}
+
+func (h *retryHandler) Create(ctx context.Context, req requestType) (progEventType, error) {
+	return req.ErrorResponse("rate exceeded").WithErrorCode(cfnerr.Throttling), nil
+}
+
+func (h *retryHandler) Read(ctx context.Context, req requestType) (progEventType, error) {
+	return nil, cfnerr.NewMessage(cfnerr.Throttling, "slow down")
+}
+
+func TestRetryableFailures(t *testing.T) {
+	h := &retryHandler{}
+	fn := makeEventFunc[model, callbackCtx](h)
+
+	ev := newTestEvent(updateAction, `{"Name": "Test Thing"}`)
+	ev.CallbackContext = json.RawMessage(`{"Step": "3"}`)
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		resp, err := fn(context.Background(), ev)
+		require.NoError(t, err)
+		require.Equal(t, cfnTypes.OperationStatusInProgress, resp.OperationStatus)
+		require.Equal(t, "slow down", resp.Message)
+		require.Equal(t, attempt*5, resp.CallbackDelaySeconds)
+		require.NotNil(t, resp.ResourceModel)
+
+		ev.CallbackContext, err = json.Marshal(resp.CallbackContext)
+		require.NoError(t, err)
+	}
+
+	req, err := newRequest[model, callbackCtx](ev)
+	require.NoError(t, err)
+	require.NotNil(t, req.CallbackContext)
+	require.Equal(t, 3, *req.CallbackContext.Step)
+
+	resp, err := fn(context.Background(), ev)
+	require.NoError(t, err)
+	require.Equal(t, cfnTypes.OperationStatusFailed, resp.OperationStatus)
+	require.EqualValues(t, cfnerr.Throttling, resp.ErrorCode)
+	require.Contains(t, resp.Message, "gave up after 2 retries")
+	require.Equal(t, []int{0, 1, 2}, h.attempts)
+
+	t.Run("requested delay", func(t *testing.T) {
+		ev := newTestEvent(deleteAction, `{"Name": "Test Thing"}`)
+		resp, err := fn(context.Background(), ev)
+		require.NoError(t, err)
+		require.Equal(t, cfnTypes.OperationStatusInProgress, resp.OperationStatus)
+		require.Equal(t, 42, resp.CallbackDelaySeconds)
+	})
+}
+
+func TestRetryFailedEvent(t *testing.T) {
+	fn := makeEventFunc[model, callbackCtx](&retryHandler{})
+
+	resp, err := fn(context.Background(), newTestEvent(createAction, `{"Name": "Test Thing"}`))
+	require.NoError(t, err)
+	require.Equal(t, cfnTypes.OperationStatusInProgress, resp.OperationStatus)
+	require.Equal(t, "rate exceeded", resp.Message)
+	require.Equal(t, 5, resp.CallbackDelaySeconds)
+}
+
+func TestRetryReadNotRetried(t *testing.T) {
+	fn := makeEventFunc[model, callbackCtx](&retryHandler{}, func(o *RuntimeOptions) { o.StrictContract = true })
+
+	resp, err := fn(context.Background(), newTestEvent(readAction, `{"Name": "Test Thing"}`))
+	require.NoError(t, err)
+	require.Equal(t, cfnTypes.OperationStatusFailed, resp.OperationStatus)
+	require.EqualValues(t, cfnerr.Throttling, resp.ErrorCode)
+	require.Equal(t, "slow down", resp.Message)
+}
+
+func TestRetryPolicyRetryable(t *testing.T) {
+	tests := map[string]struct {
+		err       error
+		retryable bool
+	}{
+		"sdk throttling":     {err: &smithy.GenericAPIError{Code: "RequestLimitExceeded", Message: "Request limit exceeded."}, retryable: true},
+		"sdk other":          {err: &smithy.GenericAPIError{Code: "InvalidParameterValue", Message: "bad"}},
+		"cfnerr code":        {err: cfnerr.NewMessage(cfnerr.Throttling, "slow down"), retryable: true},
+		"plain error":        {err: errors.New("boom")},
+		"marked retryable":   {err: cfnerr.Retryable(errors.New("not yet"), 0), retryable: true},
+		"wrapped sdk errors": {err: fmt.Errorf("describe: %w", &smithy.GenericAPIError{Code: "Throttling"}), retryable: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, ok := DefaultRetryPolicy.Retryable(tt.err)
+			require.Equal(t, tt.retryable, ok)
+		})
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{
+		InitialDelay: 10 * time.Second,
+		MaxDelay:     time.Minute,
+		Multiplier:   2,
+	}
+
+	require.Equal(t, 10*time.Second, policy.delay(1, 0))
+	require.Equal(t, 20*time.Second, policy.delay(2, 0))
+	require.Equal(t, 40*time.Second, policy.delay(3, 0))
+	require.Equal(t, time.Minute, policy.delay(4, 0))
+	require.Equal(t, 3*time.Second, policy.delay(4, 3*time.Second))
+	require.Equal(t, time.Second, policy.delay(1, time.Millisecond))
+}
+
+// newTestEvent returns a minimal event for the given action
+func newTestEvent(action string, properties string) *event {
+	creds := &credProvider{
+		AccessKeyID:     "fake",
+		SecretAccessKey: "fake",
+		SessionToken:    "fake",
+	}
+
+	return &event{
+		AWSAccountID: "000000000000",
+		BearerToken:  "xxxbearerxxx",
+		Region:       "us-east-1",
+		Action:       action,
+		ResourceType: "Dummy::Thing::Basic",
+		RequestData: requestData{
+			CallerCredentials:   creds,
+			ProviderCredentials: creds,
+			LogicalResourceID:   "logically",
+			ResourceProperties:  json.RawMessage(properties),
+		},
+		StackID: "arn:aws:cloudformation:us-east-1:123456789012:stack/SampleStack/e722ae60-fe62-11e8-9a0e-0ae8cc519968",
+	}
+}
@@ -0,0 +1,39 @@
+package cfnresource
+
+// RuntimeOptions change how the runtime handles the invocations of a
+// handler. A handler sets them by implementing RuntimeOptioner, and Invoke
+// can override them for a single invocation. The zero value is what
+// CloudFormation expects in production.
+type RuntimeOptions struct {
+	// StrictContract makes the runtime panic when a handler returns a
+	// ProgressEvent that breaks the resource provider contract, instead of
+	// converting it into an InternalFailure response. This is intended to be
+	// enabled in tests.
+	StrictContract bool
+
+	// AllowDefaultCredentials lets the handler run when the request is
+	// missing the caller or provider credentials, or they are incomplete, by
+	// falling back to the default AWS credential chain. This is meant for
+	// local development and tests; CloudFormation always sends credentials.
+	// When it is false, such a request fails with InvalidCredentials.
+	AllowDefaultCredentials bool
+
+	// CacheAwsConfigs reuses the provider and caller configs of earlier warm
+	// invocations in the same region, with only their credentials replaced.
+	// A cached config is reused without requesting the options of a handler
+	// that implements AwsConfigOptioner again, so only enable it if those
+	// options are the same for every invocation and do not set credentials.
+	CacheAwsConfigs bool
+}
+
+// runtimeOptions returns the options of the handler, with optFns applied
+func runtimeOptions(handler any, optFns []func(*RuntimeOptions)) RuntimeOptions {
+	var opts RuntimeOptions
+	if h, ok := handler.(RuntimeOptioner); ok {
+		opts = h.RuntimeOptions()
+	}
+	for _, fn := range optFns {
+		fn(&opts)
+	}
+	return opts
+}
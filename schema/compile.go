@@ -0,0 +1,376 @@
+package schema
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// node is a compiled JSON schema
+type node struct {
+	// always is set for the boolean schemas true and false
+	always *bool
+
+	ref *node
+
+	types []string
+
+	properties           map[string]*node
+	patternProperties    []patternNode
+	additionalProperties *node
+	required             []string
+	propertyNames        *node
+	minProperties        *int
+	maxProperties        *int
+	dependencies         map[string]dependency
+
+	items           *node
+	itemsList       []*node
+	additionalItems *node
+	contains        *node
+	minItems        *int
+	maxItems        *int
+	uniqueItems     bool
+
+	minLength *int
+	maxLength *int
+	pattern   *regexp.Regexp
+
+	enum     []any
+	constant *any
+
+	minimum          *float64
+	maximum          *float64
+	exclusiveMinimum *float64
+	exclusiveMaximum *float64
+	multipleOf       *float64
+
+	allOf []*node
+	anyOf []*node
+	oneOf []*node
+	not   *node
+
+	ifNode   *node
+	thenNode *node
+	elseNode *node
+}
+
+type patternNode struct {
+	pattern *regexp.Regexp
+	node    *node
+}
+
+// dependency is either a list of properties or a schema
+type dependency struct {
+	properties []string
+	node       *node
+}
+
+// compiler compiles a schema document, resolving local references
+type compiler struct {
+	root  any
+	nodes map[string]*node
+}
+
+func (c *compiler) compileRef(ptr string) (*node, error) {
+	if n, ok := c.nodes[ptr]; ok {
+		return n, nil
+	}
+
+	v, err := resolvePointer(c.root, ptr)
+	if err != nil {
+		return nil, err
+	}
+	return c.compile(v, ptr)
+}
+
+func (c *compiler) compile(v any, ptr string) (*node, error) {
+	if n, ok := c.nodes[ptr]; ok {
+		return n, nil
+	}
+
+	n := &node{}
+	// stored before it is filled in, so that recursive references resolve
+	c.nodes[ptr] = n
+
+	switch s := v.(type) {
+	case bool:
+		n.always = &s
+		return n, nil
+	case map[string]any:
+		if err := c.fill(n, s, ptr); err != nil {
+			return nil, err
+		}
+		return n, nil
+	default:
+		return nil, fmt.Errorf("%s: schema must be an object or boolean", displayPointer(ptr))
+	}
+}
+
+func (c *compiler) fill(n *node, s map[string]any, ptr string) error {
+	if ref, ok := s["$ref"]; ok {
+		refStr, ok := ref.(string)
+		if !ok {
+			return fmt.Errorf("%s: $ref must be a string", displayPointer(ptr))
+		}
+		target, ok := strings.CutPrefix(refStr, "#")
+		if !ok {
+			return fmt.Errorf("%s: only local references are supported, not %q", displayPointer(ptr), refStr)
+		}
+		target, err := url.PathUnescape(target)
+		if err != nil {
+			return fmt.Errorf("%s: invalid $ref %q: %w", displayPointer(ptr), refStr, err)
+		}
+		// in draft-07 the other keywords next to a $ref are ignored
+		n.ref, err = c.compileRef(target)
+		return err
+	}
+
+	var err error
+	sub := func(key string) *node {
+		v, ok := s[key]
+		if !ok || err != nil {
+			return nil
+		}
+		var child *node
+		child, err = c.compile(v, ptr+"/"+escapeToken(key))
+		return child
+	}
+	subList := func(key string) []*node {
+		v, ok := s[key]
+		if !ok || err != nil {
+			return nil
+		}
+		list, ok := v.([]any)
+		if !ok {
+			err = fmt.Errorf("%s/%s: must be an array", displayPointer(ptr), key)
+			return nil
+		}
+		nodes := make([]*node, 0, len(list))
+		for i, item := range list {
+			var child *node
+			child, err = c.compile(item, ptr+"/"+key+"/"+strconv.Itoa(i))
+			if err != nil {
+				return nil
+			}
+			nodes = append(nodes, child)
+		}
+		return nodes
+	}
+	subMap := func(key string) map[string]*node {
+		v, ok := s[key]
+		if !ok || err != nil {
+			return nil
+		}
+		m, ok := v.(map[string]any)
+		if !ok {
+			err = fmt.Errorf("%s/%s: must be an object", displayPointer(ptr), key)
+			return nil
+		}
+		nodes := make(map[string]*node, len(m))
+		for name, item := range m {
+			var child *node
+			child, err = c.compile(item, ptr+"/"+key+"/"+escapeToken(name))
+			if err != nil {
+				return nil
+			}
+			nodes[name] = child
+		}
+		return nodes
+	}
+	integer := func(key string) *int {
+		v, ok := s[key]
+		if !ok || err != nil {
+			return nil
+		}
+		f, ok := v.(float64)
+		if !ok || f != float64(int(f)) || f < 0 {
+			err = fmt.Errorf("%s/%s: must be a non-negative integer", displayPointer(ptr), key)
+			return nil
+		}
+		i := int(f)
+		return &i
+	}
+	number := func(key string) *float64 {
+		v, ok := s[key]
+		if !ok || err != nil {
+			return nil
+		}
+		f, ok := v.(float64)
+		if !ok {
+			err = fmt.Errorf("%s/%s: must be a number", displayPointer(ptr), key)
+			return nil
+		}
+		return &f
+	}
+	strList := func(key string) []string {
+		v, ok := s[key]
+		if !ok || err != nil {
+			return nil
+		}
+		out, ok := stringList(v)
+		if !ok {
+			err = fmt.Errorf("%s/%s: must be an array of strings", displayPointer(ptr), key)
+		}
+		return out
+	}
+	regex := func(p string, key string) *regexp.Regexp {
+		re, rerr := regexp.Compile(p)
+		if rerr != nil && err == nil {
+			err = fmt.Errorf("%s/%s: invalid pattern %q: %w", displayPointer(ptr), key, p, rerr)
+		}
+		return re
+	}
+
+	switch t := s["type"].(type) {
+	case nil:
+	case string:
+		n.types = []string{t}
+	default:
+		var ok bool
+		if n.types, ok = stringList(t); !ok {
+			return fmt.Errorf("%s/type: must be a string or an array of strings", displayPointer(ptr))
+		}
+	}
+
+	n.properties = subMap("properties")
+	n.additionalProperties = sub("additionalProperties")
+	n.required = strList("required")
+	n.propertyNames = sub("propertyNames")
+	n.minProperties = integer("minProperties")
+	n.maxProperties = integer("maxProperties")
+
+	for name, child := range subMap("patternProperties") {
+		n.patternProperties = append(n.patternProperties, patternNode{pattern: regex(name, "patternProperties"), node: child})
+	}
+	if err != nil {
+		return err
+	}
+	sort.Slice(n.patternProperties, func(i, j int) bool {
+		return n.patternProperties[i].pattern.String() < n.patternProperties[j].pattern.String()
+	})
+
+	if deps, ok := s["dependencies"].(map[string]any); ok && err == nil {
+		n.dependencies = make(map[string]dependency, len(deps))
+		for name, dep := range deps {
+			if props, ok := stringList(dep); ok {
+				n.dependencies[name] = dependency{properties: props}
+				continue
+			}
+			child, cerr := c.compile(dep, ptr+"/dependencies/"+escapeToken(name))
+			if cerr != nil {
+				return cerr
+			}
+			n.dependencies[name] = dependency{node: child}
+		}
+	}
+
+	if _, ok := s["items"].([]any); ok {
+		n.itemsList = subList("items")
+		n.additionalItems = sub("additionalItems")
+	} else {
+		n.items = sub("items")
+	}
+	n.contains = sub("contains")
+	n.minItems = integer("minItems")
+	n.maxItems = integer("maxItems")
+	n.uniqueItems, _ = s["uniqueItems"].(bool)
+
+	n.minLength = integer("minLength")
+	n.maxLength = integer("maxLength")
+	if p, ok := s["pattern"].(string); ok {
+		n.pattern = regex(p, "pattern")
+	}
+
+	if v, ok := s["enum"]; ok {
+		if n.enum, ok = v.([]any); !ok {
+			return fmt.Errorf("%s/enum: must be an array", displayPointer(ptr))
+		}
+	}
+	if v, ok := s["const"]; ok {
+		n.constant = &v
+	}
+
+	n.minimum = number("minimum")
+	n.maximum = number("maximum")
+	n.exclusiveMinimum = number("exclusiveMinimum")
+	n.exclusiveMaximum = number("exclusiveMaximum")
+	n.multipleOf = number("multipleOf")
+
+	n.allOf = subList("allOf")
+	n.anyOf = subList("anyOf")
+	n.oneOf = subList("oneOf")
+	n.not = sub("not")
+
+	n.ifNode = sub("if")
+	n.thenNode = sub("then")
+	n.elseNode = sub("else")
+
+	// definitions are only compiled when they are referenced
+
+	return err
+}
+
+func stringList(v any) ([]string, bool) {
+	list, ok := v.([]any)
+	if !ok {
+		return nil, false
+	}
+	out := make([]string, 0, len(list))
+	for _, item := range list {
+		s, ok := item.(string)
+		if !ok {
+			return nil, false
+		}
+		out = append(out, s)
+	}
+	return out, true
+}
+
+// resolvePointer returns the value in doc that a JSON pointer refers to
+func resolvePointer(doc any, ptr string) (any, error) {
+	if ptr == "" {
+		return doc, nil
+	}
+	if !strings.HasPrefix(ptr, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", ptr)
+	}
+
+	v := doc
+	for _, token := range strings.Split(ptr[1:], "/") {
+		token = unescapeToken(token)
+
+		switch cur := v.(type) {
+		case map[string]any:
+			next, ok := cur[token]
+			if !ok {
+				return nil, fmt.Errorf("%s: reference not found", displayPointer(ptr))
+			}
+			v = next
+		case []any:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(cur) {
+				return nil, fmt.Errorf("%s: reference not found", displayPointer(ptr))
+			}
+			v = cur[i]
+		default:
+			return nil, fmt.Errorf("%s: reference not found", displayPointer(ptr))
+		}
+	}
+	return v, nil
+}
+
+func escapeToken(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
+
+func unescapeToken(s string) string {
+	return strings.NewReplacer("~1", "/", "~0", "~").Replace(s)
+}
+
+func displayPointer(ptr string) string {
+	return "#" + ptr
+}
@@ -0,0 +1,251 @@
+// Package schema validates models against a resource type's schema, using a
+// JSON schema draft-07 validator.
+//
+// Only local references, such as #/definitions/Tag, are supported. The format
+// keyword is treated as an annotation, as draft-07 allows, and patterns use Go
+// regular expression syntax.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"github.com/webdestroya/cfnresource/validation"
+)
+
+// Schema is a compiled resource schema
+type Schema struct {
+	root *node
+
+	// TypeName is the resource type the schema describes, if it is set
+	TypeName string
+
+	// PrimaryIdentifier and the other property lists hold the JSON pointers
+	// from the schema, such as /properties/Arn
+	PrimaryIdentifier    []string
+	CreateOnlyProperties []string
+	ReadOnlyProperties   []string
+	WriteOnlyProperties  []string
+
+	readOnly  []propertyPath
+	writeOnly []propertyPath
+}
+
+// Parse compiles a resource schema document
+func Parse(data []byte) (*Schema, error) {
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+
+	c := &compiler{root: doc, nodes: make(map[string]*node)}
+	root, err := c.compile(doc, "")
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+
+	s := &Schema{root: root}
+
+	if obj, ok := doc.(map[string]any); ok {
+		s.TypeName, _ = obj["typeName"].(string)
+		s.PrimaryIdentifier, _ = stringList(obj["primaryIdentifier"])
+		s.CreateOnlyProperties, _ = stringList(obj["createOnlyProperties"])
+		s.ReadOnlyProperties, _ = stringList(obj["readOnlyProperties"])
+		s.WriteOnlyProperties, _ = stringList(obj["writeOnlyProperties"])
+		s.readOnly = propertyPaths(s.ReadOnlyProperties)
+		s.writeOnly = propertyPaths(s.WriteOnlyProperties)
+	}
+
+	return s, nil
+}
+
+// Load compiles the resource schema stored at name in fsys, which is usually
+// an embed.FS:
+//
+//	//go:embed example-thing.json
+//	var schemaFS embed.FS
+//
+//	var resourceSchema = schema.MustLoad(schemaFS, "example-thing.json")
+func Load(fsys fs.FS, name string) (*Schema, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// MustLoad is like Load, but panics if the schema cannot be loaded
+func MustLoad(fsys fs.FS, name string) *Schema {
+	s, err := Load(fsys, name)
+	if err != nil {
+		panic(fmt.Sprintf("schema: loading %s: %v", name, err))
+	}
+	return s
+}
+
+// Validate checks a value decoded from JSON, such as the result of
+// json.Unmarshal into an any, and returns every violation found
+func (s *Schema) Validate(value any) validation.Violations {
+	v := &validator{}
+	v.validate(s.root, value, "#")
+	return v.violations
+}
+
+// ValidateInput checks a desired model sent by CloudFormation. Required
+// properties that are read-only are not expected to be set.
+func (s *Schema) ValidateInput(model any) (validation.Violations, error) {
+	return s.validateModel(model, s.readOnly)
+}
+
+// ValidateOutput checks a model returned by a handler. Required properties
+// that are write-only are not expected to be returned.
+func (s *Schema) ValidateOutput(model any) (validation.Violations, error) {
+	return s.validateModel(model, s.writeOnly)
+}
+
+func (s *Schema) validateModel(model any, notRequired []propertyPath) (validation.Violations, error) {
+	data, err := json.Marshal(model)
+	if err != nil {
+		return nil, err
+	}
+
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+
+	var violations validation.Violations
+	for _, violation := range s.Validate(value) {
+		if violation.Keyword == "required" && matchesAny(notRequired, violation.Path) {
+			continue
+		}
+		violations = append(violations, violation)
+	}
+	return violations, nil
+}
+
+// propertyPath is a property pointer from a resource schema, such as
+// /properties/Config/properties/Password, as the tokens of the path it
+// matches in a model. A "*" token matches any array index.
+type propertyPath []string
+
+func propertyPaths(pointers []string) []propertyPath {
+	paths := make([]propertyPath, 0, len(pointers))
+	for _, ptr := range pointers {
+		paths = append(paths, parsePropertyPath(ptr))
+	}
+	return paths
+}
+
+func parsePropertyPath(ptr string) propertyPath {
+	var path propertyPath
+	tokens := strings.Split(strings.TrimPrefix(ptr, "/"), "/")
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i] == "properties" && i+1 < len(tokens) {
+			i++
+		}
+		path = append(path, unescapeToken(tokens[i]))
+	}
+	return path
+}
+
+func (p propertyPath) matches(violationPath string) bool {
+	tokens := strings.Split(strings.TrimPrefix(violationPath, "#/"), "/")
+	if len(tokens) != len(p) {
+		return false
+	}
+	for i, token := range tokens {
+		if p[i] != "*" && p[i] != unescapeToken(token) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesAny(paths []propertyPath, violationPath string) bool {
+	for _, p := range paths {
+		if p.matches(violationPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetProperty returns the value that a property pointer from a resource
+// schema, such as /properties/Config/properties/Name, refers to in a model
+// decoded from JSON. A "*" token in the pointer matches the first array item.
+func GetProperty(value any, pointer string) (any, bool) {
+	cur := value
+	for _, token := range parsePropertyPath(pointer) {
+		switch v := cur.(type) {
+		case map[string]any:
+			next, ok := v[token]
+			if !ok {
+				return nil, false
+			}
+			cur = next
+		case []any:
+			if token != "*" || len(v) == 0 {
+				return nil, false
+			}
+			cur = v[0]
+		default:
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// SetProperty sets the value that a property pointer refers to in a model
+// decoded from JSON, creating any missing parent objects. It reports false if
+// the pointer goes through a value that is not an object.
+func SetProperty(value any, pointer string, v any) bool {
+	path := parsePropertyPath(pointer)
+
+	cur, ok := value.(map[string]any)
+	if !ok || len(path) == 0 {
+		return false
+	}
+	for _, token := range path[:len(path)-1] {
+		next, exists := cur[token]
+		if !exists || next == nil {
+			next = map[string]any{}
+			cur[token] = next
+		}
+		if cur, ok = next.(map[string]any); !ok {
+			return false
+		}
+	}
+	cur[path[len(path)-1]] = v
+	return true
+}
+
+// RemoveProperty removes the value that a property pointer refers to from a
+// model decoded from JSON. A "*" token matches every array item.
+func RemoveProperty(value any, pointer string) {
+	removeProperty(value, parsePropertyPath(pointer))
+}
+
+func removeProperty(value any, path propertyPath) {
+	if len(path) == 0 {
+		return
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		if len(path) == 1 {
+			delete(v, path[0])
+			return
+		}
+		removeProperty(v[path[0]], path[1:])
+	case []any:
+		if path[0] != "*" {
+			return
+		}
+		for _, item := range v {
+			removeProperty(item, path[1:])
+		}
+	}
+}
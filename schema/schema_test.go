@@ -0,0 +1,242 @@
+package schema
+
+import (
+	"embed"
+	"encoding/json"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+	"github.com/webdestroya/cfnresource/cfnerr"
+	"github.com/webdestroya/cfnresource/validation"
+)
+
+//go:embed testdata/example-thing.json
+var testdata embed.FS
+
+func TestKeywords(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		value  string
+		exp    []string
+	}{
+		{"type ok", `{"type": "string"}`, `"a"`, nil},
+		{"type", `{"type": "string"}`, `5`, []string{"#: expected type: string, found: number"}},
+		{"integer", `{"type": "integer"}`, `5.0`, nil},
+		{"not integer", `{"type": "integer"}`, `5.5`, []string{"#: expected type: integer, found: number"}},
+		{"type list", `{"type": ["string", "null"]}`, `null`, nil},
+		{"type list mismatch", `{"type": ["string", "null"]}`, `true`, []string{"#: expected type: [string null], found: boolean"}},
+		{"enum", `{"enum": ["a", 1]}`, `1`, nil},
+		{"enum mismatch", `{"enum": ["a", 1]}`, `"b"`, []string{"#: b is not a valid enum value"}},
+		{"const", `{"const": {"a": 1}}`, `{"a": 2}`, []string{`#: {"a":2} does not match the constant {"a":1}`}},
+		{"string lengths", `{"minLength": 2, "maxLength": 3}`, `"abcd"`, []string{"#: expected maxLength: 3, actual: 4"}},
+		{"multibyte length", `{"maxLength": 2}`, `"éé"`, nil},
+		{"pattern", `{"pattern": "^a+$"}`, `"ab"`, []string{"#: string [ab] does not match pattern ^a+$"}},
+		{"pattern ignores other types", `{"pattern": "^a+$"}`, `5`, nil},
+		{"minimum", `{"minimum": 2}`, `1`, []string{"#: 1 is not greater or equal to 2"}},
+		{"maximum", `{"maximum": 2}`, `2.5`, []string{"#: 2.5 is not less or equal to 2"}},
+		{"exclusive", `{"exclusiveMinimum": 1, "exclusiveMaximum": 3}`, `3`, []string{"#: 3 is not less than 3"}},
+		{"exclusive minimum", `{"exclusiveMinimum": 1}`, `1`, []string{"#: 1 is not greater than 1"}},
+		{"multipleOf", `{"multipleOf": 0.1}`, `0.3`, nil},
+		{"not multipleOf", `{"multipleOf": 2}`, `3`, []string{"#: 3 is not a multiple of 2"}},
+		{"items", `{"items": {"type": "string"}, "minItems": 1, "maxItems": 2}`, `["a", 1, "c"]`, []string{
+			"#: expected maximum item count: 2, found: 3",
+			"#/1: expected type: string, found: number",
+		}},
+		{"tuple", `{"items": [{"type": "string"}, {"type": "number"}], "additionalItems": false}`, `["a", 1, 2]`, []string{"#: expected at most 2 items, found: 3"}},
+		{"tuple additional", `{"items": [{"type": "string"}], "additionalItems": {"type": "number"}}`, `["a", 1, "b"]`, []string{"#/2: expected type: number, found: string"}},
+		{"uniqueItems", `{"uniqueItems": true}`, `[{"a": 1}, {"a": 1}]`, []string{"#: array items are not unique"}},
+		{"contains", `{"contains": {"const": 3}}`, `[1, 2]`, []string{"#: expected at least one array item to match 'contains' schema"}},
+		{"required", `{"required": ["a", "b/c"]}`, `{"a": 1}`, []string{"#/b~1c: required property is missing"}},
+		{"properties", `{"properties": {"a": {"type": "string"}}}`, `{"a": 1, "b": 2}`, []string{"#/a: expected type: string, found: number"}},
+		{"additionalProperties false", `{"properties": {"a": {}}, "additionalProperties": false}`, `{"a": 1, "b": 2}`, []string{"#: extraneous key [b] is not permitted"}},
+		{"additionalProperties schema", `{"additionalProperties": {"type": "string"}}`, `{"b": 2}`, []string{"#/b: expected type: string, found: number"}},
+		{"patternProperties", `{"patternProperties": {"^x-": {"type": "string"}}, "additionalProperties": false}`, `{"x-a": "ok", "x-b": 1}`, []string{"#/x-b: expected type: string, found: number"}},
+		{"propertyNames", `{"propertyNames": {"maxLength": 2}}`, `{"abc": 1}`, []string{"#/abc: expected maxLength: 2, actual: 3"}},
+		{"property counts", `{"minProperties": 2, "maxProperties": 3}`, `{"a": 1}`, []string{"#: minimum size: [2], found: [1]"}},
+		{"dependencies", `{"dependencies": {"a": ["b"], "c": {"required": ["d"]}}}`, `{"a": 1, "c": 2}`, []string{
+			"#: property [b] is required by [a]",
+			"#/d: required property is missing",
+		}},
+		{"allOf", `{"allOf": [{"minimum": 2}, {"maximum": 0}]}`, `1`, []string{
+			"#: 1 is not greater or equal to 2",
+			"#: 1 is not less or equal to 0",
+		}},
+		{"anyOf", `{"anyOf": [{"type": "string"}, {"type": "boolean"}]}`, `1`, []string{"#: no subschema matched out of the total 2 subschemas"}},
+		{"anyOf match", `{"anyOf": [{"type": "string"}, {"type": "number"}]}`, `1`, nil},
+		{"oneOf", `{"oneOf": [{"type": "number"}, {"minimum": 0}]}`, `1`, []string{"#: 2 subschemas matched instead of one"}},
+		{"not", `{"not": {"type": "string"}}`, `"a"`, []string{"#: subject must not be valid against schema"}},
+		{"if then", `{"if": {"properties": {"a": {"const": 1}}}, "then": {"required": ["b"]}, "else": {"required": ["c"]}}`, `{"a": 1}`, []string{"#/b: required property is missing"}},
+		{"if else", `{"if": {"properties": {"a": {"const": 1}}}, "then": {"required": ["b"]}, "else": {"required": ["c"]}}`, `{"a": 2}`, []string{"#/c: required property is missing"}},
+		{"false schema", `{"properties": {"a": false}}`, `{"a": 1}`, []string{"#/a: no value is allowed here"}},
+		{"true schema", `true`, `{"a": 1}`, nil},
+		{"ref", `{"definitions": {"s": {"type": "string"}}, "properties": {"a": {"$ref": "#/definitions/s", "type": "number"}}}`, `{"a": "x"}`, nil},
+		{"recursive ref", `{"properties": {"v": {"type": "number"}, "next": {"$ref": "#"}}}`, `{"next": {"next": {"v": "x"}}}`, []string{"#/next/next/v: expected type: number, found: string"}},
+		{"escaped ref", `{"definitions": {"a/b": {"type": "string"}}, "$ref": "#/definitions/a~1b"}`, `1`, []string{"#: expected type: string, found: number"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse([]byte(tt.schema))
+			require.NoError(t, err)
+
+			var value any
+			require.NoError(t, json.Unmarshal([]byte(tt.value), &value))
+
+			var got []string
+			for _, v := range s.Validate(value) {
+				got = append(got, v.String())
+			}
+			require.Equal(t, tt.exp, got)
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		schema string
+		err    string
+	}{
+		{`{`, "invalid schema"},
+		{`5`, "#: schema must be an object or boolean"},
+		{`{"$ref": "other.json#/definitions/a"}`, `only local references are supported`},
+		{`{"$ref": "#/definitions/missing"}`, "#/definitions/missing: reference not found"},
+		{`{"pattern": "("}`, "#/pattern: invalid pattern"},
+		{`{"properties": {"a": {"minLength": -1}}}`, "#/properties/a/minLength: must be a non-negative integer"},
+		{`{"type": 5}`, "#/type: must be a string or an array of strings"},
+		{`{"required": [1]}`, "#/required: must be an array of strings"},
+		{`{"enum": 1}`, "#/enum: must be an array"},
+		{`{"allOf": {}}`, "#/allOf: must be an array"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.schema, func(t *testing.T) {
+			_, err := Parse([]byte(tt.schema))
+			require.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+type tag struct {
+	Key   string `json:",omitempty"`
+	Value string `json:",omitempty"`
+}
+
+type thing struct {
+	Arn      *string `json:",omitempty"`
+	Name     *string `json:",omitempty"`
+	Size     *int    `json:",omitempty"`
+	Password *string `json:",omitempty"`
+	Tags     []tag   `json:",omitempty"`
+	Extra    *string `json:",omitempty"`
+}
+
+func TestResourceSchema(t *testing.T) {
+	s := MustLoad(testdata, "testdata/example-thing.json")
+	require.Equal(t, "Example::Test::Thing", s.TypeName)
+
+	t.Run("input", func(t *testing.T) {
+		// the read only Arn is not required
+		violations, err := s.ValidateInput(&thing{Name: ptr("thing"), Password: ptr("secret"), Tags: []tag{{Key: "a", Value: "b"}}})
+		require.NoError(t, err)
+		require.Empty(t, violations)
+		require.NoError(t, violations.Err())
+
+		violations, err = s.ValidateInput(&thing{Name: ptr("Thing"), Size: ptr(0), Tags: []tag{{Value: "b"}}, Extra: ptr("x")})
+		require.NoError(t, err)
+		require.Equal(t, validation.Violations{
+			{Path: "#/Password", Keyword: "required", Message: "required property is missing"},
+			{Path: "#", Keyword: "additionalProperties", Message: "extraneous key [Extra] is not permitted"},
+			{Path: "#/Name", Keyword: "pattern", Message: "string [Thing] does not match pattern ^[a-z][a-z0-9-]*$"},
+			{Path: "#/Size", Keyword: "minimum", Message: "0 is not greater or equal to 1"},
+			{Path: "#/Tags/0/Key", Keyword: "required", Message: "required property is missing"},
+		}, violations)
+
+		ce, ok := cfnerr.As(violations.Err())
+		require.True(t, ok)
+		require.Equal(t, cfnerr.InvalidRequest, ce.Code())
+	})
+
+	t.Run("output", func(t *testing.T) {
+		// the write only Password is not required
+		violations, err := s.ValidateOutput(&thing{Arn: ptr("arn:thing"), Name: ptr("thing")})
+		require.NoError(t, err)
+		require.Empty(t, violations)
+
+		violations, err = s.ValidateOutput(&thing{Name: ptr("thing")})
+		require.NoError(t, err)
+		require.Equal(t, validation.Violations{
+			{Path: "#/Arn", Keyword: "required", Message: "required property is missing"},
+		}, violations)
+	})
+
+	t.Run("not a model", func(t *testing.T) {
+		_, err := s.ValidateInput(func() {})
+		require.Error(t, err)
+	})
+}
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"schema.json": {Data: []byte(`{"typeName": "A::B::C", "properties": {}}`)},
+		"bad.json":    {Data: []byte(`{"type": 5}`)},
+	}
+
+	s, err := Load(fsys, "schema.json")
+	require.NoError(t, err)
+	require.Equal(t, "A::B::C", s.TypeName)
+
+	_, err = Load(fsys, "missing.json")
+	require.Error(t, err)
+
+	require.Panics(t, func() { MustLoad(fsys, "bad.json") })
+}
+
+func TestPropertyPaths(t *testing.T) {
+	paths := propertyPaths([]string{"/properties/A", "/properties/B/properties/C", "/properties/D/*/E", "/properties/a~1b"})
+	require.Equal(t, []propertyPath{{"A"}, {"B", "C"}, {"D", "*", "E"}, {"a/b"}}, paths)
+
+	require.True(t, paths[1].matches("#/B/C"))
+	require.True(t, paths[2].matches("#/D/3/E"))
+	require.True(t, paths[3].matches("#/a~1b"))
+	require.False(t, paths[0].matches("#/A/B"))
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestProperties(t *testing.T) {
+	s := MustLoad(testdata, "testdata/example-thing.json")
+	require.Equal(t, []string{"/properties/Arn"}, s.PrimaryIdentifier)
+	require.Equal(t, []string{"/properties/Name"}, s.CreateOnlyProperties)
+	require.Equal(t, []string{"/properties/Arn"}, s.ReadOnlyProperties)
+	require.Equal(t, []string{"/properties/Password"}, s.WriteOnlyProperties)
+
+	var value any
+	require.NoError(t, json.Unmarshal([]byte(`{"A": {"B": 1}, "L": [{"K": 1, "V": 2}, {"K": 3}]}`), &value))
+
+	v, ok := GetProperty(value, "/properties/A/properties/B")
+	require.True(t, ok)
+	require.Equal(t, 1.0, v)
+
+	v, ok = GetProperty(value, "/properties/L/*/K")
+	require.True(t, ok)
+	require.Equal(t, 1.0, v)
+
+	_, ok = GetProperty(value, "/properties/A/properties/C")
+	require.False(t, ok)
+
+	require.True(t, SetProperty(value, "/properties/N/properties/M", "x"))
+	require.False(t, SetProperty(value, "/properties/L/properties/X", "x"))
+	v, ok = GetProperty(value, "/properties/N/properties/M")
+	require.True(t, ok)
+	require.Equal(t, "x", v)
+	RemoveProperty(value, "/properties/N")
+
+	RemoveProperty(value, "/properties/A/properties/B")
+	RemoveProperty(value, "/properties/L/*/K")
+	RemoveProperty(value, "/properties/Missing/properties/X")
+	require.Equal(t, map[string]any{"A": map[string]any{}, "L": []any{map[string]any{"V": 2.0}, map[string]any{}}}, value)
+}
@@ -0,0 +1,319 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/webdestroya/cfnresource/validation"
+)
+
+// validator collects the violations of a value
+type validator struct {
+	violations validation.Violations
+}
+
+func (v *validator) add(path string, keyword string, format string, args ...any) {
+	v.violations = append(v.violations, validation.Violation{Path: path, Keyword: keyword, Message: fmt.Sprintf(format, args...)})
+}
+
+// matches reports whether value is valid against n, without recording why not
+func matches(n *node, value any) bool {
+	sub := &validator{}
+	sub.validate(n, value, "#")
+	return len(sub.violations) == 0
+}
+
+func (v *validator) validate(n *node, value any, path string) {
+	if n.ref != nil {
+		v.validate(n.ref, value, path)
+		return
+	}
+
+	if n.always != nil {
+		if !*n.always {
+			v.add(path, "false", "no value is allowed here")
+		}
+		return
+	}
+
+	if len(n.types) > 0 && !matchesType(n.types, value) {
+		expected := n.types[0]
+		if len(n.types) > 1 {
+			expected = fmt.Sprint(n.types)
+		}
+		v.add(path, "type", "expected type: %s, found: %s", expected, typeName(value))
+		return
+	}
+
+	if len(n.enum) > 0 && !containsValue(n.enum, value) {
+		v.add(path, "enum", "%s is not a valid enum value", formatValue(value))
+	}
+	if n.constant != nil && !reflect.DeepEqual(*n.constant, value) {
+		v.add(path, "const", "%s does not match the constant %s", formatValue(value), formatValue(*n.constant))
+	}
+
+	switch val := value.(type) {
+	case map[string]any:
+		v.validateObject(n, val, path)
+	case []any:
+		v.validateArray(n, val, path)
+	case string:
+		v.validateString(n, val, path)
+	case float64:
+		v.validateNumber(n, val, path)
+	}
+
+	for _, sub := range n.allOf {
+		v.validate(sub, value, path)
+	}
+
+	if len(n.anyOf) > 0 {
+		matched := false
+		for _, sub := range n.anyOf {
+			if matches(sub, value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			v.add(path, "anyOf", "no subschema matched out of the total %d subschemas", len(n.anyOf))
+		}
+	}
+
+	if len(n.oneOf) > 0 {
+		count := 0
+		for _, sub := range n.oneOf {
+			if matches(sub, value) {
+				count++
+			}
+		}
+		if count != 1 {
+			v.add(path, "oneOf", "%d subschemas matched instead of one", count)
+		}
+	}
+
+	if n.not != nil && matches(n.not, value) {
+		v.add(path, "not", "subject must not be valid against schema")
+	}
+
+	if n.ifNode != nil {
+		if matches(n.ifNode, value) {
+			if n.thenNode != nil {
+				v.validate(n.thenNode, value, path)
+			}
+		} else if n.elseNode != nil {
+			v.validate(n.elseNode, value, path)
+		}
+	}
+}
+
+func (v *validator) validateObject(n *node, obj map[string]any, path string) {
+	for _, name := range n.required {
+		if _, ok := obj[name]; !ok {
+			v.add(path+"/"+escapeToken(name), "required", "required property is missing")
+		}
+	}
+
+	if n.minProperties != nil && len(obj) < *n.minProperties {
+		v.add(path, "minProperties", "minimum size: [%d], found: [%d]", *n.minProperties, len(obj))
+	}
+	if n.maxProperties != nil && len(obj) > *n.maxProperties {
+		v.add(path, "maxProperties", "maximum size: [%d], found: [%d]", *n.maxProperties, len(obj))
+	}
+
+	// properties are checked in a stable order, so violations are too
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value := obj[name]
+		childPath := path + "/" + escapeToken(name)
+
+		if n.propertyNames != nil {
+			v.validate(n.propertyNames, name, childPath)
+		}
+
+		matched := false
+		if child, ok := n.properties[name]; ok {
+			matched = true
+			v.validate(child, value, childPath)
+		}
+		for _, pp := range n.patternProperties {
+			if pp.pattern.MatchString(name) {
+				matched = true
+				v.validate(pp.node, value, childPath)
+			}
+		}
+
+		if !matched && n.additionalProperties != nil {
+			if isFalse(n.additionalProperties) {
+				v.add(path, "additionalProperties", "extraneous key [%s] is not permitted", name)
+			} else {
+				v.validate(n.additionalProperties, value, childPath)
+			}
+		}
+
+		if dep, ok := n.dependencies[name]; ok {
+			for _, required := range dep.properties {
+				if _, ok := obj[required]; !ok {
+					v.add(path, "dependencies", "property [%s] is required by [%s]", required, name)
+				}
+			}
+			if dep.node != nil {
+				v.validate(dep.node, obj, path)
+			}
+		}
+	}
+}
+
+func (v *validator) validateArray(n *node, arr []any, path string) {
+	if n.minItems != nil && len(arr) < *n.minItems {
+		v.add(path, "minItems", "expected minimum item count: %d, found: %d", *n.minItems, len(arr))
+	}
+	if n.maxItems != nil && len(arr) > *n.maxItems {
+		v.add(path, "maxItems", "expected maximum item count: %d, found: %d", *n.maxItems, len(arr))
+	}
+	if n.uniqueItems && !uniqueItems(arr) {
+		v.add(path, "uniqueItems", "array items are not unique")
+	}
+
+	for i, item := range arr {
+		itemPath := path + "/" + strconv.Itoa(i)
+		switch {
+		case n.items != nil:
+			v.validate(n.items, item, itemPath)
+		case i < len(n.itemsList):
+			v.validate(n.itemsList[i], item, itemPath)
+		case n.additionalItems != nil:
+			if isFalse(n.additionalItems) {
+				v.add(path, "additionalItems", "expected at most %d items, found: %d", len(n.itemsList), len(arr))
+				return
+			}
+			v.validate(n.additionalItems, item, itemPath)
+		}
+	}
+
+	if n.contains != nil {
+		for _, item := range arr {
+			if matches(n.contains, item) {
+				return
+			}
+		}
+		v.add(path, "contains", "expected at least one array item to match 'contains' schema")
+	}
+}
+
+func (v *validator) validateString(n *node, s string, path string) {
+	length := utf8.RuneCountInString(s)
+	if n.minLength != nil && length < *n.minLength {
+		v.add(path, "minLength", "expected minLength: %d, actual: %d", *n.minLength, length)
+	}
+	if n.maxLength != nil && length > *n.maxLength {
+		v.add(path, "maxLength", "expected maxLength: %d, actual: %d", *n.maxLength, length)
+	}
+	if n.pattern != nil && !n.pattern.MatchString(s) {
+		v.add(path, "pattern", "string [%s] does not match pattern %s", s, n.pattern)
+	}
+}
+
+func (v *validator) validateNumber(n *node, f float64, path string) {
+	if n.minimum != nil && f < *n.minimum {
+		v.add(path, "minimum", "%s is not greater or equal to %s", formatNumber(f), formatNumber(*n.minimum))
+	}
+	if n.maximum != nil && f > *n.maximum {
+		v.add(path, "maximum", "%s is not less or equal to %s", formatNumber(f), formatNumber(*n.maximum))
+	}
+	if n.exclusiveMinimum != nil && f <= *n.exclusiveMinimum {
+		v.add(path, "exclusiveMinimum", "%s is not greater than %s", formatNumber(f), formatNumber(*n.exclusiveMinimum))
+	}
+	if n.exclusiveMaximum != nil && f >= *n.exclusiveMaximum {
+		v.add(path, "exclusiveMaximum", "%s is not less than %s", formatNumber(f), formatNumber(*n.exclusiveMaximum))
+	}
+	if n.multipleOf != nil && *n.multipleOf > 0 {
+		q := f / *n.multipleOf
+		if math.Abs(q-math.Round(q)) > 1e-9 {
+			v.add(path, "multipleOf", "%s is not a multiple of %s", formatNumber(f), formatNumber(*n.multipleOf))
+		}
+	}
+}
+
+func matchesType(types []string, value any) bool {
+	for _, t := range types {
+		switch t {
+		case "integer":
+			if f, ok := value.(float64); ok && f == math.Trunc(f) {
+				return true
+			}
+		default:
+			if typeName(value) == t {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func typeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func isFalse(n *node) bool {
+	return n.always != nil && !*n.always
+}
+
+func containsValue(values []any, value any) bool {
+	for _, v := range values {
+		if reflect.DeepEqual(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func uniqueItems(arr []any) bool {
+	for i := range arr {
+		for j := i + 1; j < len(arr); j++ {
+			if reflect.DeepEqual(arr[i], arr[j]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+func formatValue(value any) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	out, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(out)
+}
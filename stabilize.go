@@ -0,0 +1,151 @@
+package cfnresource
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/webdestroya/cfnresource/cfnerr"
+)
+
+// StabilizeFunc polls the current state of a resource.
+//
+// It should return done once the resource has reached the desired state, or
+// failed if the resource reached a state it will not recover from. The model
+// is returned in the resulting progress event. Returning an error aborts
+// stabilization and fails the operation (subject to the retry policy).
+type StabilizeFunc[Model any] func(ctx context.Context) (done bool, failed bool, model *Model, err error)
+
+// StabilizeOptions controls how long and how often Stabilize will poll. Zero
+// fields are taken from DefaultStabilizeOptions.
+type StabilizeOptions struct {
+	// Key identifies this stabilization in the callback context. Use a distinct
+	// key for each thing a handler waits on.
+	Key string
+
+	// MaxAttempts is the number of polls before giving up. Zero means no limit.
+	MaxAttempts int
+
+	// Timeout is the total time allowed for the resource to stabilize. A
+	// negative timeout means no limit.
+	Timeout time.Duration
+
+	// InitialDelay is the callback delay after the first unsuccessful poll.
+	InitialDelay time.Duration
+
+	// MaxDelay caps the callback delay between polls.
+	MaxDelay time.Duration
+
+	// Multiplier is applied to the delay after every unsuccessful poll.
+	Multiplier float64
+}
+
+// DefaultStabilizeOptions are used when Stabilize is called with nil options.
+var DefaultStabilizeOptions = StabilizeOptions{
+	Key:          "stabilize",
+	Timeout:      time.Hour,
+	InitialDelay: 5 * time.Second,
+	MaxDelay:     time.Minute,
+	Multiplier:   1.5,
+}
+
+// stabilizeState is stored in the callback context between polls
+type stabilizeState struct {
+	Attempt int       `json:"attempt"`
+	Started time.Time `json:"started"`
+}
+
+// timeNow is replaced in tests
+var timeNow = time.Now
+
+// Stabilize polls a resource until it reaches a desired state, using
+// CloudFormation callbacks to wait between polls.
+//
+// On every invocation it calls poll once, and returns:
+//   - SUCCESS with the model once poll reports done, or with no model for a DELETE
+//   - FAILED with NotStabilized if poll reports failed, or the attempts or timeout are exhausted
+//   - IN_PROGRESS with the model and cbCtx otherwise, with an increasing callback delay
+//
+// The attempt count and start time are kept in the callback context, alongside
+// cbCtx, so the handler's own callback context can be used to track which
+// operation is being waited on.
+func Stabilize[Model any, Ctx any](ctx context.Context, req *Request[Model, Ctx], cbCtx *Ctx, poll StabilizeFunc[Model], opts *StabilizeOptions) (*ProgressEvent[Model, Ctx], error) {
+	opts = opts.withDefaults()
+	key := opts.Key
+
+	var state stabilizeState
+	if _, err := req.Extension(key, &state); err != nil {
+		return nil, err
+	}
+	if state.Started.IsZero() {
+		state.Started = timeNow()
+	}
+
+	done, failed, model, err := poll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if failed {
+		return req.ErrorResponse(cfnerr.NewMessage(cfnerr.NotStabilized, "Resource failed to stabilize")).WithModel(model), nil
+	}
+
+	if done {
+		// a deleted resource has no model
+		if req.Action == deleteAction {
+			return req.SuccessResponse(nil), nil
+		}
+		return req.SuccessResponse(model), nil
+	}
+
+	state.Attempt++
+	elapsed := timeNow().Sub(state.Started)
+
+	if opts.MaxAttempts > 0 && state.Attempt >= opts.MaxAttempts {
+		msg := fmt.Sprintf("Resource did not stabilize after %d attempts", state.Attempt)
+		return req.ErrorResponse(cfnerr.NewMessage(cfnerr.NotStabilized, msg)).WithModel(model), nil
+	}
+
+	if opts.Timeout > 0 && elapsed >= opts.Timeout {
+		msg := fmt.Sprintf("Resource did not stabilize within %s", opts.Timeout)
+		return req.ErrorResponse(cfnerr.NewMessage(cfnerr.NotStabilized, msg)).WithModel(model), nil
+	}
+
+	if model == nil {
+		model = req.ResourceProperties
+	}
+
+	delay := backoffDelay(opts.InitialDelay, opts.MaxDelay, opts.Multiplier, state.Attempt)
+
+	return req.InProgressResponse(model, cbCtx).
+		WithCallbackDelay(delay).
+		WithExtension(key, state).
+		withRequestExtensions(req), nil
+}
+
+// withDefaults returns a copy of the options with every zero field taken from
+// DefaultStabilizeOptions. MaxAttempts has no default, as zero means no limit.
+func (o *StabilizeOptions) withDefaults() *StabilizeOptions {
+	out := DefaultStabilizeOptions
+	if o == nil {
+		return &out
+	}
+
+	out.MaxAttempts = o.MaxAttempts
+	if o.Key != "" {
+		out.Key = o.Key
+	}
+	if o.Timeout > 0 {
+		out.Timeout = o.Timeout
+	}
+	if o.InitialDelay > 0 {
+		out.InitialDelay = o.InitialDelay
+	}
+	if o.MaxDelay > 0 {
+		out.MaxDelay = o.MaxDelay
+	}
+	if o.Multiplier > 0 {
+		out.Multiplier = o.Multiplier
+	}
+	return &out
+}
@@ -0,0 +1,153 @@
+package cfnresource
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	cfnTypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/stretchr/testify/require"
+	"github.com/webdestroya/cfnresource/cfnerr"
+)
+
+// stabilizeRoundTrip sends the progress event through a response and back
+// into a new request, like CloudFormation does for a callback
+func stabilizeRoundTrip(t *testing.T, pe progEventType) requestType {
+	t.Helper()
+
+	resp, err := newResponse(pe, "token")
+	require.NoError(t, err)
+
+	ev := newTestEvent(createAction, `{"Name": "Test Thing"}`)
+	ev.CallbackContext, err = json.Marshal(resp.CallbackContext)
+	require.NoError(t, err)
+
+	req, err := newRequest[model, callbackCtx](ev)
+	require.NoError(t, err)
+	return req
+}
+
+func TestStabilize(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	t.Cleanup(func() { timeNow = time.Now })
+
+	req, err := newRequest[model, callbackCtx](newTestEvent(createAction, `{"Name": "Test Thing"}`))
+	require.NoError(t, err)
+
+	polls := 0
+	poll := func(ctx context.Context) (bool, bool, *model, error) {
+		polls++
+		return polls == 4, false, &model{Name: "polled"}, nil
+	}
+
+	opts := &StabilizeOptions{
+		Key:          "waiter",
+		InitialDelay: 2 * time.Second,
+		MaxDelay:     5 * time.Second,
+		Multiplier:   2,
+	}
+
+	step := 2
+	for _, delay := range []int{2, 4, 5} {
+		pe, err := Stabilize(context.Background(), req, &callbackCtx{Step: &step}, poll, opts)
+		require.NoError(t, err)
+		require.Equal(t, cfnTypes.OperationStatusInProgress, pe.OperationStatus)
+		require.Equal(t, delay, pe.CallbackDelaySeconds)
+		require.Equal(t, "polled", pe.ResourceModel.Name)
+
+		req = stabilizeRoundTrip(t, pe)
+		require.Equal(t, 2, *req.CallbackContext.Step)
+	}
+
+	pe, err := Stabilize(context.Background(), req, req.CallbackContext, poll, opts)
+	require.NoError(t, err)
+	require.Equal(t, cfnTypes.OperationStatusSuccess, pe.OperationStatus)
+	require.Equal(t, "polled", pe.ResourceModel.Name)
+	require.Nil(t, pe.CallbackContext)
+
+	t.Run("failed", func(t *testing.T) {
+		poll := func(ctx context.Context) (bool, bool, *model, error) {
+			return false, true, nil, nil
+		}
+		pe, err := Stabilize(context.Background(), req, nil, poll, nil)
+		require.NoError(t, err)
+		require.Equal(t, cfnTypes.OperationStatusFailed, pe.OperationStatus)
+		require.Equal(t, cfnerr.NotStabilized, pe.HandlerErrorCode)
+	})
+
+	t.Run("partial options", func(t *testing.T) {
+		req := stabilizeRoundTrip(t, req.InProgressResponse(req.ResourceProperties, nil).WithExtension("other", "kept"))
+
+		poll := func(ctx context.Context) (bool, bool, *model, error) {
+			return false, false, nil, nil
+		}
+		pe, err := Stabilize(context.Background(), req, nil, poll, &StabilizeOptions{Timeout: time.Minute})
+		require.NoError(t, err)
+		require.Equal(t, cfnTypes.OperationStatusInProgress, pe.OperationStatus)
+		require.Equal(t, 5, pe.CallbackDelaySeconds)
+
+		// extensions of the request, such as retry or workflow state, are kept
+		ext, err := pe.Extensions()
+		require.NoError(t, err)
+		require.JSONEq(t, `"kept"`, string(ext["other"]))
+		require.Contains(t, ext, DefaultStabilizeOptions.Key)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		req, err := newRequest[model, callbackCtx](newTestEvent(deleteAction, `{"Name": "Test Thing"}`))
+		require.NoError(t, err)
+
+		poll := func(ctx context.Context) (bool, bool, *model, error) {
+			return true, false, &model{Name: "polled"}, nil
+		}
+		pe, err := Stabilize(context.Background(), req, nil, poll, nil)
+		require.NoError(t, err)
+		require.Equal(t, cfnTypes.OperationStatusSuccess, pe.OperationStatus)
+		require.Nil(t, pe.ResourceModel)
+		require.NoError(t, checkContract(req.Action, pe))
+	})
+
+	t.Run("error", func(t *testing.T) {
+		poll := func(ctx context.Context) (bool, bool, *model, error) {
+			return false, false, nil, errors.New("boom")
+		}
+		_, err := Stabilize(context.Background(), req, nil, poll, nil)
+		require.ErrorContains(t, err, "boom")
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		poll := func(ctx context.Context) (bool, bool, *model, error) {
+			return false, false, nil, nil
+		}
+		opts := &StabilizeOptions{Timeout: time.Minute}
+
+		pe, err := Stabilize(context.Background(), req, nil, poll, opts)
+		require.NoError(t, err)
+		require.Equal(t, cfnTypes.OperationStatusInProgress, pe.OperationStatus)
+		require.Equal(t, "Test Thing", pe.ResourceModel.Name)
+
+		req := stabilizeRoundTrip(t, pe)
+		now = now.Add(2 * time.Minute)
+
+		pe, err = Stabilize(context.Background(), req, nil, poll, opts)
+		require.NoError(t, err)
+		require.Equal(t, cfnTypes.OperationStatusFailed, pe.OperationStatus)
+		require.Equal(t, cfnerr.NotStabilized, pe.HandlerErrorCode)
+		require.Contains(t, pe.Message, "within 1m0s")
+	})
+
+	t.Run("max attempts", func(t *testing.T) {
+		poll := func(ctx context.Context) (bool, bool, *model, error) {
+			return false, false, nil, nil
+		}
+		opts := &StabilizeOptions{MaxAttempts: 1}
+
+		pe, err := Stabilize(context.Background(), req, nil, poll, opts)
+		require.NoError(t, err)
+		require.Equal(t, cfnTypes.OperationStatusFailed, pe.OperationStatus)
+		require.Contains(t, pe.Message, "after 1 attempts")
+	})
+}
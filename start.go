@@ -2,20 +2,18 @@ package cfnresource
 
 import (
 	"context"
-	"errors"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"sync"
 
 	"github.com/aws/aws-lambda-go/lambda"
-	"github.com/aws/aws-sdk-go-v2/config"
 	cfnTypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
-	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
 	"github.com/webdestroya/cfnresource/cfncontext"
 	"github.com/webdestroya/cfnresource/cfnerr"
 	"github.com/webdestroya/cfnresource/cfnutils"
-	"github.com/webdestroya/cfnresource/cloudwatchwriter"
+	"github.com/webdestroya/cfnresource/internal/handlerutil"
 )
 
 var (
@@ -58,38 +56,56 @@ func Start[Model any, Ctx any](handler Handler[Model, Ctx]) {
 	lambda.Start(makeEventFunc(handler))
 }
 
-func makeEventFunc[Model any, Ctx any](handler Handler[Model, Ctx]) func(context.Context, *event) (response, error) {
+func makeEventFunc[Model any, Ctx any](handler Handler[Model, Ctx], optFns ...func(*RuntimeOptions)) func(context.Context, *event) (response, error) {
+	opts := runtimeOptions(handler, optFns)
+
 	return func(ctx context.Context, event *event) (response, error) {
 
-		providerCfg, err := config.LoadDefaultConfig(ctx, config.WithCredentialsProvider(event.RequestData.ProviderCredentials))
+		if err := event.validate(); err != nil {
+			return newFailedResponse(err, event.BearerToken)
+		}
+
+		providerCreds, err := event.providerCredentials(opts.AllowDefaultCredentials)
+		if err != nil {
+			return newFailedResponse(err, event.BearerToken)
+		}
+		callerCreds, err := event.callerCredentials(opts.AllowDefaultCredentials)
 		if err != nil {
 			return newFailedResponse(err, event.BearerToken)
 		}
 
-		// setup the caller aws config
-		callerCfgOptions := []loadOptionsFunc{config.WithCredentialsProvider(event.RequestData.CallerCredentials)}
-		if haws, ok := handler.(AwsConfigOptioner); ok {
-			callerCfgOptions = append(callerCfgOptions, haws.GetAwsConfigOptions(ctx)...)
+		provider, err := awsConfigs.load(ctx, providerConfig, providerCreds, event.Region, opts.CacheAwsConfigs, nil)
+		if err != nil {
+			return newFailedResponse(err, event.BearerToken)
 		}
-		callerCfg, err := config.LoadDefaultConfig(ctx, callerCfgOptions...)
+		providerCfg := provider.cfg
+		ctx = cfncontext.SetProviderAwsConfig(ctx, providerCfg)
+
+		// setup the caller aws config
+		caller, err := awsConfigs.load(ctx, callerConfig, callerCreds, event.Region, opts.CacheAwsConfigs, func() []loadOptionsFunc {
+			if haws, ok := handler.(AwsConfigOptioner); ok {
+				return haws.GetAwsConfigOptions(ctx)
+			}
+			return nil
+		})
 		if err != nil {
 			return newFailedResponse(err, event.BearerToken)
 		}
-		ctx = cfncontext.SetAwsConfig(ctx, callerCfg)
+		ctx = cfncontext.SetAwsConfig(ctx, caller.cfg)
+		ctx = cfncontext.SetClientCache(ctx, caller.clients)
 
 		logicalId := event.RequestData.LogicalResourceID
 
 		// logging setup
 		// logSetup.Do(func() {
-		logStreamName := fmt.Sprintf("%s/%s", cfnutils.GetStackNameFromArn(event.StackID), logicalId)
-		cwClient := cloudwatchlogs.NewFromConfig(providerCfg)
-		logWriter = cloudwatchwriter.NewSync(ctx, cwClient, event.RequestData.ProviderLogGroupName, logStreamName)
-
-		log.SetOutput(logWriter)
-		log.SetFlags(0)
-		log.SetPrefix("")
+		logWriter = log.Writer()
+		if event.RequestData.ProviderLogGroupName != "" {
+			logStreamName := fmt.Sprintf("%s/%s", cfnutils.GetStackNameFromArn(event.StackID), logicalId)
+			logWriter = handlerutil.SetupLogging(ctx, providerCfg, event.RequestData.ProviderLogGroupName, logStreamName)
+		}
 
 		// })
+
 		if hlog, ok := handler.(PostInitializer); ok {
 			ctx, err = hlog.PostInitialize(ctx, logWriter)
 			if err != nil {
@@ -101,26 +117,66 @@ func makeEventFunc[Model any, Ctx any](handler Handler[Model, Ctx]) func(context
 			hlog.LogEvent(ctx, event)
 		}
 
-		handlerFn, err := router(event.Action, handler)
+		req, err := newRequest[Model, Ctx](event)
 		if err != nil {
 			return newFailedResponse(err, event.BearerToken)
 		}
+		ctx = cfncontext.SetRequestInfo(ctx, req.Info())
 
-		req, err := newRequest[Model, Ctx](event)
+		if err := validateModel(req); err != nil {
+			return newFailedResponse(err, event.BearerToken)
+		}
+		if err := validateInputSchema(handler, req); err != nil {
+			return newFailedResponse(err, event.BearerToken)
+		}
+
+		handlerFn, err := router(event.Action, handler)
 		if err != nil {
 			return newFailedResponse(err, event.BearerToken)
 		}
 
+		handlerFn = withRetry(handlerFn, getRetryPolicy(handler))
+		handlerFn = applyMiddleware(handlerFn, handler)
+
 		pe := invoke(handlerFn, ctx, req)
+		pe = enforceContract(event.Action, req, pe, opts.StrictContract)
+		pe = enforceOutputSchema(handler, event.Action, req, pe, opts.StrictContract)
+
+		if err := normalizeModels(event.Action, req, pe); err != nil {
+			return newFailedResponse(err, event.BearerToken)
+		}
+
 		resp, err := newResponse(pe, event.BearerToken)
 		if err != nil {
 			return newFailedResponse(err, event.BearerToken)
 		}
+
+		if obs, ok := handler.(ResponseObserver[Model, Ctx]); ok {
+			obs.ObserveResponse(ctx, req, pe)
+		}
+
 		return resp, nil
 	}
 }
 
-func router[Model any, Ctx any](action string, handler Handler[Model, Ctx]) (func(context.Context, *Request[Model, Ctx]) (*ProgressEvent[Model, Ctx], error), error) {
+// Invoke handles a single invocation payload, as sent by CloudFormation, in
+// the same way that Start does in Lambda, and returns the response payload.
+// It allows handlers to be run in-process, such as by cfntest. The optFns
+// are applied to the runtime options of the handler for this invocation.
+func Invoke[Model any, Ctx any](ctx context.Context, handler Handler[Model, Ctx], payload []byte, optFns ...func(*RuntimeOptions)) ([]byte, error) {
+	ev := new(event)
+	if err := json.Unmarshal(payload, ev); err != nil {
+		return nil, err
+	}
+
+	resp, err := makeEventFunc(handler, optFns...)(ctx, ev)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(resp)
+}
+
+func router[Model any, Ctx any](action string, handler Handler[Model, Ctx]) (HandlerFunc[Model, Ctx], error) {
 	switch action {
 	case createAction:
 		return handler.Create, nil
@@ -158,10 +214,7 @@ func invokeWrap[Model any, Ctx any](handlerFn func(context.Context, *Request[Mod
 	defer func() {
 		// Catch any panics and return a failed ProgressEvent
 		if r := recover(); r != nil {
-			err, ok := r.(error)
-			if !ok {
-				err = errors.New(fmt.Sprint(r))
-			}
+			err := handlerutil.PanicError(r)
 
 			log.Printf("Trapped error in handler: %v", err)
 
@@ -0,0 +1,94 @@
+package tags
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// As converts the tags into a list of SDK tag structs, sorted by key. T must
+// be a struct with Key and Value fields of type string or *string, which
+// covers the Tag types of nearly every AWS service:
+//
+//	input.Tags = tags.As[s3types.Tag](req.DesiredTags())
+//
+// Reserved aws: tags are left out, as AWS rejects tagging calls that include
+// them. As panics if T does not have that shape.
+func As[T any](t Tags) []T {
+	keyIdx, valueIdx := tagFields(reflect.TypeFor[T]())
+
+	out := make([]T, 0, len(t))
+	for _, k := range t.Keys() {
+		if IsReserved(k) {
+			continue
+		}
+
+		var tag T
+		v := reflect.ValueOf(&tag).Elem()
+		setString(v.Field(keyIdx), k)
+		setString(v.Field(valueIdx), t[k])
+		out = append(out, tag)
+	}
+	return out
+}
+
+// From converts a list of SDK tag structs into Tags. T must have the same
+// shape as for As. Tags with a nil key are skipped.
+func From[T any](list []T) Tags {
+	keyIdx, valueIdx := tagFields(reflect.TypeFor[T]())
+
+	out := make(Tags, len(list))
+	for _, tag := range list {
+		v := reflect.ValueOf(tag)
+		key, ok := getString(v.Field(keyIdx))
+		if !ok {
+			continue
+		}
+		value, _ := getString(v.Field(valueIdx))
+		out[key] = value
+	}
+	return out
+}
+
+// tagFields returns the indexes of the Key and Value fields of an SDK tag type
+func tagFields(t reflect.Type) (int, int) {
+	if t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("tags: %s is not a struct", t))
+	}
+
+	field := func(name string) int {
+		f, ok := t.FieldByName(name)
+		if !ok || len(f.Index) != 1 || !isString(f.Type) {
+			panic(fmt.Sprintf("tags: %s must have a %s field of type string or *string", t, name))
+		}
+		return f.Index[0]
+	}
+
+	return field("Key"), field("Value")
+}
+
+func isString(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.String
+}
+
+func setString(v reflect.Value, s string) {
+	if v.Kind() == reflect.Ptr {
+		p := reflect.New(v.Type().Elem())
+		p.Elem().SetString(s)
+		v.Set(p)
+		return
+	}
+	v.SetString(s)
+}
+
+func getString(v reflect.Value) (string, bool) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "", false
+		}
+		v = v.Elem()
+	}
+	return v.String(), true
+}
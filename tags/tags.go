@@ -0,0 +1,52 @@
+// Package tags contains helpers for the stack, system and resource tags that
+// CloudFormation sends to a resource handler.
+package tags
+
+import (
+	"maps"
+	"slices"
+	"strings"
+)
+
+// ReservedPrefix is the prefix of tags managed by AWS. They cannot be added,
+// changed or removed by a handler after the resource is created.
+const ReservedPrefix = "aws:"
+
+// Tags are stored as key/value paired strings
+type Tags map[string]string
+
+// IsReserved reports whether the key is managed by AWS
+func IsReserved(key string) bool {
+	return strings.HasPrefix(strings.ToLower(key), ReservedPrefix)
+}
+
+// Keys returns the tag keys in sorted order
+func (t Tags) Keys() []string {
+	return slices.Sorted(maps.Keys(t))
+}
+
+// WithoutReserved returns a copy of the tags with any reserved aws: tags removed
+func (t Tags) WithoutReserved() Tags {
+	out := make(Tags, len(t))
+	for k, v := range t {
+		if !IsReserved(k) {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// Merge combines the given tag sets into a new set. When a key appears in
+// more than one set, the value from the later set wins, so the usual order is
+//
+//	tags.Merge(systemTags, stackTags, resourceTags)
+//
+// giving resource tags precedence over stack tags, and stack tags precedence
+// over system tags.
+func Merge(sets ...Tags) Tags {
+	out := Tags{}
+	for _, set := range sets {
+		maps.Copy(out, set)
+	}
+	return out
+}
@@ -0,0 +1,87 @@
+package tags_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/webdestroya/cfnresource/cfnerr"
+	"github.com/webdestroya/cfnresource/tags"
+)
+
+type ptrTag struct {
+	Key   *string
+	Value *string
+}
+
+type plainTag struct {
+	Key   string
+	Value string
+}
+
+func TestMerge(t *testing.T) {
+	system := tags.Tags{"aws:cloudformation:stack-name": "stack", "Env": "system"}
+	stack := tags.Tags{"Env": "stack", "Team": "core"}
+	resource := tags.Tags{"Env": "resource"}
+
+	merged := tags.Merge(system, stack, resource)
+	require.Equal(t, tags.Tags{"aws:cloudformation:stack-name": "stack", "Env": "resource", "Team": "core"}, merged)
+	require.Equal(t, tags.Tags{"Env": "resource", "Team": "core"}, merged.WithoutReserved())
+	require.Equal(t, []string{"Env", "Team", "aws:cloudformation:stack-name"}, merged.Keys())
+}
+
+func TestSDKConversion(t *testing.T) {
+	in := tags.Tags{"b": "2", "a": "1"}
+
+	ptrs := tags.As[ptrTag](in)
+	require.Len(t, ptrs, 2)
+	require.Equal(t, "a", *ptrs[0].Key)
+	require.Equal(t, "1", *ptrs[0].Value)
+
+	plain := tags.As[plainTag](in)
+	require.Equal(t, []plainTag{{"a", "1"}, {"b", "2"}}, plain)
+
+	require.Equal(t, in, tags.From(ptrs))
+	require.Equal(t, in, tags.From(plain))
+	require.Equal(t, tags.Tags{}, tags.From([]ptrTag{{}}))
+
+	// reserved tags cannot be passed to tagging calls
+	require.Equal(t, []plainTag{{"a", "1"}}, tags.As[plainTag](tags.Tags{"a": "1", "aws:cloudformation:stack-name": "stack"}))
+
+	require.Panics(t, func() {
+		tags.As[struct{ Name string }](in)
+	})
+}
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, tags.Validate(tags.Tags{"Env": "prod", "aws:cloudformation:stack-id": "x", "path/to:thing": "a b=c+d@e"}))
+
+	tests := map[string]tags.Tags{
+		"tag keys cannot be empty":    {"": "x"},
+		"longer than 128 characters":  {strings.Repeat("k", 129): "x"},
+		"longer than 256 characters":  {"k": strings.Repeat("v", 257)},
+		"contains invalid characters": {"bad*key": "x"},
+	}
+
+	for msg, in := range tests {
+		t.Run(msg, func(t *testing.T) {
+			err := tags.Validate(in)
+			require.ErrorContains(t, err, msg)
+
+			var cerr cfnerr.Error
+			require.ErrorAs(t, err, &cerr)
+			require.Equal(t, cfnerr.InvalidRequest, cerr.Code())
+		})
+	}
+
+	t.Run("too many", func(t *testing.T) {
+		many := tags.Tags{"aws:reserved": "x"}
+		for i := 0; i < tags.MaxTags; i++ {
+			many[strings.Repeat("k", i+1)] = "v"
+		}
+		require.NoError(t, tags.Validate(many))
+
+		many["extra-key"] = "v"
+		require.ErrorContains(t, tags.Validate(many), "51 tags were given, but at most 50 are allowed")
+	})
+}
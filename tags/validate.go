@@ -0,0 +1,55 @@
+package tags
+
+import (
+	"fmt"
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/webdestroya/cfnresource/cfnerr"
+)
+
+// These are the limits that apply to most AWS services. They can be lowered
+// for services with stricter limits.
+var (
+	MaxTags        = 50
+	MaxKeyLength   = 128
+	MaxValueLength = 256
+)
+
+// validTagChars matches the characters allowed in tag keys and values
+var validTagChars = regexp.MustCompile(`^[\p{L}\p{Z}\p{N}_.:/=+\-@]*$`)
+
+// Validate checks the tags against the AWS tagging limits, returning an
+// InvalidRequest error describing the first problem found. Reserved aws: tags
+// are not counted, as they are managed by AWS.
+func Validate(t Tags) error {
+	count := 0
+
+	for _, k := range t.Keys() {
+		if IsReserved(k) {
+			continue
+		}
+		count++
+
+		v := t[k]
+
+		switch {
+		case k == "":
+			return cfnerr.NewMessage(cfnerr.InvalidRequest, "tag keys cannot be empty")
+		case utf8.RuneCountInString(k) > MaxKeyLength:
+			return cfnerr.NewMessage(cfnerr.InvalidRequest, fmt.Sprintf("tag key %q is longer than %d characters", k, MaxKeyLength))
+		case utf8.RuneCountInString(v) > MaxValueLength:
+			return cfnerr.NewMessage(cfnerr.InvalidRequest, fmt.Sprintf("value of tag %q is longer than %d characters", k, MaxValueLength))
+		case !validTagChars.MatchString(k):
+			return cfnerr.NewMessage(cfnerr.InvalidRequest, fmt.Sprintf("tag key %q contains invalid characters", k))
+		case !validTagChars.MatchString(v):
+			return cfnerr.NewMessage(cfnerr.InvalidRequest, fmt.Sprintf("value of tag %q contains invalid characters", k))
+		}
+	}
+
+	if count > MaxTags {
+		return cfnerr.NewMessage(cfnerr.InvalidRequest, fmt.Sprintf("%d tags were given, but at most %d are allowed", count, MaxTags))
+	}
+
+	return nil
+}
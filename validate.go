@@ -0,0 +1,33 @@
+package cfnresource
+
+import (
+	"github.com/webdestroya/cfnresource/cfnerr"
+	"github.com/webdestroya/cfnresource/validation"
+)
+
+// validateModel checks the desired model of a new CREATE or UPDATE against
+// its schema struct tags, and fails the request with InvalidRequest if any
+// constraint is not met. See validation.Constraints for the supported tags.
+// Models without schema tags always pass. Callbacks are not validated again,
+// as the model they carry was returned by the handler.
+func validateModel[Model any, Ctx any](req *Request[Model, Ctx]) error {
+	if !isNewWrite(req) {
+		return nil
+	}
+
+	err := validation.Validate(req.ResourceProperties)
+	if err != nil && !cfnerr.Is(err) {
+		// the model's tags are invalid, which is a bug in the handler
+		return cfnerr.Wrap(cfnerr.InternalFailure, err)
+	}
+	return err
+}
+
+// isNewWrite reports whether the request is the first invocation of a CREATE
+// or UPDATE with a desired model
+func isNewWrite[Model any, Ctx any](req *Request[Model, Ctx]) bool {
+	if req.ResourceProperties == nil || (req.event != nil && len(req.event.CallbackContext) > 0) {
+		return false
+	}
+	return req.Action == createAction || req.Action == updateAction
+}
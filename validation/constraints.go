@@ -0,0 +1,237 @@
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// TagName is the struct tag that holds the constraints of a field
+const TagName = "schema"
+
+// Constraints are the JSON schema keywords declared in a field's schema tag:
+//
+//	Name  *string   `json:",omitempty" schema:"required,minLength=1,maxLength=64,pattern=^[a-z-]+$"`
+//	Mode  *string   `json:",omitempty" schema:"enum=fast|safe"`
+//	Size  *int      `json:",omitempty" schema:"minimum=1,maximum=100"`
+//	Ports []int     `json:",omitempty" schema:"minItems=1,maxItems=5,uniqueItems"`
+//
+// The tag is separate from the validate tag used by validator libraries, so
+// models can carry both. A pattern must be the last keyword, as the expression may contain commas.
+// Enum values are separated by '|'.
+//
+// A zero value in a field that is not a pointer is treated as missing, so
+// minimum, maximum and enum are only accepted on pointers to numbers, where
+// an explicit 0 can still be checked.
+type Constraints struct {
+	Required    bool
+	MinLength   *int
+	MaxLength   *int
+	Pattern     string
+	Enum        []string
+	Minimum     *float64
+	Maximum     *float64
+	MinItems    *int
+	MaxItems    *int
+	UniqueItems bool
+
+	pattern *regexp.Regexp
+}
+
+// IsZero reports whether no constraints are declared
+func (c Constraints) IsZero() bool {
+	return !c.Required && c.MinLength == nil && c.MaxLength == nil && c.Pattern == "" && len(c.Enum) == 0 &&
+		c.Minimum == nil && c.Maximum == nil && c.MinItems == nil && c.MaxItems == nil && !c.UniqueItems
+}
+
+// ParseTag parses the contents of a schema struct tag
+func ParseTag(tag string) (Constraints, error) {
+	var c Constraints
+
+	for tag != "" {
+		if v, ok := strings.CutPrefix(tag, "pattern="); ok {
+			re, err := regexp.Compile(v)
+			if err != nil {
+				return c, fmt.Errorf("invalid %s pattern %q: %w", TagName, v, err)
+			}
+			c.Pattern = v
+			c.pattern = re
+			break
+		}
+
+		var opt string
+		opt, tag, _ = strings.Cut(tag, ",")
+
+		key, value, hasValue := strings.Cut(strings.TrimSpace(opt), "=")
+
+		var err error
+		switch key {
+		case "required":
+			c.Required = true
+		case "uniqueItems":
+			c.UniqueItems = true
+		case "minLength":
+			c.MinLength, err = parseInt(value)
+		case "maxLength":
+			c.MaxLength, err = parseInt(value)
+		case "minItems":
+			c.MinItems, err = parseInt(value)
+		case "maxItems":
+			c.MaxItems, err = parseInt(value)
+		case "minimum":
+			c.Minimum, err = parseFloat(value)
+		case "maximum":
+			c.Maximum, err = parseFloat(value)
+		case "enum":
+			if value == "" {
+				err = fmt.Errorf("enum needs at least one value")
+			}
+			c.Enum = strings.Split(value, "|")
+		case "":
+			continue
+		default:
+			return c, fmt.Errorf("unknown %s tag option %q", TagName, opt)
+		}
+
+		if err == nil && hasValue != needsValue(key) {
+			err = fmt.Errorf("unexpected value")
+			if needsValue(key) {
+				err = fmt.Errorf("missing value")
+			}
+		}
+		if err != nil {
+			return c, fmt.Errorf("invalid %s tag option %q: %w", TagName, opt, err)
+		}
+	}
+
+	return c, nil
+}
+
+func needsValue(key string) bool {
+	return key != "required" && key != "uniqueItems"
+}
+
+func parseInt(s string) (*int, error) {
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func parseFloat(s string) (*float64, error) {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// Field is a struct field along with its JSON name and constraints
+type Field struct {
+	reflect.StructField
+
+	// Name is the name of the field in JSON
+	Name string
+
+	// Inline is set for embedded structs whose fields are part of the parent
+	Inline bool
+
+	Constraints Constraints
+}
+
+var fieldCache sync.Map // map[reflect.Type]fieldsResult
+
+type fieldsResult struct {
+	fields []Field
+	err    error
+}
+
+// Fields returns the fields of a struct type that are encoded in JSON, with
+// their constraints. The result is cached for each type.
+func Fields(t reflect.Type) ([]Field, error) {
+	if cached, ok := fieldCache.Load(t); ok {
+		r := cached.(fieldsResult)
+		return r.fields, r.err
+	}
+
+	fields, err := structFields(t)
+	fieldCache.Store(t, fieldsResult{fields, err})
+	return fields, err
+}
+
+func structFields(t reflect.Type) ([]Field, error) {
+	fields := make([]Field, 0, t.NumField())
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+
+		name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if !sf.IsExported() {
+			continue
+		}
+		inline := sf.Anonymous && name == "" && indirect(sf.Type).Kind() == reflect.Struct
+		if name == "" {
+			name = sf.Name
+		}
+
+		c, err := ParseTag(sf.Tag.Get(TagName))
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", t.Name(), sf.Name, err)
+		}
+		if err := checkApplicable(sf.Type, c); err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", t.Name(), sf.Name, err)
+		}
+
+		fields = append(fields, Field{StructField: sf, Name: name, Inline: inline, Constraints: c})
+	}
+
+	return fields, nil
+}
+
+// checkApplicable rejects keywords that can never apply to a field's type
+func checkApplicable(t reflect.Type, c Constraints) error {
+	kind := indirect(t).Kind()
+
+	isString := kind == reflect.String
+	isNumber := isNumberKind(kind)
+	isList := kind == reflect.Slice || kind == reflect.Array
+
+	switch {
+	case !isString && (c.MinLength != nil || c.MaxLength != nil || c.Pattern != ""):
+		return fmt.Errorf("minLength, maxLength and pattern only apply to strings, not %s", t)
+	case !isString && !isNumber && len(c.Enum) > 0:
+		return fmt.Errorf("enum only applies to strings and numbers, not %s", t)
+	case !isNumber && (c.Minimum != nil || c.Maximum != nil):
+		return fmt.Errorf("minimum and maximum only apply to numbers, not %s", t)
+	case isNumber && t.Kind() != reflect.Pointer && (c.Minimum != nil || c.Maximum != nil || len(c.Enum) > 0):
+		return fmt.Errorf("minimum, maximum and enum need a pointer to tell a zero number from a missing one, not %s", t)
+	case !isList && (c.MinItems != nil || c.MaxItems != nil || c.UniqueItems):
+		return fmt.Errorf("minItems, maxItems and uniqueItems only apply to lists, not %s", t)
+	}
+	return nil
+}
+
+func isNumberKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
@@ -0,0 +1,250 @@
+// Package validation checks models against the JSON schema style constraints
+// declared in their schema struct tags. See Constraints for the keywords.
+package validation
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/webdestroya/cfnresource/cfnerr"
+)
+
+// Violation is a value that does not meet one of its constraints
+type Violation struct {
+	// Path is a JSON pointer to the value, such as #/Tags/0/Key
+	Path string
+
+	// Keyword is the constraint that was not met, such as maxLength
+	Keyword string
+
+	Message string
+}
+
+func (v Violation) String() string {
+	return v.Path + ": " + v.Message
+}
+
+// Violations are all of the problems found in a model
+type Violations []Violation
+
+func (v Violations) Error() string {
+	parts := make([]string, 0, len(v))
+	for _, violation := range v {
+		parts = append(parts, violation.String())
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Err returns nil if there are no violations, and otherwise the
+// cfnerr.InvalidRequest error that reports them
+func (v Violations) Err() error {
+	if len(v) == 0 {
+		return nil
+	}
+	return cfnerr.New(cfnerr.InvalidRequest, "Model validation failed ("+v.Error()+")", v)
+}
+
+// Validate checks v, and everything it contains, against the constraints in
+// its schema tags. Every violation is reported, in a cfnerr.InvalidRequest
+// error that wraps Violations:
+//
+//	Model validation failed (#/Name: expected maxLength: 64, actual: 70; #/Size: 0 is not greater or equal to 1)
+//
+// A nil pointer is treated as a missing property, so only the required
+// keyword applies to it. A field that is not a pointer is missing when it has
+// its zero value, as it would be left out by omitempty.
+//
+// An error that is not a cfnerr.Error is returned if a schema tag is invalid.
+func Validate(v any) error {
+	var violations Violations
+	if err := validateValue(reflect.ValueOf(v), "#", &violations); err != nil {
+		return err
+	}
+
+	return violations.Err()
+}
+
+// validateValue validates the contents of a value, but not its own constraints
+func validateValue(rv reflect.Value, path string, violations *Violations) error {
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.Struct:
+		return validateStruct(rv, path, violations)
+
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if err := validateValue(rv.Index(i), path+"/"+strconv.Itoa(i), violations); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		// keys are sorted so that violations are reported in the same order
+		// every time
+		keys := make(map[string]reflect.Value, rv.Len())
+		for _, k := range rv.MapKeys() {
+			keys[fmt.Sprint(k.Interface())] = k
+		}
+		for _, name := range slices.Sorted(maps.Keys(keys)) {
+			if err := validateValue(rv.MapIndex(keys[name]), path+"/"+escapePointer(name), violations); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateStruct(rv reflect.Value, path string, violations *Violations) error {
+	fields, err := Fields(rv.Type())
+	if err != nil {
+		return err
+	}
+
+	for _, f := range fields {
+		fv := rv.FieldByIndex(f.Index)
+
+		if f.Inline {
+			if err := validateValue(fv, path, violations); err != nil {
+				return err
+			}
+			continue
+		}
+
+		fieldPath := path + "/" + escapePointer(f.Name)
+		checkConstraints(fv, fieldPath, f.Constraints, violations)
+
+		if err := validateValue(fv, fieldPath, violations); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkConstraints checks a value against its own constraints
+func checkConstraints(rv reflect.Value, path string, c Constraints, violations *Violations) {
+	add := func(keyword string, format string, args ...any) {
+		*violations = append(*violations, Violation{Path: path, Keyword: keyword, Message: fmt.Sprintf(format, args...)})
+	}
+
+	missing := rv.IsZero()
+	if rv.Kind() == reflect.Pointer {
+		missing = rv.IsNil()
+	}
+	if missing {
+		if c.Required {
+			add("required", "required property is missing")
+		}
+		return
+	}
+
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return
+		}
+		rv = rv.Elem()
+	}
+
+	switch {
+	case rv.Kind() == reflect.String:
+		s := rv.String()
+		length := utf8.RuneCountInString(s)
+		if c.MinLength != nil && length < *c.MinLength {
+			add("minLength", "expected minLength: %d, actual: %d", *c.MinLength, length)
+		}
+		if c.MaxLength != nil && length > *c.MaxLength {
+			add("maxLength", "expected maxLength: %d, actual: %d", *c.MaxLength, length)
+		}
+		if c.pattern != nil && !c.pattern.MatchString(s) {
+			add("pattern", "string [%s] does not match pattern %s", s, c.Pattern)
+		}
+		if len(c.Enum) > 0 && !contains(c.Enum, s) {
+			add("enum", "%s is not a valid enum value", s)
+		}
+
+	case isNumberKind(rv.Kind()):
+		n := numberValue(rv)
+		if c.Minimum != nil && n < *c.Minimum {
+			add("minimum", "%s is not greater or equal to %s", formatNumber(n), formatNumber(*c.Minimum))
+		}
+		if c.Maximum != nil && n > *c.Maximum {
+			add("maximum", "%s is not less or equal to %s", formatNumber(n), formatNumber(*c.Maximum))
+		}
+		if len(c.Enum) > 0 && !containsNumber(c.Enum, n) {
+			add("enum", "%s is not a valid enum value", formatNumber(n))
+		}
+
+	case rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array:
+		count := rv.Len()
+		if c.MinItems != nil && count < *c.MinItems {
+			add("minItems", "expected minimum item count: %d, found: %d", *c.MinItems, count)
+		}
+		if c.MaxItems != nil && count > *c.MaxItems {
+			add("maxItems", "expected maximum item count: %d, found: %d", *c.MaxItems, count)
+		}
+		if c.UniqueItems && !uniqueItems(rv) {
+			add("uniqueItems", "array items are not unique")
+		}
+	}
+}
+
+func numberValue(rv reflect.Value) float64 {
+	switch {
+	case rv.CanInt():
+		return float64(rv.Int())
+	case rv.CanUint():
+		return float64(rv.Uint())
+	default:
+		return rv.Float()
+	}
+}
+
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsNumber(values []string, n float64) bool {
+	for _, v := range values {
+		if f, err := strconv.ParseFloat(v, 64); err == nil && f == n {
+			return true
+		}
+	}
+	return false
+}
+
+func uniqueItems(rv reflect.Value) bool {
+	for i := 0; i < rv.Len(); i++ {
+		for j := i + 1; j < rv.Len(); j++ {
+			if reflect.DeepEqual(rv.Index(i).Interface(), rv.Index(j).Interface()) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// escapePointer escapes a JSON pointer reference token
+func escapePointer(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
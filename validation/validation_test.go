@@ -0,0 +1,196 @@
+package validation
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/webdestroya/cfnresource/cfnerr"
+)
+
+type tag struct {
+	Key   *string `json:",omitempty" schema:"required,minLength=1,maxLength=128"`
+	Value *string `json:",omitempty" schema:"maxLength=256"`
+}
+
+type Common struct {
+	Description *string `json:",omitempty" schema:"maxLength=10"`
+}
+
+type model struct {
+	Common
+
+	Name     *string        `json:",omitempty" schema:"required,pattern=^[a-z][a-z0-9-]*$"`
+	Mode     *string        `json:",omitempty" schema:"enum=fast|safe"`
+	Size     *int           `json:",omitempty" schema:"minimum=1,maximum=100"`
+	Ratio    *float64       `json:",omitempty" schema:"minimum=0.5"`
+	Level    *int           `json:",omitempty" schema:"enum=1|2|3"`
+	Ports    []int          `json:",omitempty" schema:"minItems=1,maxItems=3,uniqueItems"`
+	Tags     []tag          `json:",omitempty"`
+	Labels   map[string]tag `json:",omitempty"`
+	Renamed  string         `json:"renamed/key,omitempty" schema:"maxLength=2"`
+	Ignored  string         `json:"-" schema:"required"`
+	internal string
+	Extra    map[string]string `json:",omitempty"`
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		model      any
+		violations []Violation
+	}{
+		{
+			name:  "valid",
+			model: &model{Name: ptr("good-name"), Mode: ptr("fast"), Size: ptr(1), Level: ptr(2), Ports: []int{1, 2}},
+		},
+		{
+			name:  "empty lists are still checked",
+			model: &model{Name: ptr("a"), Ports: []int{}},
+			violations: []Violation{
+				{Path: "#/Ports", Keyword: "minItems", Message: "expected minimum item count: 1, found: 0"},
+			},
+		},
+		{
+			name:  "missing required",
+			model: &model{},
+			violations: []Violation{
+				{Path: "#/Name", Keyword: "required", Message: "required property is missing"},
+			},
+		},
+		{
+			name: "every violation is reported",
+			model: &model{
+				Common:  Common{Description: ptr("much too long")},
+				Name:    ptr("Bad Name"),
+				Mode:    ptr("slow"),
+				Size:    ptr(101),
+				Ratio:   ptr(0.25),
+				Level:   ptr(4),
+				Ports:   []int{1, 2, 2, 3},
+				Renamed: "abc",
+			},
+			violations: []Violation{
+				{Path: "#/Description", Keyword: "maxLength", Message: "expected maxLength: 10, actual: 13"},
+				{Path: "#/Name", Keyword: "pattern", Message: "string [Bad Name] does not match pattern ^[a-z][a-z0-9-]*$"},
+				{Path: "#/Mode", Keyword: "enum", Message: "slow is not a valid enum value"},
+				{Path: "#/Size", Keyword: "maximum", Message: "101 is not less or equal to 100"},
+				{Path: "#/Ratio", Keyword: "minimum", Message: "0.25 is not greater or equal to 0.5"},
+				{Path: "#/Level", Keyword: "enum", Message: "4 is not a valid enum value"},
+				{Path: "#/Ports", Keyword: "maxItems", Message: "expected maximum item count: 3, found: 4"},
+				{Path: "#/Ports", Keyword: "uniqueItems", Message: "array items are not unique"},
+				{Path: "#/renamed~1key", Keyword: "maxLength", Message: "expected maxLength: 2, actual: 3"},
+			},
+		},
+		{
+			name: "nested values",
+			model: &model{
+				Name:   ptr("a"),
+				Tags:   []tag{{Key: ptr("ok")}, {Value: ptr("v")}, {Key: ptr("")}},
+				Labels: map[string]tag{"x": {}, "b": {}, "a/c": {}, "z": {Key: ptr("ok")}},
+			},
+			violations: []Violation{
+				{Path: "#/Tags/1/Key", Keyword: "required", Message: "required property is missing"},
+				{Path: "#/Tags/2/Key", Keyword: "minLength", Message: "expected minLength: 1, actual: 0"},
+				{Path: "#/Labels/a~1c/Key", Keyword: "required", Message: "required property is missing"},
+				{Path: "#/Labels/b/Key", Keyword: "required", Message: "required property is missing"},
+				{Path: "#/Labels/x/Key", Keyword: "required", Message: "required property is missing"},
+			},
+		},
+		{
+			name:  "slice of models",
+			model: []*tag{{Key: ptr("ok")}, nil, {}},
+			violations: []Violation{
+				{Path: "#/2/Key", Keyword: "required", Message: "required property is missing"},
+			},
+		},
+		{
+			name:  "nil",
+			model: (*model)(nil),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.model)
+			if len(tt.violations) == 0 {
+				require.NoError(t, err)
+				return
+			}
+
+			ce, ok := cfnerr.As(err)
+			require.True(t, ok)
+			require.Equal(t, cfnerr.InvalidRequest, ce.Code())
+
+			var violations Violations
+			require.ErrorAs(t, err, &violations)
+			require.Equal(t, Violations(tt.violations), violations)
+			require.Equal(t, "Model validation failed ("+violations.Error()+")", ce.Message())
+		})
+	}
+}
+
+func TestValidateMultibyteLength(t *testing.T) {
+	// lengths are counted in characters, like JSON schema
+	require.NoError(t, Validate(&model{Name: ptr("a"), Common: Common{Description: ptr("éééééééééé")}}))
+}
+
+func TestParseTag(t *testing.T) {
+	c, err := ParseTag("required, minLength=1,maxItems=2,enum=a|b,minimum=-1.5,uniqueItems,pattern=^a{1,2}$")
+	require.NoError(t, err)
+	require.True(t, c.Required)
+	require.Equal(t, 1, *c.MinLength)
+	require.Equal(t, 2, *c.MaxItems)
+	require.Equal(t, []string{"a", "b"}, c.Enum)
+	require.Equal(t, -1.5, *c.Minimum)
+	require.True(t, c.UniqueItems)
+	require.Equal(t, "^a{1,2}$", c.Pattern)
+	require.False(t, c.IsZero())
+
+	c, err = ParseTag("")
+	require.NoError(t, err)
+	require.True(t, c.IsZero())
+
+	for _, tag := range []string{"bogus", "minLength", "minLength=x", "required=true", "enum=", "pattern=("} {
+		_, err := ParseTag(tag)
+		require.Error(t, err, tag)
+	}
+}
+
+func TestInvalidTags(t *testing.T) {
+	type wrongKind struct {
+		Count *int `schema:"minLength=1"`
+	}
+	err := Validate(&wrongKind{Count: ptr(1)})
+	require.ErrorContains(t, err, "wrongKind.Count: minLength, maxLength and pattern only apply to strings")
+	require.False(t, cfnerr.Is(err))
+
+	type zeroNumber struct {
+		Ratio float64 `schema:"minimum=0.5"`
+	}
+	err = Validate(&zeroNumber{})
+	require.ErrorContains(t, err, "zeroNumber.Ratio: minimum, maximum and enum need a pointer")
+
+	type badTag struct {
+		Name string `schema:"maxLen=3"`
+	}
+	_, err = Fields(reflect.TypeFor[badTag]())
+	require.ErrorContains(t, err, `unknown schema tag option "maxLen=3"`)
+}
+
+func TestFields(t *testing.T) {
+	fields, err := Fields(reflect.TypeFor[model]())
+	require.NoError(t, err)
+
+	names := make([]string, 0, len(fields))
+	for _, f := range fields {
+		names = append(names, f.Name)
+	}
+	require.Equal(t, []string{"Common", "Name", "Mode", "Size", "Ratio", "Level", "Ports", "Tags", "Labels", "renamed/key", "Extra"}, names)
+	require.True(t, fields[0].Inline)
+	require.True(t, fields[1].Constraints.Required)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
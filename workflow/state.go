@@ -0,0 +1,67 @@
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// State is data that a single step keeps between invocations, such as the
+// identifier of something it created that must be polled or rolled back.
+type State struct {
+	data json.RawMessage
+}
+
+// Get decodes the step's state into v, reporting whether any state was stored.
+func (s *State) Get(v any) (bool, error) {
+	if len(s.data) == 0 {
+		return false, nil
+	}
+
+	if err := json.Unmarshal(s.data, v); err != nil {
+		return true, fmt.Errorf("unable to unmarshal step state: %w", err)
+	}
+
+	return true, nil
+}
+
+// Set replaces the step's state with v. The value is encoded with encoding/json.
+func (s *State) Set(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("unable to marshal step state: %w", err)
+	}
+	s.data = data
+	return nil
+}
+
+// progress is what the workflow persists in the callback context
+type progress struct {
+	// Current is the name of the step being executed
+	Current string `json:"current,omitempty"`
+
+	// Started is set once the current step's Run has succeeded
+	Started bool `json:"started,omitempty"`
+
+	// Completed lists the names of steps that have finished, in order
+	Completed []string `json:"completed,omitempty"`
+
+	// Retries is the number of retryable failures of the current step
+	Retries int `json:"retries,omitempty"`
+
+	// States holds the State of each step by name
+	States map[string]json.RawMessage `json:"states,omitempty"`
+}
+
+func (p *progress) state(name string) *State {
+	return &State{data: p.States[name]}
+}
+
+func (p *progress) saveState(name string, s *State) {
+	if len(s.data) == 0 {
+		return
+	}
+	if p.States == nil {
+		p.States = make(map[string]json.RawMessage)
+	}
+	p.States[name] = s.data
+}
@@ -0,0 +1,251 @@
+/*
+Package workflow runs a handler operation as a sequence of named steps that
+can span several CloudFormation callbacks.
+
+The current step, the steps that have completed and each step's State are
+stored in the callback context, so a re-invoked handler resumes where it left
+off. If a step fails, the steps that already completed are compensated in
+reverse order before the operation is failed.
+*/
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	cfnTypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/webdestroya/cfnresource"
+	"github.com/webdestroya/cfnresource/cfnerr"
+)
+
+const (
+	defaultExtensionKey = "workflow"
+	deleteAction        = "DELETE"
+)
+
+// Step is a single stage of a workflow.
+type Step[Model any, Ctx any] struct {
+	// Name identifies the step in the callback context. It must be unique
+	// within a workflow and should not change between releases of a handler.
+	Name string
+
+	// Run performs the step. It is called once, unless it returns an error
+	// that the workflow's RetryPolicy retries, in which case it is called
+	// again on the next callback.
+	Run func(context.Context, *cfnresource.Request[Model, Ctx], *State) error
+
+	// Stabilize is optional, and is polled after Run until the step has
+	// finished. Polling uses cfnresource.Stabilize with StabilizeOptions.
+	Stabilize func(context.Context, *cfnresource.Request[Model, Ctx], *State) (done bool, failed bool, err error)
+
+	// StabilizeOptions overrides the workflow's options for this step.
+	StabilizeOptions *cfnresource.StabilizeOptions
+
+	// Compensate is optional, and undoes the step when a later step fails.
+	Compensate func(context.Context, *cfnresource.Request[Model, Ctx], *State) error
+}
+
+// Workflow is an ordered list of steps.
+type Workflow[Model any, Ctx any] struct {
+	steps []Step[Model, Ctx]
+
+	// Key is where the workflow progress is stored in the callback context.
+	// Use distinct keys if a handler runs more than one workflow.
+	Key string
+
+	// StabilizeOptions is used for steps that do not provide their own.
+	StabilizeOptions cfnresource.StabilizeOptions
+
+	// RetryPolicy decides which step errors are retried, from its Codes and
+	// cfnerr.Retryable. When nil, cfnresource.DefaultRetryPolicy is used, like
+	// the runtime does for handlers without a RetryPolicyProvider.
+	RetryPolicy *cfnresource.RetryPolicy
+
+	// MaxRetries is the number of retryable failures allowed per step.
+	MaxRetries int
+
+	// RetryDelay is the callback delay used when a retryable error does not specify one.
+	RetryDelay time.Duration
+}
+
+// New returns a workflow that executes the steps in order.
+func New[Model any, Ctx any](steps ...Step[Model, Ctx]) *Workflow[Model, Ctx] {
+	names := make(map[string]struct{}, len(steps))
+	for _, s := range steps {
+		if s.Name == "" || s.Run == nil {
+			panic("workflow: every step needs a Name and a Run function")
+		}
+		if _, ok := names[s.Name]; ok {
+			panic(fmt.Sprintf("workflow: duplicate step name %q", s.Name))
+		}
+		names[s.Name] = struct{}{}
+	}
+
+	return &Workflow[Model, Ctx]{
+		steps:            steps,
+		Key:              defaultExtensionKey,
+		StabilizeOptions: cfnresource.DefaultStabilizeOptions,
+		MaxRetries:       5,
+		RetryDelay:       10 * time.Second,
+	}
+}
+
+// Run executes the workflow for the request, starting at the step recorded
+// in the callback context. Steps run back-to-back within an invocation until
+// one needs to wait, at which point an IN_PROGRESS event is returned.
+//
+// When every step has completed, a SUCCESS event is returned with the request's
+// ResourceProperties, which steps may modify (except for DELETE, which returns no model).
+func (w *Workflow[Model, Ctx]) Run(ctx context.Context, req *cfnresource.Request[Model, Ctx]) (*cfnresource.ProgressEvent[Model, Ctx], error) {
+	var p progress
+	if _, err := req.Extension(w.Key, &p); err != nil {
+		return nil, err
+	}
+
+	idx := 0
+	if p.Current != "" {
+		idx = slices.IndexFunc(w.steps, func(s Step[Model, Ctx]) bool { return s.Name == p.Current })
+		if idx < 0 {
+			return nil, cfnerr.NewMessage(cfnerr.InternalFailure, fmt.Sprintf("workflow: unknown step %q in callback context", p.Current))
+		}
+	}
+
+	for ; idx < len(w.steps); idx++ {
+		step := w.steps[idx]
+		state := p.state(step.Name)
+		p.Current = step.Name
+
+		if !p.Started {
+			err := step.Run(ctx, req, state)
+			p.saveState(step.Name, state)
+			if err != nil {
+				return w.handleError(ctx, req, &p, step, err)
+			}
+			p.Started = true
+			p.Retries = 0
+		}
+
+		if step.Stabilize != nil {
+			poll := func(ctx context.Context) (bool, bool, *Model, error) {
+				done, failed, err := step.Stabilize(ctx, req, state)
+				return done, failed, req.ResourceProperties, err
+			}
+
+			pe, err := cfnresource.Stabilize(ctx, req, req.CallbackContext, poll, w.stabilizeOptions(step))
+			p.saveState(step.Name, state)
+			if err != nil {
+				return w.handleError(ctx, req, &p, step, err)
+			}
+
+			switch pe.OperationStatus {
+			case cfnTypes.OperationStatusInProgress:
+				return pe.WithExtension(w.Key, p), nil
+			case cfnTypes.OperationStatusFailed:
+				return w.rollback(ctx, req, &p, true, cfnerr.NewMessage(pe.HandlerErrorCode, fmt.Sprintf("step %s: %s", step.Name, pe.Message)))
+			}
+		}
+
+		log.Printf("Workflow step %s completed", step.Name)
+		p.Completed = append(p.Completed, step.Name)
+		p.Started = false
+		p.Retries = 0
+	}
+
+	if req.Action == deleteAction {
+		return req.SuccessResponse(nil), nil
+	}
+	return req.SuccessResponse(req.ResourceProperties), nil
+}
+
+func (w *Workflow[Model, Ctx]) stabilizeOptions(step Step[Model, Ctx]) *cfnresource.StabilizeOptions {
+	opts := w.StabilizeOptions
+	if step.StabilizeOptions != nil {
+		opts = *step.StabilizeOptions
+	}
+	opts.Key = w.Key + ":" + step.Name
+	return &opts
+}
+
+// handleError either schedules a retry of the current step, or rolls back the workflow
+func (w *Workflow[Model, Ctx]) handleError(ctx context.Context, req *cfnresource.Request[Model, Ctx], p *progress, step Step[Model, Ctx], err error) (*cfnresource.ProgressEvent[Model, Ctx], error) {
+	if after, ok := w.retryPolicy().Retryable(err); ok && p.Retries < w.MaxRetries {
+		p.Retries++
+		if after <= 0 {
+			after = w.RetryDelay
+		}
+
+		log.Printf("Workflow step %s will be retried (attempt %d of %d): %v", step.Name, p.Retries, w.MaxRetries, err)
+
+		pe := req.InProgressResponse(req.ResourceProperties, req.CallbackContext).
+			WithCallbackDelay(max(after, time.Second)).
+			WithMessage(cfnerr.FromAWSError(err).Message()).
+			WithExtension(w.Key, *p)
+
+		// keep the step's stabilization progress, so that its attempts and
+		// timeout carry on from where they were
+		key := w.stabilizeOptions(step).Key
+		var state json.RawMessage
+		ok, xerr := req.Extension(key, &state)
+		if xerr != nil {
+			return nil, xerr
+		}
+		if ok {
+			pe = pe.WithExtension(key, state)
+		}
+		return pe, nil
+	}
+
+	ce := cfnerr.FromAWSError(err)
+	return w.rollback(ctx, req, p, p.Started, cfnerr.New(ce.Code(), fmt.Sprintf("step %s: %s", step.Name, ce.Message()), err))
+}
+
+func (w *Workflow[Model, Ctx]) retryPolicy() cfnresource.RetryPolicy {
+	if w.RetryPolicy != nil {
+		return *w.RetryPolicy
+	}
+	return cfnresource.DefaultRetryPolicy
+}
+
+// rollback compensates every completed step in reverse order, including the
+// current step if it had started, and returns a failure event for cause.
+//
+// The failure must not be retried by the runtime, as a retry would run and
+// compensate the steps again, so a cause the retry policy would retry is
+// reported as a GeneralServiceException, and the workflow progress is cleared.
+func (w *Workflow[Model, Ctx]) rollback(ctx context.Context, req *cfnresource.Request[Model, Ctx], p *progress, includeCurrent bool, cause error) (*cfnresource.ProgressEvent[Model, Ctx], error) {
+	log.Printf("Workflow failed, rolling back: %v", cause)
+
+	names := slices.Clone(p.Completed)
+	if includeCurrent {
+		names = append(names, p.Current)
+	}
+
+	var rollbackErrs []string
+	for i := len(names) - 1; i >= 0; i-- {
+		idx := slices.IndexFunc(w.steps, func(s Step[Model, Ctx]) bool { return s.Name == names[i] })
+		if idx < 0 || w.steps[idx].Compensate == nil {
+			continue
+		}
+
+		if err := w.steps[idx].Compensate(ctx, req, p.state(names[i])); err != nil {
+			log.Printf("Workflow step %s failed to roll back: %v", names[i], err)
+			rollbackErrs = append(rollbackErrs, fmt.Sprintf("rollback of step %s failed: %v", names[i], err))
+		}
+	}
+
+	ce := cfnerr.FromAWSError(cause)
+	if _, retryable := w.retryPolicy().Retryable(cause); retryable {
+		cause = cfnerr.New(cfnerr.GeneralServiceException, fmt.Sprintf("%s (%s, rolled back)", ce.Message(), ce.Code()), cause)
+	}
+
+	pe := req.ErrorResponse(cause).WithExtension(w.Key, progress{})
+	if len(rollbackErrs) > 0 {
+		pe = pe.WithMessage(pe.Message + "; " + strings.Join(rollbackErrs, "; "))
+	}
+	return pe, nil
+}
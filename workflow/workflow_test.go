@@ -0,0 +1,261 @@
+package workflow_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	cfnTypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/stretchr/testify/require"
+	"github.com/webdestroya/cfnresource"
+	"github.com/webdestroya/cfnresource/cfnerr"
+	"github.com/webdestroya/cfnresource/cfntest"
+	"github.com/webdestroya/cfnresource/workflow"
+)
+
+type model struct {
+	Name string `json:",omitempty"`
+	Arn  string `json:",omitempty"`
+}
+
+type callbackCtx struct {
+	Note string `json:",omitempty"`
+}
+
+type requestType = *cfnresource.Request[model, callbackCtx]
+type stepType = workflow.Step[model, callbackCtx]
+
+type tracker struct {
+	calls []string
+}
+
+func (tr *tracker) run(name string, err error) func(context.Context, requestType, *workflow.State) error {
+	return func(ctx context.Context, req requestType, state *workflow.State) error {
+		tr.calls = append(tr.calls, "run:"+name)
+		if err != nil {
+			return err
+		}
+		return state.Set(name + "-id")
+	}
+}
+
+func (tr *tracker) compensate(name string) func(context.Context, requestType, *workflow.State) error {
+	return func(ctx context.Context, req requestType, state *workflow.State) error {
+		var id string
+		_, err := state.Get(&id)
+		tr.calls = append(tr.calls, "undo:"+id)
+		return err
+	}
+}
+
+func TestWorkflowSuccess(t *testing.T) {
+	tr := &tracker{}
+	polls := 0
+
+	wf := workflow.New(
+		stepType{
+			Name: "create",
+			Run: func(ctx context.Context, req requestType, state *workflow.State) error {
+				tr.calls = append(tr.calls, "run:create")
+				req.ResourceProperties.Arn = "arn:thing"
+				return nil
+			},
+			Stabilize: func(ctx context.Context, req requestType, state *workflow.State) (bool, bool, error) {
+				polls++
+				return polls == 2, false, nil
+			},
+		},
+		stepType{Name: "tag", Run: tr.run("tag", nil)},
+	)
+
+	req := &cfnresource.Request[model, callbackCtx]{
+		Action:             "CREATE",
+		ResourceProperties: &model{Name: "thing"},
+		CallbackContext:    &callbackCtx{Note: "mine"},
+	}
+
+	pe, err := wf.Run(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, cfnTypes.OperationStatusInProgress, pe.OperationStatus)
+	require.Equal(t, "mine", pe.CallbackContext.Note)
+	require.Equal(t, "arn:thing", pe.ResourceModel.Arn)
+
+	req, err = req.CallbackRequest(pe)
+	require.NoError(t, err)
+
+	pe, err = wf.Run(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, cfnTypes.OperationStatusSuccess, pe.OperationStatus)
+	require.Equal(t, "arn:thing", pe.ResourceModel.Arn)
+	require.Equal(t, []string{"run:create", "run:tag"}, tr.calls)
+	require.Equal(t, 2, polls)
+}
+
+func TestWorkflowRollback(t *testing.T) {
+	tr := &tracker{}
+
+	wf := workflow.New(
+		stepType{Name: "create", Run: tr.run("create", nil), Compensate: tr.compensate("create")},
+		stepType{Name: "nocomp", Run: tr.run("nocomp", nil)},
+		stepType{Name: "attach", Run: tr.run("attach", nil), Compensate: tr.compensate("attach")},
+		stepType{Name: "policy", Run: tr.run("policy", cfnerr.NewMessage(cfnerr.AccessDenied, "denied")), Compensate: tr.compensate("policy")},
+	)
+
+	req := &cfnresource.Request[model, callbackCtx]{Action: "CREATE", ResourceProperties: &model{}}
+
+	pe, err := wf.Run(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, cfnTypes.OperationStatusFailed, pe.OperationStatus)
+	require.Equal(t, cfnerr.AccessDenied, pe.HandlerErrorCode)
+	require.Equal(t, "step policy: denied", pe.Message)
+	require.Equal(t, []string{"run:create", "run:nocomp", "run:attach", "run:policy", "undo:attach-id", "undo:create-id"}, tr.calls)
+}
+
+func TestWorkflowRetryAndStabilizeFailure(t *testing.T) {
+	tr := &tracker{}
+	attempts := 0
+
+	wf := workflow.New(
+		stepType{Name: "create", Run: tr.run("create", nil), Compensate: tr.compensate("create")},
+		stepType{
+			Name: "flaky",
+			Run: func(ctx context.Context, req requestType, state *workflow.State) error {
+				attempts++
+				if attempts == 1 {
+					return cfnerr.Retryable(errors.New("not yet"), 7*time.Second)
+				}
+				return state.Set("flaky-id")
+			},
+			Stabilize: func(ctx context.Context, req requestType, state *workflow.State) (bool, bool, error) {
+				return false, true, nil
+			},
+			Compensate: tr.compensate("flaky"),
+		},
+	)
+
+	req := &cfnresource.Request[model, callbackCtx]{Action: "UPDATE", ResourceProperties: &model{}}
+
+	pe, err := wf.Run(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, cfnTypes.OperationStatusInProgress, pe.OperationStatus)
+	require.Equal(t, 7, pe.CallbackDelaySeconds)
+	require.Equal(t, "not yet", pe.Message)
+
+	req, err = req.CallbackRequest(pe)
+	require.NoError(t, err)
+
+	pe, err = wf.Run(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, cfnTypes.OperationStatusFailed, pe.OperationStatus)
+	require.Equal(t, cfnerr.NotStabilized, pe.HandlerErrorCode)
+	require.Equal(t, 2, attempts)
+	require.Equal(t, []string{"run:create", "undo:flaky-id", "undo:create-id"}, tr.calls)
+}
+
+func TestWorkflowRetryPolicy(t *testing.T) {
+	polls := 0
+
+	wf := workflow.New(
+		stepType{
+			Name: "create",
+			Run:  (&tracker{}).run("create", nil),
+			Stabilize: func(ctx context.Context, req requestType, state *workflow.State) (bool, bool, error) {
+				polls++
+				if polls == 2 {
+					return false, false, cfnerr.NewMessage(cfnerr.Throttling, "rate exceeded")
+				}
+				return false, false, nil
+			},
+			StabilizeOptions: &cfnresource.StabilizeOptions{MaxAttempts: 2, InitialDelay: time.Second},
+		},
+	)
+
+	req := &cfnresource.Request[model, callbackCtx]{Action: "CREATE", ResourceProperties: &model{}}
+
+	pe, err := wf.Run(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, cfnTypes.OperationStatusInProgress, pe.OperationStatus)
+
+	// Throttling is in the DefaultRetryPolicy codes, so it is retried
+	req, err = req.CallbackRequest(pe)
+	require.NoError(t, err)
+	pe, err = wf.Run(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, cfnTypes.OperationStatusInProgress, pe.OperationStatus)
+	require.Equal(t, 10, pe.CallbackDelaySeconds)
+	require.Equal(t, "rate exceeded", pe.Message)
+
+	// the stabilization attempts carry on across the retry
+	req, err = req.CallbackRequest(pe)
+	require.NoError(t, err)
+	pe, err = wf.Run(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, cfnTypes.OperationStatusFailed, pe.OperationStatus)
+	require.Equal(t, cfnerr.NotStabilized, pe.HandlerErrorCode)
+	require.Contains(t, pe.Message, "after 2 attempts")
+
+	t.Run("custom policy", func(t *testing.T) {
+		wf := workflow.New(stepType{Name: "create", Run: (&tracker{}).run("create", cfnerr.NewMessage(cfnerr.Throttling, "rate exceeded"))})
+		wf.RetryPolicy = &cfnresource.RetryPolicy{}
+
+		pe, err := wf.Run(context.Background(), &cfnresource.Request[model, callbackCtx]{Action: "CREATE", ResourceProperties: &model{}})
+		require.NoError(t, err)
+		require.Equal(t, cfnTypes.OperationStatusFailed, pe.OperationStatus)
+		require.Equal(t, cfnerr.Throttling, pe.HandlerErrorCode)
+	})
+}
+
+// workflowHandler runs a workflow for CREATE
+type workflowHandler struct {
+	wf *workflow.Workflow[model, callbackCtx]
+}
+
+func (h workflowHandler) Create(ctx context.Context, req requestType) (*cfnresource.ProgressEvent[model, callbackCtx], error) {
+	return h.wf.Run(ctx, req)
+}
+
+func (workflowHandler) Update(ctx context.Context, req requestType) (*cfnresource.ProgressEvent[model, callbackCtx], error) {
+	return req.SuccessResponse(req.ResourceProperties), nil
+}
+
+func (workflowHandler) Delete(ctx context.Context, req requestType) (*cfnresource.ProgressEvent[model, callbackCtx], error) {
+	return req.SuccessResponse(nil), nil
+}
+
+func (workflowHandler) Read(ctx context.Context, req requestType) (*cfnresource.ProgressEvent[model, callbackCtx], error) {
+	return req.SuccessResponse(req.ResourceProperties), nil
+}
+
+func (workflowHandler) List(ctx context.Context, req requestType) (*cfnresource.ProgressEvent[model, callbackCtx], error) {
+	return req.SuccessResponse(nil).WithModels(), nil
+}
+
+func TestWorkflowRollbackNotRetried(t *testing.T) {
+	tr := &tracker{}
+
+	wf := workflow.New(
+		stepType{Name: "create", Run: tr.run("create", nil), Compensate: tr.compensate("create")},
+		stepType{Name: "attach", Run: tr.run("attach", cfnerr.NewMessage(cfnerr.Throttling, "rate exceeded"))},
+	)
+	wf.MaxRetries = 0
+
+	// the runtime retries Throttling failures, which would run and roll back
+	// the workflow again
+	result, err := cfntest.New[model, callbackCtx](workflowHandler{wf: wf}).Create(context.Background(), &model{Name: "thing"})
+	require.NoError(t, err)
+	require.Equal(t, cfnTypes.OperationStatusFailed, result.Status)
+	require.Equal(t, cfnerr.GeneralServiceException, result.ErrorCode)
+	require.Equal(t, "step attach: rate exceeded (Throttling, rolled back)", result.Message)
+	require.Equal(t, 1, result.Invocations)
+	require.Equal(t, []string{"run:create", "run:attach", "undo:create-id"}, tr.calls)
+}
+
+func TestWorkflowInvalid(t *testing.T) {
+	require.Panics(t, func() {
+		workflow.New(stepType{Name: "a", Run: (&tracker{}).run("a", nil)}, stepType{Name: "a", Run: (&tracker{}).run("a", nil)})
+	})
+	require.Panics(t, func() {
+		workflow.New(stepType{Name: "a"})
+	})
+}